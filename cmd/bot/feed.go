@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+	"github.com/dynamost/telegram-bot/internal/repository"
+	"github.com/dynamost/telegram-bot/internal/service"
+	"github.com/dynamost/telegram-bot/internal/telegram"
+)
+
+// feedHandler serves the iCalendar feeds that parents subscribe to:
+// /calendar/club.ics, /calendar/team/<id>.ics and /calendar/tournament/<id>.ics,
+// each with its own signed ?token=.
+type feedHandler struct {
+	calendars service.CalendarService
+	logger    repository.Logger
+}
+
+func newFeedHandler(calendars service.CalendarService, logger repository.Logger) *feedHandler {
+	return &feedHandler{calendars: calendars, logger: logger}
+}
+
+func (h *feedHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	scope, id, ok := parseFeedPath(r.URL.Path)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if !h.calendars.CheckFeedToken(scope, id, r.URL.Query().Get("token")) {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+	data, _, err := h.calendars.Build(r.Context(), scope, id)
+	if errors.Is(err, models.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		h.logger.Error(err, "calendar_feed", string(scope), id, 0)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=900")
+	_, _ = w.Write(data)
+}
+
+func parseFeedPath(path string) (models.CalendarScope, int64, bool) {
+	rest, ok := strings.CutPrefix(path, "/calendar/")
+	if !ok {
+		return "", 0, false
+	}
+	rest, ok = strings.CutSuffix(rest, ".ics")
+	if !ok {
+		return "", 0, false
+	}
+	if rest == string(models.CalendarScopeClub) {
+		return models.CalendarScopeClub, 0, true
+	}
+	scope, idPart, ok := strings.Cut(rest, "/")
+	if !ok || (scope != string(models.CalendarScopeTeam) && scope != string(models.CalendarScopeTournament)) {
+		return "", 0, false
+	}
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil || id <= 0 {
+		return "", 0, false
+	}
+	return models.CalendarScope(scope), id, true
+}
+
+// runPollingWithFeed serves the calendar feeds on HTTP_LISTEN while the bot
+// receives updates by long polling.
+func runPollingWithFeed(ctx context.Context, bot *telegram.Bot, listen string, handler http.Handler) error {
+	server := &http.Server{
+		Addr:              listen,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+	log.Printf("calendar feeds: listening on %s", listen)
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	botErr := make(chan error, 1)
+	go func() {
+		botErr <- bot.Run(runCtx)
+	}()
+
+	var (
+		runErr  error
+		botDone bool
+	)
+	select {
+	case runErr = <-botErr:
+		botDone = true
+	case err := <-serverErr:
+		if err != nil {
+			runErr = fmt.Errorf("feed server: %w", err)
+		}
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && runErr == nil {
+		runErr = fmt.Errorf("feed shutdown: %w", err)
+	}
+	if !botDone {
+		<-botErr
+	}
+	return runErr
+}
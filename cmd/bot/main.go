@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
@@ -33,19 +34,25 @@ func main() {
 	tournamentsRepo := pg.NewTournamentsRepo(pool)
 	rostersRepo := pg.NewRostersRepo(pool)
 	matchesRepo := pg.NewMatchesRepo(pool)
+	bracketsRepo := pg.NewBracketsRepo(pool)
+	regulationsRepo := pg.NewRegulationsRepo(pool)
 	lineupRepo := pg.NewLineupRepo(pool)
 	eventsRepo := pg.NewEventsRepo(pool)
 	sessionsRepo := pg.NewSessionsRepo(pool)
+	callbacksRepo := pg.NewCallbacksRepo(pool)
 
 	teamsSvc := service.NewTeamsService(teamsRepo)
 	playersSvc := service.NewPlayersService(playersRepo)
-	tournamentsSvc := service.NewTournamentsService(tournamentsRepo)
-	rostersSvc := service.NewRostersService(rostersRepo)
-	matchesSvc := service.NewMatchesService(matchesRepo, rostersRepo)
-	lineupSvc := service.NewLineupService(lineupRepo, matchesRepo, rostersRepo)
-	eventsSvc := service.NewEventsService(eventsRepo, matchesRepo, rostersRepo)
+	tournamentsSvc := service.NewTournamentsService(tournamentsRepo, regulationsRepo, rostersRepo, matchesRepo)
+	rostersSvc := service.NewRostersService(rostersRepo, matchesRepo, tournamentsRepo, regulationsRepo, playersRepo)
+	matchesSvc := service.NewMatchesService(matchesRepo, rostersRepo, tournamentsRepo)
+	lineupSvc := service.NewLineupService(lineupRepo, matchesRepo, rostersRepo, regulationsRepo, tournamentsRepo)
+	eventsSvc := service.NewEventsService(eventsRepo, matchesRepo, rostersRepo, lineupRepo, regulationsRepo, tournamentsRepo)
 	sessionSvc := service.NewSessionService(sessionsRepo)
 	sessionStore := session.NewStore(sessionSvc)
+	callbackSvc := service.NewCallbackService(callbacksRepo, settings.CallbackSecret)
+	bracketsSvc := service.NewBracketsService(bracketsRepo, matchesRepo, rostersRepo, tournamentsRepo)
+	calendarSvc := service.NewCalendarService(matchesSvc, teamsRepo, tournamentsRepo, settings.FeedSecret)
 
 	botAPI, err := tgbotapi.NewBotAPI(settings.BotToken)
 	if err != nil {
@@ -62,9 +69,29 @@ func main() {
 		Lineup:      lineupSvc,
 		Events:      eventsSvc,
 		Sessions:    sessionStore,
-	}, logger)
+		Callbacks:   callbackSvc,
+		Calendars:   calendarSvc,
+		Brackets:    bracketsSvc,
+	}, logger, telegram.Options{
+		Workers:   settings.Workers,
+		WizardTTL: settings.WizardTTL,
+		FeedURL:   settings.FeedURL,
+		Directors: settings.DirectorIDs,
+	})
 
-	if err := bot.Run(ctx); err != nil && err != context.Canceled {
+	mux := http.NewServeMux()
+	if settings.FeedURL != "" {
+		mux.Handle("GET /calendar/", newFeedHandler(calendarSvc, logger))
+	}
+	switch {
+	case settings.Mode == config.ModeWebhook:
+		err = runWebhook(ctx, botAPI, bot, settings, mux)
+	case settings.FeedURL != "":
+		err = runPollingWithFeed(ctx, bot, settings.HTTPListen, mux)
+	default:
+		err = bot.Run(ctx)
+	}
+	if err != nil && err != context.Canceled {
 		log.Fatalf("bot stopped: %v", err)
 	}
 }
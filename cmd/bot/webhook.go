@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/dynamost/telegram-bot/internal/config"
+	"github.com/dynamost/telegram-bot/internal/telegram"
+)
+
+const (
+	secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+	maxUpdateBody     = 1 << 20
+	shutdownTimeout   = 10 * time.Second
+)
+
+// webhookHandler validates Telegram's secret token header and forwards
+// decoded updates to the bot pipeline.
+type webhookHandler struct {
+	secret  []byte
+	updates chan tgbotapi.Update
+	// mu guards closed: updates is only sent to while it is open.
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newWebhookHandler(secret string) *webhookHandler {
+	return &webhookHandler{
+		secret:  []byte(secret),
+		updates: make(chan tgbotapi.Update, 100),
+	}
+}
+
+func (h *webhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	token := []byte(r.Header.Get(secretTokenHeader))
+	if subtle.ConstantTimeCompare(token, h.secret) != 1 {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var update tgbotapi.Update
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxUpdateBody)).Decode(&update); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.closed {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	select {
+	case h.updates <- update:
+		w.WriteHeader(http.StatusOK)
+	case <-r.Context().Done():
+		// Telegram retries undelivered updates.
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+}
+
+// close stops accepting updates and closes the channel once no request is
+// sending to it, so that the bot drains what was acknowledged.
+func (h *webhookHandler) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.closed {
+		h.closed = true
+		close(h.updates)
+	}
+}
+
+// runWebhook registers the webhook with Telegram, serves it on HTTP_LISTEN
+// together with the other routes of mux and shuts the server down once ctx is
+// canceled.
+func runWebhook(ctx context.Context, api *tgbotapi.BotAPI, bot *telegram.Bot, settings *config.Settings, mux *http.ServeMux) error {
+	hookURL, err := url.Parse(settings.WebhookURL)
+	if err != nil {
+		return fmt.Errorf("parse WEBHOOK_URL: %w", err)
+	}
+	path := hookURL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	handler := newWebhookHandler(settings.WebhookSecret)
+	mux.Handle(path, handler)
+	server := &http.Server{
+		Addr:              settings.HTTPListen,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+
+	if _, err := api.MakeRequest("setWebhook", tgbotapi.Params{
+		"url":          settings.WebhookURL,
+		"secret_token": settings.WebhookSecret,
+	}); err != nil {
+		_ = server.Close()
+		return fmt.Errorf("set webhook: %w", err)
+	}
+	log.Printf("webhook: listening on %s%s", settings.HTTPListen, path)
+
+	botErr := make(chan error, 1)
+	go func() {
+		botErr <- bot.Serve(ctx, handler.updates)
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		runErr = ctx.Err()
+	case err := <-serverErr:
+		if err != nil {
+			runErr = fmt.Errorf("webhook server: %w", err)
+		}
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil && runErr == nil {
+		runErr = fmt.Errorf("webhook shutdown: %w", err)
+	}
+	// Updates already answered with 200 are handled before returning.
+	handler.close()
+	<-botErr
+	return runErr
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"context"
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"strconv"
@@ -12,11 +13,38 @@ import (
 	"github.com/joho/godotenv"
 )
 
+const (
+	ModePolling = "polling"
+	ModeWebhook = "webhook"
+)
+
 type Settings struct {
 	BotToken string
 	DBDSN    string
 	AdminIDs []int64
 	Location *time.Location
+	// DirectorIDs are admins who may override the tournament rules, such as
+	// reopening a finished tournament.
+	DirectorIDs []int64
+
+	// Mode selects how updates are received: long polling or webhook.
+	Mode          string
+	WebhookURL    string
+	WebhookSecret string
+	HTTPListen    string
+
+	// Workers is the size of the update handling pool.
+	Workers int
+	// WizardTTL is how long an untouched wizard stays open.
+	WizardTTL time.Duration
+
+	// CallbackSecret signs the tokens put into inline button callback_data.
+	CallbackSecret []byte
+
+	// FeedURL is the public base URL of the calendar feeds; empty disables
+	// them. FeedSecret signs the feed tokens.
+	FeedURL    string
+	FeedSecret []byte
 }
 
 func Load(ctx context.Context) (*Settings, *pgxpool.Pool, error) {
@@ -37,20 +65,19 @@ func Load(ctx context.Context) (*Settings, *pgxpool.Pool, error) {
 	if adminRaw == "" {
 		return nil, nil, fmt.Errorf("ADMIN_IDS is required")
 	}
-	for _, part := range strings.Split(adminRaw, ",") {
-		part = strings.TrimSpace(part)
-		if part == "" {
-			continue
-		}
-		val, err := strconv.ParseInt(part, 10, 64)
-		if err != nil {
-			return nil, nil, fmt.Errorf("invalid admin id %q: %w", part, err)
-		}
-		set.AdminIDs = append(set.AdminIDs, val)
+	admins, err := parseIDs(adminRaw, "admin")
+	if err != nil {
+		return nil, nil, err
 	}
+	set.AdminIDs = admins
 	if len(set.AdminIDs) == 0 {
 		return nil, nil, fmt.Errorf("ADMIN_IDS must contain at least one value")
 	}
+	directors, err := parseIDs(os.Getenv("DIRECTOR_IDS"), "director")
+	if err != nil {
+		return nil, nil, err
+	}
+	set.DirectorIDs = directors
 
 	tz := strings.TrimSpace(os.Getenv("CLUB_TZ"))
 	if tz == "" {
@@ -62,6 +89,64 @@ func Load(ctx context.Context) (*Settings, *pgxpool.Pool, error) {
 	}
 	set.Location = location
 
+	set.Mode = strings.ToLower(strings.TrimSpace(os.Getenv("BOT_MODE")))
+	if set.Mode == "" {
+		set.Mode = ModePolling
+	}
+	set.HTTPListen = strings.TrimSpace(os.Getenv("HTTP_LISTEN"))
+	if set.HTTPListen == "" {
+		set.HTTPListen = ":8080"
+	}
+	switch set.Mode {
+	case ModePolling:
+	case ModeWebhook:
+		set.WebhookURL = strings.TrimSpace(os.Getenv("WEBHOOK_URL"))
+		if set.WebhookURL == "" {
+			return nil, nil, fmt.Errorf("WEBHOOK_URL is required in webhook mode")
+		}
+		set.WebhookSecret = strings.TrimSpace(os.Getenv("WEBHOOK_SECRET"))
+		if set.WebhookSecret == "" {
+			return nil, nil, fmt.Errorf("WEBHOOK_SECRET is required in webhook mode")
+		}
+	default:
+		return nil, nil, fmt.Errorf("invalid BOT_MODE %q: use polling or webhook", set.Mode)
+	}
+
+	set.Workers = 4
+	if raw := strings.TrimSpace(os.Getenv("WORKERS")); raw != "" {
+		workers, err := strconv.Atoi(raw)
+		if err != nil || workers < 1 {
+			return nil, nil, fmt.Errorf("invalid WORKERS %q: must be a positive integer", raw)
+		}
+		set.Workers = workers
+	}
+
+	set.WizardTTL = time.Hour
+	if raw := strings.TrimSpace(os.Getenv("WIZARD_TTL")); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			return nil, nil, fmt.Errorf("invalid WIZARD_TTL %q: use a positive duration such as 30m", raw)
+		}
+		set.WizardTTL = ttl
+	}
+
+	// Without an explicit secret one is derived from the bot token, so that
+	// buttons survive restarts and change together with the token.
+	if secret := strings.TrimSpace(os.Getenv("CALLBACK_SECRET")); secret != "" {
+		set.CallbackSecret = []byte(secret)
+	} else {
+		sum := sha256.Sum256([]byte("callback:" + set.BotToken))
+		set.CallbackSecret = sum[:]
+	}
+
+	set.FeedURL = strings.TrimRight(strings.TrimSpace(os.Getenv("FEED_URL")), "/")
+	if secret := strings.TrimSpace(os.Getenv("FEED_SECRET")); secret != "" {
+		set.FeedSecret = []byte(secret)
+	} else {
+		sum := sha256.Sum256([]byte("feed:" + set.BotToken))
+		set.FeedSecret = sum[:]
+	}
+
 	cfg, err := pgxpool.ParseConfig(set.DBDSN)
 	if err != nil {
 		return nil, nil, fmt.Errorf("parse db dsn: %w", err)
@@ -76,3 +161,20 @@ func Load(ctx context.Context) (*Settings, *pgxpool.Pool, error) {
 	}
 	return set, pool, nil
 }
+
+// parseIDs reads a comma-separated list of Telegram user ids.
+func parseIDs(raw, kind string) ([]int64, error) {
+	var ids []int64
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		val, err := strconv.ParseInt(part, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s id %q: %w", kind, part, err)
+		}
+		ids = append(ids, val)
+	}
+	return ids, nil
+}
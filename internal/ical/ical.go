@@ -0,0 +1,167 @@
+// Package ical reads and writes the subset of iCalendar (RFC 5545) used for
+// match schedules: VEVENTs with UID, SUMMARY, DTSTART, LOCATION and STATUS.
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Event statuses.
+const (
+	StatusConfirmed = "CONFIRMED"
+	StatusCancelled = "CANCELLED"
+)
+
+// Event is one VEVENT.
+type Event struct {
+	UID         string
+	Summary     string
+	Description string
+	Location    string
+	Start       time.Time
+	// End is written when set; Parse leaves it empty.
+	End time.Time
+	// AllDay is set for DTSTART;VALUE=DATE events, which carry no time.
+	AllDay bool
+	Status string
+	// Modified is written as DTSTAMP and LAST-MODIFIED so that the output
+	// only changes when a match does.
+	Modified time.Time
+}
+
+// Parse reads the events of a calendar. Times without a zone and all-day
+// dates are taken in loc; TZID parameters are honoured when the zone is known.
+func Parse(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		events  []Event
+		current *Event
+		sawCal  bool
+	)
+	for n, line := range lines {
+		name, params, value, ok := splitLine(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VCALENDAR"):
+			sawCal = true
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = &Event{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: END:VEVENT without BEGIN", n+1)
+			}
+			events = append(events, *current)
+			current = nil
+		case current == nil:
+		case name == "UID":
+			current.UID = unescapeText(value)
+		case name == "SUMMARY":
+			current.Summary = unescapeText(value)
+		case name == "LOCATION":
+			current.Location = unescapeText(value)
+		case name == "STATUS":
+			current.Status = strings.ToUpper(value)
+		case name == "DTSTART":
+			start, allDay, err := parseDateTime(value, params, loc)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: DTSTART: %w", n+1, err)
+			}
+			current.Start, current.AllDay = start, allDay
+		}
+	}
+	if !sawCal {
+		return nil, fmt.Errorf("not an iCalendar file")
+	}
+	return events, nil
+}
+
+// unfold joins continuation lines, which start with a space or a tab.
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitLine splits "NAME;PARAM=x;PARAM=y:value".
+func splitLine(line string) (string, map[string]string, string, bool) {
+	head, value, ok := cutOutsideQuotes(line)
+	if !ok {
+		return "", nil, "", false
+	}
+	parts := strings.Split(head, ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		key, val, _ := strings.Cut(part, "=")
+		params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return strings.ToUpper(parts[0]), params, value, true
+}
+
+// cutOutsideQuotes cuts at the first colon that is not inside a quoted
+// parameter value, such as TZID="Europe/Moscow:x".
+func cutOutsideQuotes(line string) (string, string, bool) {
+	quoted := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ':' && !quoted:
+			return line[:i], line[i+1:], true
+		}
+	}
+	return "", "", false
+}
+
+func parseDateTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	if tzid := params["TZID"]; tzid != "" {
+		if zone, err := time.LoadLocation(tzid); err == nil {
+			loc = zone
+		}
+	}
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		date, err := time.ParseInLocation("20060102", value, loc)
+		return date, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		start, err := time.Parse(utcLayout, value)
+		return start, false, err
+	}
+	start, err := time.ParseInLocation("20060102T150405", value, loc)
+	return start, false, err
+}
+
+func unescapeText(value string) string {
+	var builder strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != '\\' || i == len(value)-1 {
+			builder.WriteByte(value[i])
+			continue
+		}
+		i++
+		switch value[i] {
+		case 'n', 'N':
+			builder.WriteByte('\n')
+		default:
+			builder.WriteByte(value[i])
+		}
+	}
+	return builder.String()
+}
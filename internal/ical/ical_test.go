@@ -0,0 +1,118 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParse(t *testing.T) {
+	const calendar = "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:first@league\r\n" +
+		"SUMMARY:Спартак\\, дубль\r\n" +
+		"DTSTART;TZID=Asia/Yekaterinburg:20261017T150000\r\n" +
+		"LOCATION:Стадион Труд\\, поле\r\n" +
+		"  2\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:second@league\r\n" +
+		"SUMMARY:Зенит\r\n" +
+		"DTSTART;TZID=\"Unknown/Zone\":20261024T110000\r\n" +
+		"STATUS:cancelled\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:third@league\r\n" +
+		"SUMMARY:Торпедо\r\n" +
+		"DTSTART:20261031T090000Z\r\n" +
+		"STATUS:CONFIRMED\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:fourth@league\r\n" +
+		"SUMMARY:Сбор\r\n" +
+		"DTSTART;VALUE=DATE:20261107\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	moscow := time.FixedZone("MSK", 3*60*60)
+	events, err := Parse(strings.NewReader(calendar), moscow)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []struct {
+		uid, summary, location, status string
+		start                          string
+		allDay                         bool
+	}{
+		{uid: "first@league", summary: "Спартак, дубль", location: "Стадион Труд, поле 2", start: "2026-10-17T10:00:00Z"},
+		{uid: "second@league", summary: "Зенит", status: StatusCancelled, start: "2026-10-24T08:00:00Z"},
+		{uid: "third@league", summary: "Торпедо", status: StatusConfirmed, start: "2026-10-31T09:00:00Z"},
+		{uid: "fourth@league", summary: "Сбор", start: "2026-11-06T21:00:00Z", allDay: true},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Parse returned %d events, want %d", len(events), len(want))
+	}
+	for i, w := range want {
+		event := events[i]
+		if event.UID != w.uid || event.Summary != w.summary || event.Location != w.location || event.Status != w.status || event.AllDay != w.allDay {
+			t.Errorf("event %d = %q %q %q %q %v, want %q %q %q %q %v", i,
+				event.UID, event.Summary, event.Location, event.Status, event.AllDay,
+				w.uid, w.summary, w.location, w.status, w.allDay)
+		}
+		if start := event.Start.UTC().Format(time.RFC3339); start != w.start {
+			t.Errorf("event %d starts at %s, want %s", i, start, w.start)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := map[string]string{
+		"not a calendar":  "hello\n",
+		"stray END":       "BEGIN:VCALENDAR\nEND:VEVENT\nEND:VCALENDAR\n",
+		"malformed start": "BEGIN:VCALENDAR\nBEGIN:VEVENT\nDTSTART:2026-10-17\nEND:VEVENT\nEND:VCALENDAR\n",
+	}
+	for name, calendar := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Parse(strings.NewReader(calendar), time.UTC); err == nil {
+				t.Errorf("Parse(%q) succeeded, want an error", calendar)
+			}
+		})
+	}
+}
+
+// Written calendars must read back the same, long folded lines included.
+func TestWriteParse(t *testing.T) {
+	start := time.Date(2026, time.October, 17, 12, 0, 0, 0, time.UTC)
+	long := strings.Repeat("Стадион имени героев, ", 8)
+	cal := Calendar{Name: "Матчи", Events: []Event{{
+		UID:      "match-1@test",
+		Summary:  "Динамо — Спартак; дубль",
+		Location: long,
+		Start:    start,
+		End:      start.Add(90 * time.Minute),
+		Status:   StatusCancelled,
+		Modified: start,
+	}}}
+	var buf strings.Builder
+	if err := Write(&buf, cal); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line of %d octets: %q", len(line), line)
+		}
+	}
+	events, err := Parse(strings.NewReader(buf.String()), time.UTC)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Parse returned %d events, want 1", len(events))
+	}
+	event := events[0]
+	if event.UID != "match-1@test" || event.Summary != "Динамо — Спартак; дубль" || event.Location != long ||
+		event.Status != StatusCancelled || !event.Start.Equal(start) {
+		t.Errorf("read back %+v", event)
+	}
+}
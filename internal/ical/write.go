@@ -0,0 +1,84 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Calendar is a named list of events to write.
+type Calendar struct {
+	Name   string
+	Events []Event
+}
+
+const (
+	productID     = "-//dynamost//football bot//RU"
+	utcLayout     = "20060102T150405Z"
+	maxLineOctets = 75
+)
+
+// Write renders the calendar. Times are written in UTC.
+func Write(w io.Writer, cal Calendar) error {
+	out := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeFolded(out, name+":"+value)
+	}
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", productID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME", escapeText(cal.Name))
+	}
+	for _, event := range cal.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", escapeText(event.UID))
+		line("DTSTAMP", event.Modified.UTC().Format(utcLayout))
+		line("LAST-MODIFIED", event.Modified.UTC().Format(utcLayout))
+		line("DTSTART", event.Start.UTC().Format(utcLayout))
+		if !event.End.IsZero() {
+			line("DTEND", event.End.UTC().Format(utcLayout))
+		}
+		line("SUMMARY", escapeText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", escapeText(event.Description))
+		}
+		if event.Location != "" {
+			line("LOCATION", escapeText(event.Location))
+		}
+		if event.Status != "" {
+			line("STATUS", event.Status)
+		}
+		line("END", "VEVENT")
+	}
+	line("END", "VCALENDAR")
+	return out.Flush()
+}
+
+// writeFolded splits content lines longer than 75 octets without breaking a
+// UTF-8 character.
+func writeFolded(out *bufio.Writer, text string) {
+	limit := maxLineOctets
+	for len(text) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		out.WriteString(text[:cut])
+		out.WriteString("\r\n ")
+		text = text[cut:]
+		// The leading space of a continuation line counts too.
+		limit = maxLineOctets - 1
+	}
+	out.WriteString(text)
+	out.WriteString("\r\n")
+}
+
+var textEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeText(value string) string {
+	return textEscaper.Replace(value)
+}
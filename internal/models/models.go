@@ -12,6 +12,10 @@ var (
 	ErrConflict = errors.New("conflict")
 	// ErrValidation indicates business rule violation.
 	ErrValidation = errors.New("validation error")
+	// ErrLocked indicates a change to a finished tournament.
+	ErrLocked = errors.New("tournament is finished")
+	// ErrForbidden indicates an action reserved for directors.
+	ErrForbidden = errors.New("only a director may do this")
 )
 
 type NavigationEntry struct {
@@ -63,25 +67,201 @@ const (
 	TournamentStatusFinished TournamentStatus = "finished"
 )
 
+// TournamentFormat decides how a tournament is played and what the bot shows
+// for it.
+type TournamentFormat string
+
+const (
+	TournamentFormatLeague       TournamentFormat = "league"
+	TournamentFormatCup          TournamentFormat = "cup"
+	TournamentFormatGroupPlayoff TournamentFormat = "group_playoff"
+	TournamentFormatFriendly     TournamentFormat = "friendly"
+	// TournamentFormatFestival is a youth festival where scores are not kept.
+	TournamentFormatFestival TournamentFormat = "festival"
+)
+
+var TournamentFormats = []TournamentFormat{
+	TournamentFormatLeague,
+	TournamentFormatCup,
+	TournamentFormatGroupPlayoff,
+	TournamentFormatFriendly,
+	TournamentFormatFestival,
+}
+
+func (f TournamentFormat) Valid() bool {
+	for _, format := range TournamentFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// Scored reports whether match scores are kept.
+func (f TournamentFormat) Scored() bool {
+	return f != TournamentFormatFestival
+}
+
+// HasStandings reports whether teams earn points for a table.
+func (f TournamentFormat) HasStandings() bool {
+	return f == TournamentFormatLeague || f == TournamentFormatGroupPlayoff
+}
+
+// HasBracket reports whether the tournament ends with a knockout bracket.
+func (f TournamentFormat) HasBracket() bool {
+	return f == TournamentFormatCup || f == TournamentFormatGroupPlayoff
+}
+
+// FormatConfig holds the settings of a tournament format. Settings that do
+// not apply to the format are left zero.
+type FormatConfig struct {
+	PointsWin  int `json:"points_win,omitempty"`
+	PointsDraw int `json:"points_draw,omitempty"`
+	PointsLoss int `json:"points_loss,omitempty"`
+	// Legs is how many times the league teams meet.
+	Legs int `json:"legs,omitempty"`
+	// Groups and AdvancePerGroup describe the group stage of group_playoff.
+	Groups          int `json:"groups,omitempty"`
+	AdvancePerGroup int `json:"advance_per_group,omitempty"`
+}
+
+// DefaultFormatConfig returns the usual settings of a format.
+func DefaultFormatConfig(format TournamentFormat) FormatConfig {
+	switch format {
+	case TournamentFormatLeague:
+		return FormatConfig{PointsWin: 3, PointsDraw: 1, Legs: 2}
+	case TournamentFormatGroupPlayoff:
+		return FormatConfig{PointsWin: 3, PointsDraw: 1, Groups: 2, AdvancePerGroup: 2}
+	default:
+		return FormatConfig{}
+	}
+}
+
+// Regulations are the match rules of a tournament.
+type Regulations struct {
+	TournamentID  int64 `json:"tournament_id"`
+	Periods       int   `json:"periods"`
+	PeriodMinutes int   `json:"period_minutes"`
+	MaxStarters   int   `json:"max_starters"`
+	// MaxBench and MaxSubs are nil when not limited.
+	MaxBench *int `json:"max_bench,omitempty"`
+	MaxSubs  *int `json:"max_subs,omitempty"`
+	// RollingSubs lets a substituted player come back on.
+	RollingSubs bool `json:"rolling_subs"`
+	// MinStarters is the fewest starters a match can be played with.
+	MinStarters int `json:"min_starters"`
+	// RosterOpens and RosterCloses bound the days players can be entered
+	// into or removed from team rosters, both inclusive.
+	RosterOpens  *time.Time `json:"roster_opens,omitempty"`
+	RosterCloses *time.Time `json:"roster_closes,omitempty"`
+	// MinSquad and MaxSquad limit the number of players in a team roster.
+	MinSquad *int `json:"min_squad,omitempty"`
+	MaxSquad *int `json:"max_squad,omitempty"`
+	// BornFrom and BornTo bound the birth years of eligible players.
+	BornFrom *int `json:"born_from,omitempty"`
+	BornTo   *int `json:"born_to,omitempty"`
+	// MaxOverage is how many players born before BornFrom a team may enter.
+	MaxOverage int `json:"max_overage"`
+	// OneTeamPerPlayer keeps a player on one team roster of the tournament
+	// at a time; moving between teams takes a transfer.
+	OneTeamPerPlayer bool      `json:"one_team_per_player"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// DefaultRegulations are the rules of a tournament nobody set them for:
+// adult eleven-a-side, without a minimum of starters so that smaller formats
+// are not held up.
+func DefaultRegulations(tournamentID int64) Regulations {
+	return Regulations{
+		TournamentID:  tournamentID,
+		Periods:       2,
+		PeriodMinutes: 45,
+		MaxStarters:   11,
+		MinStarters:   1,
+	}
+}
+
+// Duration is the regular playing time in minutes.
+func (r Regulations) Duration() int {
+	return r.Periods * r.PeriodMinutes
+}
+
+// AgeStatus tells how a player fits the age rules of a tournament.
+type AgeStatus int
+
+const (
+	AgeEligible AgeStatus = iota
+	// AgeOverage players are older than the limit and take an overage place.
+	AgeOverage
+	// AgeIneligible players are too young or have no birth date.
+	AgeIneligible
+)
+
+// HasAgeLimits reports whether the tournament restricts birth years.
+func (r Regulations) HasAgeLimits() bool {
+	return r.BornFrom != nil || r.BornTo != nil
+}
+
+func (r Regulations) AgeStatus(birthDate *time.Time) AgeStatus {
+	if !r.HasAgeLimits() {
+		return AgeEligible
+	}
+	if birthDate == nil {
+		return AgeIneligible
+	}
+	year := birthDate.Year()
+	switch {
+	case r.BornTo != nil && year > *r.BornTo:
+		return AgeIneligible
+	case r.BornFrom != nil && year < *r.BornFrom:
+		return AgeOverage
+	}
+	return AgeEligible
+}
+
+// RosterWindow tells where a day is relative to the registration window.
+type RosterWindow int
+
+const (
+	RosterWindowOpen RosterWindow = iota
+	RosterWindowNotOpened
+	RosterWindowClosed
+)
+
+// RosterWindowOn places the calendar day of t, in its own location, against
+// the registration window.
+func (r Regulations) RosterWindowOn(t time.Time) RosterWindow {
+	day := t.Format("2006-01-02")
+	switch {
+	case r.RosterOpens != nil && day < r.RosterOpens.Format("2006-01-02"):
+		return RosterWindowNotOpened
+	case r.RosterCloses != nil && day > r.RosterCloses.Format("2006-01-02"):
+		return RosterWindowClosed
+	}
+	return RosterWindowOpen
+}
+
 type Tournament struct {
-	ID        int64            `json:"id"`
-	Name      string           `json:"name"`
-	Type      *string          `json:"type,omitempty"`
-	Status    TournamentStatus `json:"status"`
-	StartDate *time.Time       `json:"start_date,omitempty"`
-	EndDate   *time.Time       `json:"end_date,omitempty"`
-	Note      *string          `json:"note,omitempty"`
-	CreatedAt time.Time        `json:"created_at"`
-	UpdatedAt time.Time        `json:"updated_at"`
+	ID           int64            `json:"id"`
+	Name         string           `json:"name"`
+	Format       TournamentFormat `json:"format"`
+	FormatConfig FormatConfig     `json:"format_config"`
+	Status       TournamentStatus `json:"status"`
+	StartDate    *time.Time       `json:"start_date,omitempty"`
+	EndDate      *time.Time       `json:"end_date,omitempty"`
+	Note         *string          `json:"note,omitempty"`
+	CreatedAt    time.Time        `json:"created_at"`
+	UpdatedAt    time.Time        `json:"updated_at"`
 }
 
 type TournamentPatch struct {
-	Name      *string
-	Type      OptionalString
-	Status    *TournamentStatus
-	StartDate OptionalTime
-	EndDate   OptionalTime
-	Note      OptionalString
+	Name         *string
+	Format       *TournamentFormat
+	FormatConfig *FormatConfig
+	Status       *TournamentStatus
+	StartDate    OptionalTime
+	EndDate      OptionalTime
+	Note         OptionalString
 }
 
 type TournamentRosterEntry struct {
@@ -93,12 +273,27 @@ type TournamentRosterEntry struct {
 	TournamentNumber *int      `json:"tournament_number,omitempty"`
 	CreatedAt        time.Time `json:"created_at"`
 	UpdatedAt        time.Time `json:"updated_at"`
+	// BirthDate is the player's, filled in team rosters.
+	BirthDate *time.Time `json:"birth_date,omitempty"`
+	// TeamName is filled when listing the entries of a player.
+	TeamName string `json:"team_name,omitempty"`
+	// LeftOn is the day the player was transferred out of the team; the
+	// entry stays for the matches played before it.
+	LeftOn *time.Time `json:"left_on,omitempty"`
+	// JoinedOn is the first day of the spell in the team; a player who comes
+	// back gets a new entry.
+	JoinedOn time.Time `json:"joined_on"`
 }
 
+// TournamentTeam is a team registered in a tournament. Only registered teams
+// can have a roster.
 type TournamentTeam struct {
-	TeamID    int64  `json:"team_id"`
-	TeamName  string `json:"team_name"`
-	ShortCode string `json:"short_code"`
+	TournamentID int64     `json:"tournament_id"`
+	TeamID       int64     `json:"team_id"`
+	TeamName     string    `json:"team_name"`
+	ShortCode    string    `json:"short_code"`
+	GroupName    *string   `json:"group_name,omitempty"`
+	RegisteredOn time.Time `json:"registered_on"`
 }
 
 type MatchStatus string
@@ -109,6 +304,21 @@ const (
 	MatchStatusCanceled  MatchStatus = "canceled"
 )
 
+// MatchStage is the round of a tournament a match belongs to.
+type MatchStage string
+
+const (
+	MatchStageGroup        MatchStage = "group"
+	MatchStageRoundOf16    MatchStage = "r16"
+	MatchStageQuarterFinal MatchStage = "qf"
+	MatchStageSemiFinal    MatchStage = "sf"
+	MatchStageFinal        MatchStage = "final"
+)
+
+// KnockoutStages lists the knockout rounds from the earliest; each has half
+// the ties of the previous one.
+var KnockoutStages = []MatchStage{MatchStageRoundOf16, MatchStageQuarterFinal, MatchStageSemiFinal, MatchStageFinal}
+
 type Match struct {
 	ID             int64       `json:"id"`
 	TournamentID   int64       `json:"tournament_id"`
@@ -123,8 +333,33 @@ type Match struct {
 	ScorePEN       *string     `json:"score_pen,omitempty"`
 	ScoreFinalUs   *int        `json:"score_final_us,omitempty"`
 	ScoreFinalThem *int        `json:"score_final_them,omitempty"`
-	CreatedAt      time.Time   `json:"created_at"`
-	UpdatedAt      time.Time   `json:"updated_at"`
+	// ExternalUID is the UID of the calendar event the match was imported
+	// from; re-importing the calendar updates the match instead of adding a
+	// new one.
+	ExternalUID *string `json:"external_uid,omitempty"`
+	// CanceledByCalendar is set when a calendar import canceled the match;
+	// it is scheduled again if the event is confirmed.
+	CanceledByCalendar bool `json:"canceled_by_calendar,omitempty"`
+	// Home tells whether the club team hosts the match; nil when unknown.
+	Home      *bool       `json:"home,omitempty"`
+	Stage     *MatchStage `json:"stage,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+// CalendarScope selects the matches exported as a calendar.
+type CalendarScope string
+
+const (
+	CalendarScopeTeam       CalendarScope = "team"
+	CalendarScopeTournament CalendarScope = "tournament"
+	CalendarScopeClub       CalendarScope = "club"
+)
+
+// MatchFilter selects matches; a zero field matches any.
+type MatchFilter struct {
+	TournamentID int64
+	TeamID       int64
 }
 
 type MatchPatch struct {
@@ -138,6 +373,71 @@ type MatchPatch struct {
 	ScoreFinalUs   OptionalInt
 	ScoreFinalThem OptionalInt
 	OpponentName   *string
+	Stage          OptionalString
+}
+
+// StandingRow is the record of a club team in a tournament.
+type StandingRow struct {
+	TeamID       int64   `json:"team_id"`
+	TeamName     string  `json:"team_name"`
+	GroupName    *string `json:"group_name,omitempty"`
+	Played       int     `json:"played"`
+	Won          int     `json:"won"`
+	Drawn        int     `json:"drawn"`
+	Lost         int     `json:"lost"`
+	GoalsFor     int     `json:"goals_for"`
+	GoalsAgainst int     `json:"goals_against"`
+	Points       int     `json:"points"`
+}
+
+// BracketTie is a stored tie of a knockout bracket: the entrants of the first
+// stage and results entered by hand.
+type BracketTie struct {
+	TournamentID int64      `json:"tournament_id"`
+	Stage        MatchStage `json:"stage"`
+	Position     int        `json:"position"`
+	HomeName     *string    `json:"home_name,omitempty"`
+	AwayName     *string    `json:"away_name,omitempty"`
+	WinnerName   *string    `json:"winner_name,omitempty"`
+}
+
+// Bracket is a knockout bracket with the winners carried forward.
+type Bracket struct {
+	TournamentID int64          `json:"tournament_id"`
+	Stages       []BracketStage `json:"stages"`
+}
+
+type BracketStage struct {
+	Stage MatchStage    `json:"stage"`
+	Ties  []ResolvedTie `json:"ties"`
+}
+
+// ResolvedTie is a tie as it stands: entrants are empty until known, a
+// tie with a single entrant is a bye.
+type ResolvedTie struct {
+	Position int    `json:"position"`
+	Home     string `json:"home"`
+	Away     string `json:"away"`
+	Winner   string `json:"winner,omitempty"`
+	// Match is the club match of the tie, which decides it once played.
+	Match *Match `json:"match,omitempty"`
+	// Manual is set when the winner was entered by hand.
+	Manual bool `json:"manual,omitempty"`
+}
+
+// Tie returns the tie at a stage and position, or nil.
+func (b *Bracket) Tie(stage MatchStage, position int) *ResolvedTie {
+	for i := range b.Stages {
+		if b.Stages[i].Stage != stage {
+			continue
+		}
+		for j := range b.Stages[i].Ties {
+			if b.Stages[i].Ties[j].Position == position {
+				return &b.Stages[i].Ties[j]
+			}
+		}
+	}
+	return nil
 }
 
 type LineupRole string
@@ -215,8 +515,24 @@ func NewPagination(page, perPage int) Pagination {
 	}
 }
 
+// CallbackKeyboard keeps the actions of an inline keyboard on the server. The
+// button at index i carries a signed token referring to Actions[i].
+type CallbackKeyboard struct {
+	ID        int64
+	Actions   []NavigationEntry
+	ExpiresAt time.Time
+}
+
+// SessionKey identifies an admin's session in one chat, so that a private
+// chat and a staff group keep separate wizards and navigation.
+type SessionKey struct {
+	AdminID int64
+	ChatID  int64
+}
+
 type AdminSession struct {
 	AdminID     int64
+	ChatID      int64
 	CurrentFlow *string
 	FlowState   []byte
 	UpdatedAt   time.Time
@@ -2,11 +2,14 @@ package pg
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/dynamost/telegram-bot/internal/models"
@@ -24,11 +27,19 @@ func NewTeamsRepo(pool *pgxpool.Pool) repository.TeamsRepository {
 }
 
 func (r *TeamsRepo) ListActive(ctx context.Context) ([]models.Team, error) {
+	return r.list(ctx, true)
+}
+
+func (r *TeamsRepo) ListAll(ctx context.Context) ([]models.Team, error) {
+	return r.list(ctx, false)
+}
+
+func (r *TeamsRepo) list(ctx context.Context, activeOnly bool) ([]models.Team, error) {
 	rows, err := r.pool.Query(ctx, `
 		SELECT id, name, short_code, active, note, created_at, updated_at
 		FROM teams
-		WHERE active = TRUE
-		ORDER BY name`)
+		WHERE active OR NOT $1
+		ORDER BY name`, activeOnly)
 	if err != nil {
 		return nil, err
 	}
@@ -168,6 +179,16 @@ func (r *PlayersRepo) List(ctx context.Context, pagination models.Pagination) ([
 	return items, rows.Err()
 }
 
+// escapeLike escapes the LIKE wildcards so that they match literally.
+func escapeLike(value string) string {
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		"%", `\%`,
+		"_", `\_`,
+	)
+	return replacer.Replace(value)
+}
+
 func (r *PlayersRepo) Count(ctx context.Context) (int, error) {
 	var total int
 	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM players`).Scan(&total); err != nil {
@@ -176,6 +197,66 @@ func (r *PlayersRepo) Count(ctx context.Context) (int, error) {
 	return total, nil
 }
 
+// Search matches the query against full names using prefix, substring and
+// trigram similarity. The query must already be lower-cased with ё folded to е.
+// LIKE patterns get the query escaped ($1), the similarity operator the query
+// as typed ($3). The operator, unlike a word_similarity() comparison, can use
+// the trigram index; its threshold is set for the transaction only.
+func (r *PlayersRepo) Search(ctx context.Context, query string, limit int) ([]models.Player, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `SET LOCAL pg_trgm.word_similarity_threshold = 0.4`); err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(ctx, `
+		SELECT id, full_name, birth_date, position, active, note, created_at, updated_at
+		FROM players
+		WHERE translate(lower(full_name), 'ё', 'е') LIKE '%' || $1 || '%'
+		   OR $3 <% translate(lower(full_name), 'ё', 'е')
+		ORDER BY translate(lower(full_name), 'ё', 'е') LIKE $1 || '%' DESC,
+		         word_similarity($3, translate(lower(full_name), 'ё', 'е')) DESC,
+		         full_name
+		LIMIT $2`, escapeLike(query), limit, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.Player
+	for rows.Next() {
+		var (
+			player   models.Player
+			birth    *time.Time
+			position *string
+			note     *string
+		)
+		if err := rows.Scan(
+			&player.ID,
+			&player.FullName,
+			&birth,
+			&position,
+			&player.Active,
+			&note,
+			&player.CreatedAt,
+			&player.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		player.BirthDate = birth
+		player.Position = position
+		player.Note = note
+		items = append(items, player)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	return items, tx.Commit(ctx)
+}
+
 func (r *PlayersRepo) Get(ctx context.Context, id int64) (*models.Player, error) {
 	row := r.pool.QueryRow(ctx, `
 		SELECT id, full_name, birth_date, position, active, note, created_at, updated_at
@@ -251,11 +332,11 @@ func (r *PlayersRepo) Update(ctx context.Context, id int64, patch models.PlayerP
 func (r *PlayersRepo) ListAssignments(ctx context.Context, playerID int64) ([]models.TournamentRosterEntry, error) {
 	rows, err := r.pool.Query(ctx, `
 		SELECT tr.id, tr.tournament_id, tr.team_id, tr.player_id, tr.tournament_number,
-		       tr.created_at, tr.updated_at, t.name
+		       tr.created_at, tr.updated_at, t.name, tr.left_on, tr.joined_on
 		FROM tournament_roster tr
 		JOIN teams t ON t.id = tr.team_id
 		WHERE tr.player_id = $1
-		ORDER BY tr.updated_at DESC`, playerID)
+		ORDER BY tr.joined_on DESC, tr.id DESC`, playerID)
 	if err != nil {
 		return nil, err
 	}
@@ -273,7 +354,9 @@ func (r *PlayersRepo) ListAssignments(ctx context.Context, playerID int64) ([]mo
 			&number,
 			&entry.CreatedAt,
 			&entry.UpdatedAt,
-			&entry.PlayerName,
+			&entry.TeamName,
+			&entry.LeftOn,
+			&entry.JoinedOn,
 		); err != nil {
 			return nil, err
 		}
@@ -295,7 +378,7 @@ func NewTournamentsRepo(pool *pgxpool.Pool) repository.TournamentsRepository {
 
 func (r *TournamentsRepo) List(ctx context.Context, status *models.TournamentStatus) ([]models.Tournament, error) {
 	query := `
-		SELECT id, name, type, status, start_date, end_date, note, created_at, updated_at
+		SELECT id, name, format, format_config, status, start_date, end_date, note, created_at, updated_at
 		FROM tournaments`
 	args := []any{}
 	if status != nil {
@@ -314,7 +397,6 @@ func (r *TournamentsRepo) List(ctx context.Context, status *models.TournamentSta
 	for rows.Next() {
 		var (
 			tournament models.Tournament
-			typ        *string
 			start      *time.Time
 			end        *time.Time
 			note       *string
@@ -322,7 +404,8 @@ func (r *TournamentsRepo) List(ctx context.Context, status *models.TournamentSta
 		if err := rows.Scan(
 			&tournament.ID,
 			&tournament.Name,
-			&typ,
+			&tournament.Format,
+			&tournament.FormatConfig,
 			&tournament.Status,
 			&start,
 			&end,
@@ -332,7 +415,6 @@ func (r *TournamentsRepo) List(ctx context.Context, status *models.TournamentSta
 		); err != nil {
 			return nil, err
 		}
-		tournament.Type = typ
 		tournament.StartDate = start
 		tournament.EndDate = end
 		tournament.Note = note
@@ -343,12 +425,11 @@ func (r *TournamentsRepo) List(ctx context.Context, status *models.TournamentSta
 
 func (r *TournamentsRepo) Get(ctx context.Context, id int64) (*models.Tournament, error) {
 	row := r.pool.QueryRow(ctx, `
-		SELECT id, name, type, status, start_date, end_date, note, created_at, updated_at
+		SELECT id, name, format, format_config, status, start_date, end_date, note, created_at, updated_at
 		FROM tournaments WHERE id=$1`, id)
 
 	var (
 		tournament models.Tournament
-		typ        *string
 		start      *time.Time
 		end        *time.Time
 		note       *string
@@ -356,7 +437,8 @@ func (r *TournamentsRepo) Get(ctx context.Context, id int64) (*models.Tournament
 	if err := row.Scan(
 		&tournament.ID,
 		&tournament.Name,
-		&typ,
+		&tournament.Format,
+		&tournament.FormatConfig,
 		&tournament.Status,
 		&start,
 		&end,
@@ -369,7 +451,6 @@ func (r *TournamentsRepo) Get(ctx context.Context, id int64) (*models.Tournament
 		}
 		return nil, err
 	}
-	tournament.Type = typ
 	tournament.StartDate = start
 	tournament.EndDate = end
 	tournament.Note = note
@@ -379,11 +460,12 @@ func (r *TournamentsRepo) Get(ctx context.Context, id int64) (*models.Tournament
 func (r *TournamentsRepo) Create(ctx context.Context, tournament models.Tournament) (int64, error) {
 	var id int64
 	if err := r.pool.QueryRow(ctx, `
-		INSERT INTO tournaments (name, type, status, start_date, end_date, note)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO tournaments (name, format, format_config, status, start_date, end_date, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id`,
 		tournament.Name,
-		tournament.Type,
+		tournament.Format,
+		tournament.FormatConfig,
 		tournament.Status,
 		tournament.StartDate,
 		tournament.EndDate,
@@ -397,7 +479,8 @@ func (r *TournamentsRepo) Create(ctx context.Context, tournament models.Tourname
 func (r *TournamentsRepo) Update(ctx context.Context, id int64, patch models.TournamentPatch) error {
 	set, args := buildUpdateSet([]column{
 		{name: "name", value: patch.Name},
-		{name: "type", value: patch.Type},
+		{name: "format", value: patch.Format},
+		{name: "format_config", value: patch.FormatConfig},
 		{name: "status", value: patch.Status},
 		{name: "start_date", value: patch.StartDate},
 		{name: "end_date", value: patch.EndDate},
@@ -430,11 +513,11 @@ func NewRostersRepo(pool *pgxpool.Pool) repository.RostersRepository {
 
 func (r *RostersRepo) ListTeams(ctx context.Context, tournamentID int64) ([]models.TournamentTeam, error) {
 	rows, err := r.pool.Query(ctx, `
-		SELECT DISTINCT t.id, t.name, t.short_code
-		FROM tournament_roster tr
-		JOIN teams t ON t.id = tr.team_id
-		WHERE tr.tournament_id = $1
-		ORDER BY t.name`, tournamentID)
+		SELECT tt.tournament_id, t.id, t.name, t.short_code, tt.group_name, tt.registered_on
+		FROM tournament_teams tt
+		JOIN teams t ON t.id = tt.team_id
+		WHERE tt.tournament_id = $1
+		ORDER BY tt.group_name NULLS FIRST, t.name`, tournamentID)
 	if err != nil {
 		return nil, err
 	}
@@ -443,7 +526,7 @@ func (r *RostersRepo) ListTeams(ctx context.Context, tournamentID int64) ([]mode
 	var items []models.TournamentTeam
 	for rows.Next() {
 		var team models.TournamentTeam
-		if err := rows.Scan(&team.TeamID, &team.TeamName, &team.ShortCode); err != nil {
+		if err := rows.Scan(&team.TournamentID, &team.TeamID, &team.TeamName, &team.ShortCode, &team.GroupName, &team.RegisteredOn); err != nil {
 			return nil, err
 		}
 		items = append(items, team)
@@ -451,14 +534,64 @@ func (r *RostersRepo) ListTeams(ctx context.Context, tournamentID int64) ([]mode
 	return items, rows.Err()
 }
 
+func (r *RostersRepo) GetTeam(ctx context.Context, tournamentID, teamID int64) (*models.TournamentTeam, error) {
+	row := r.pool.QueryRow(ctx, `
+		SELECT tt.tournament_id, t.id, t.name, t.short_code, tt.group_name, tt.registered_on
+		FROM tournament_teams tt
+		JOIN teams t ON t.id = tt.team_id
+		WHERE tt.tournament_id = $1 AND tt.team_id = $2`, tournamentID, teamID)
+	var team models.TournamentTeam
+	if err := row.Scan(&team.TournamentID, &team.TeamID, &team.TeamName, &team.ShortCode, &team.GroupName, &team.RegisteredOn); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (r *RostersRepo) RegisterTeam(ctx context.Context, team models.TournamentTeam) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO tournament_teams (tournament_id, team_id, group_name, registered_on)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tournament_id, team_id)
+		DO UPDATE SET group_name = EXCLUDED.group_name,
+		              registered_on = EXCLUDED.registered_on,
+		              updated_at = NOW()`,
+		team.TournamentID, team.TeamID, team.GroupName, team.RegisteredOn)
+	return err
+}
+
+func (r *RostersRepo) UnregisterTeam(ctx context.Context, tournamentID, teamID int64) error {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM tournament_teams WHERE tournament_id = $1 AND team_id = $2`, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	return nil
+}
+
 func (r *RostersRepo) ListRoster(ctx context.Context, tournamentID, teamID int64) ([]models.TournamentRosterEntry, error) {
+	return r.listRoster(ctx, `tr.left_on IS NULL`, tournamentID, teamID)
+}
+
+func (r *RostersRepo) ListRosterOn(ctx context.Context, tournamentID, teamID int64, on time.Time) ([]models.TournamentRosterEntry, error) {
+	return r.listRoster(ctx, `tr.joined_on <= $3::date AND (tr.left_on IS NULL OR tr.left_on > $3::date)`, tournamentID, teamID, on)
+}
+
+// listRoster returns the roster entries of a team that match the condition;
+// the tournament and team are $1 and $2.
+func (r *RostersRepo) listRoster(ctx context.Context, condition string, args ...any) ([]models.TournamentRosterEntry, error) {
 	rows, err := r.pool.Query(ctx, `
 		SELECT tr.id, tr.tournament_id, tr.team_id, tr.player_id, tr.tournament_number,
-		       tr.created_at, tr.updated_at, p.full_name
+		       tr.created_at, tr.updated_at, p.full_name, p.birth_date, tr.left_on, tr.joined_on
 		FROM tournament_roster tr
 		JOIN players p ON p.id = tr.player_id
-		WHERE tr.tournament_id = $1 AND tr.team_id = $2
-		ORDER BY COALESCE(tr.tournament_number, 999), p.full_name`, tournamentID, teamID)
+		WHERE tr.tournament_id = $1 AND tr.team_id = $2 AND `+condition+`
+		ORDER BY COALESCE(tr.tournament_number, 999), p.full_name`, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -477,6 +610,9 @@ func (r *RostersRepo) ListRoster(ctx context.Context, tournamentID, teamID int64
 			&entry.CreatedAt,
 			&entry.UpdatedAt,
 			&entry.PlayerName,
+			&entry.BirthDate,
+			&entry.LeftOn,
+			&entry.JoinedOn,
 		); err != nil {
 			return nil, err
 		}
@@ -486,24 +622,90 @@ func (r *RostersRepo) ListRoster(ctx context.Context, tournamentID, teamID int64
 	return items, rows.Err()
 }
 
-func (r *RostersRepo) AddPlayer(ctx context.Context, tournamentID, teamID, playerID int64, number *int) error {
+// The constraints of the team rosters: one current entry per player, one
+// holder per number and spells that do not end before they start.
+const (
+	rosterPlayerKey  = "tournament_roster_player_key"
+	rosterNumberKey  = "tournament_roster_number_key"
+	rosterSpellCheck = "tournament_roster_spell_check"
+	uniqueViolation  = "23505"
+	checkViolation   = "23514"
+)
+
+// rosterConflict turns a violation of the roster constraints into
+// ErrConflict or ErrValidation.
+func rosterConflict(err error, number *int) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+	switch {
+	case pgErr.Code == uniqueViolation && pgErr.ConstraintName == rosterPlayerKey:
+		return fmt.Errorf("player is already in the roster: %w", models.ErrConflict)
+	case pgErr.Code == uniqueViolation && pgErr.ConstraintName == rosterNumberKey && number != nil:
+		return fmt.Errorf("number %d is taken: %w", *number, models.ErrConflict)
+	case pgErr.Code == checkViolation && pgErr.ConstraintName == rosterSpellCheck:
+		return fmt.Errorf("transfer date is before the player joined the team: %w", models.ErrValidation)
+	}
+	return err
+}
+
+func (r *RostersRepo) AddPlayer(ctx context.Context, tournamentID, teamID, playerID int64, number *int, on time.Time) error {
 	_, err := r.pool.Exec(ctx, `
-		INSERT INTO tournament_roster (tournament_id, team_id, player_id, tournament_number)
-		VALUES ($1, $2, $3, $4)`,
-		tournamentID, teamID, playerID, number,
+		INSERT INTO tournament_roster (tournament_id, team_id, player_id, tournament_number, joined_on)
+		SELECT $1, $2, $3, $4,
+		       CASE WHEN EXISTS (
+		                SELECT 1 FROM tournament_roster
+		                WHERE tournament_id = $1 AND team_id = $2 AND player_id = $3)
+		            THEN $5::date
+		            ELSE GREATEST(
+		                LEAST($5::date,
+		                      (SELECT start_date FROM tournaments WHERE id = $1),
+		                      (SELECT MIN(start_time)::date FROM matches WHERE tournament_id = $1 AND team_id = $2)),
+		                (SELECT MAX(left_on) FROM tournament_roster WHERE tournament_id = $1 AND player_id = $3))
+		       END`,
+		tournamentID, teamID, playerID, number, on,
 	)
-	return err
+	return rosterConflict(err, number)
+}
+
+func (r *RostersRepo) TransferPlayer(ctx context.Context, tournamentID, playerID, fromTeamID, toTeamID int64, number *int, on time.Time) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	tag, err := tx.Exec(ctx, `
+		UPDATE tournament_roster
+		SET left_on = $4, updated_at = NOW()
+		WHERE tournament_id = $1 AND team_id = $2 AND player_id = $3 AND left_on IS NULL`,
+		tournamentID, fromTeamID, playerID, on,
+	)
+	if err != nil {
+		return rosterConflict(err, nil)
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+	if _, err := tx.Exec(ctx, `
+		INSERT INTO tournament_roster (tournament_id, team_id, player_id, tournament_number, joined_on)
+		VALUES ($1, $2, $3, $4, $5)`,
+		tournamentID, toTeamID, playerID, number, on,
+	); err != nil {
+		return rosterConflict(err, number)
+	}
+	return tx.Commit(ctx)
 }
 
 func (r *RostersRepo) UpdateNumber(ctx context.Context, tournamentID, teamID, playerID int64, number *int) error {
 	tag, err := r.pool.Exec(ctx, `
 		UPDATE tournament_roster
 		SET tournament_number = $4, updated_at = NOW()
-		WHERE tournament_id = $1 AND team_id = $2 AND player_id = $3`,
+		WHERE tournament_id = $1 AND team_id = $2 AND player_id = $3 AND left_on IS NULL`,
 		tournamentID, teamID, playerID, number,
 	)
 	if err != nil {
-		return err
+		return rosterConflict(err, number)
 	}
 	if tag.RowsAffected() == 0 {
 		return models.ErrNotFound
@@ -514,7 +716,7 @@ func (r *RostersRepo) UpdateNumber(ctx context.Context, tournamentID, teamID, pl
 func (r *RostersRepo) RemovePlayer(ctx context.Context, tournamentID, teamID, playerID int64) error {
 	tag, err := r.pool.Exec(ctx, `
 		DELETE FROM tournament_roster
-		WHERE tournament_id = $1 AND team_id = $2 AND player_id = $3`,
+		WHERE tournament_id = $1 AND team_id = $2 AND player_id = $3 AND left_on IS NULL`,
 		tournamentID, teamID, playerID,
 	)
 	if err != nil {
@@ -555,7 +757,7 @@ func (r *RostersRepo) TeamPlayerCount(ctx context.Context, tournamentID, teamID
 	var count int
 	if err := r.pool.QueryRow(ctx, `
 		SELECT COUNT(*) FROM tournament_roster
-		WHERE tournament_id = $1 AND team_id = $2`,
+		WHERE tournament_id = $1 AND team_id = $2 AND left_on IS NULL`,
 		tournamentID, teamID,
 	).Scan(&count); err != nil {
 		return 0, err
@@ -567,7 +769,7 @@ func (r *RostersRepo) IsPlayerInRoster(ctx context.Context, tournamentID, teamID
 	var count int
 	if err := r.pool.QueryRow(ctx, `
 		SELECT COUNT(*) FROM tournament_roster
-		WHERE tournament_id = $1 AND team_id = $2 AND player_id = $3`,
+		WHERE tournament_id = $1 AND team_id = $2 AND player_id = $3 AND left_on IS NULL`,
 		tournamentID, teamID, playerID,
 	).Scan(&count); err != nil {
 		return false, err
@@ -575,6 +777,19 @@ func (r *RostersRepo) IsPlayerInRoster(ctx context.Context, tournamentID, teamID
 	return count > 0, nil
 }
 
+func (r *RostersRepo) IsPlayerInRosterOn(ctx context.Context, tournamentID, teamID, playerID int64, on time.Time) (bool, error) {
+	var count int
+	if err := r.pool.QueryRow(ctx, `
+		SELECT COUNT(*) FROM tournament_roster
+		WHERE tournament_id = $1 AND team_id = $2 AND player_id = $3
+		  AND joined_on <= $4::date AND (left_on IS NULL OR left_on > $4::date)`,
+		tournamentID, teamID, playerID, on,
+	).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 // Matches --------------------------------------------------------------------
 
 type MatchesRepo struct {
@@ -589,13 +804,32 @@ func (r *MatchesRepo) List(ctx context.Context, tournamentID, teamID int64) ([]m
 	rows, err := r.pool.Query(ctx, `
 		SELECT id, tournament_id, team_id, opponent_name, start_time, location,
 		       status, score_ht, score_ft, score_et, score_pen,
-		       score_final_us, score_final_them, created_at, updated_at
+		       score_final_us, score_final_them, external_uid, canceled_by_calendar, is_home, stage, created_at, updated_at
 		FROM matches
 		WHERE tournament_id = $1 AND team_id = $2
 		ORDER BY start_time`, tournamentID, teamID)
 	if err != nil {
 		return nil, err
 	}
+	return scanMatches(rows)
+}
+
+func (r *MatchesRepo) ListAll(ctx context.Context, filter models.MatchFilter) ([]models.Match, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, tournament_id, team_id, opponent_name, start_time, location,
+		       status, score_ht, score_ft, score_et, score_pen,
+		       score_final_us, score_final_them, external_uid, canceled_by_calendar, is_home, stage, created_at, updated_at
+		FROM matches
+		WHERE ($1 = 0 OR tournament_id = $1) AND ($2 = 0 OR team_id = $2)
+		ORDER BY start_time`, filter.TournamentID, filter.TeamID)
+	if err != nil {
+		return nil, err
+	}
+	return scanMatches(rows)
+}
+
+// scanMatches reads the rows of a List query and closes them.
+func scanMatches(rows pgx.Rows) ([]models.Match, error) {
 	defer rows.Close()
 
 	var items []models.Match
@@ -610,6 +844,7 @@ func (r *MatchesRepo) List(ctx context.Context, tournamentID, teamID int64) ([]m
 			scoreUs   *int
 			scoreThem *int
 			status    string
+			stage     *string
 		)
 		if err := rows.Scan(
 			&match.ID,
@@ -625,6 +860,10 @@ func (r *MatchesRepo) List(ctx context.Context, tournamentID, teamID int64) ([]m
 			&scorePEN,
 			&scoreUs,
 			&scoreThem,
+			&match.ExternalUID,
+			&match.CanceledByCalendar,
+			&match.Home,
+			&stage,
 			&match.CreatedAt,
 			&match.UpdatedAt,
 		); err != nil {
@@ -638,6 +877,7 @@ func (r *MatchesRepo) List(ctx context.Context, tournamentID, teamID int64) ([]m
 		match.ScorePEN = scorePEN
 		match.ScoreFinalUs = scoreUs
 		match.ScoreFinalThem = scoreThem
+		match.Stage = matchStage(stage)
 		items = append(items, match)
 	}
 	return items, rows.Err()
@@ -647,7 +887,7 @@ func (r *MatchesRepo) Get(ctx context.Context, id int64) (*models.Match, error)
 	row := r.pool.QueryRow(ctx, `
 		SELECT id, tournament_id, team_id, opponent_name, start_time, location,
 		       status, score_ht, score_ft, score_et, score_pen,
-		       score_final_us, score_final_them, created_at, updated_at
+		       score_final_us, score_final_them, external_uid, canceled_by_calendar, is_home, stage, created_at, updated_at
 		FROM matches WHERE id=$1`, id)
 
 	var (
@@ -660,6 +900,7 @@ func (r *MatchesRepo) Get(ctx context.Context, id int64) (*models.Match, error)
 		scoreUs   *int
 		scoreThem *int
 		status    string
+		stage     *string
 	)
 	if err := row.Scan(
 		&match.ID,
@@ -675,6 +916,10 @@ func (r *MatchesRepo) Get(ctx context.Context, id int64) (*models.Match, error)
 		&scorePEN,
 		&scoreUs,
 		&scoreThem,
+		&match.ExternalUID,
+		&match.CanceledByCalendar,
+		&match.Home,
+		&stage,
 		&match.CreatedAt,
 		&match.UpdatedAt,
 	); err != nil {
@@ -691,29 +936,130 @@ func (r *MatchesRepo) Get(ctx context.Context, id int64) (*models.Match, error)
 	match.ScorePEN = scorePEN
 	match.ScoreFinalUs = scoreUs
 	match.ScoreFinalThem = scoreThem
+	match.Stage = matchStage(stage)
 	return &match, nil
 }
 
+func matchStage(value *string) *models.MatchStage {
+	if value == nil {
+		return nil
+	}
+	stage := models.MatchStage(*value)
+	return &stage
+}
+
+const insertMatchSQL = `
+	INSERT INTO matches (tournament_id, team_id, opponent_name, start_time, location, status, external_uid, is_home, stage)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	RETURNING id`
+
 func (r *MatchesRepo) Create(ctx context.Context, match models.Match) (int64, error) {
 	var id int64
-	if err := r.pool.QueryRow(ctx, `
-		INSERT INTO matches (tournament_id, team_id, opponent_name, start_time, location, status)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id`,
+	if err := r.pool.QueryRow(ctx, insertMatchSQL,
 		match.TournamentID,
 		match.TeamID,
 		match.OpponentName,
 		match.StartTime,
 		match.Location,
 		match.Status,
+		match.ExternalUID,
+		match.Home,
+		match.Stage,
 	).Scan(&id); err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
+func (r *MatchesRepo) CreateMany(ctx context.Context, matches []models.Match) ([]int64, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	ids := make([]int64, 0, len(matches))
+	for _, match := range matches {
+		var id int64
+		if err := tx.QueryRow(ctx, insertMatchSQL,
+			match.TournamentID,
+			match.TeamID,
+			match.OpponentName,
+			match.StartTime,
+			match.Location,
+			match.Status,
+			match.ExternalUID,
+			match.Home,
+			match.Stage,
+		).Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *MatchesRepo) Import(ctx context.Context, matches []models.Match) (int, int, error) {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback(ctx)
+	var created, updated int
+	for _, match := range matches {
+		var inserted bool
+		if err := tx.QueryRow(ctx, `
+			INSERT INTO matches (tournament_id, team_id, opponent_name, start_time, location, status, external_uid, is_home)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (tournament_id, team_id, external_uid) WHERE external_uid IS NOT NULL
+			DO UPDATE SET opponent_name = EXCLUDED.opponent_name,
+			              start_time = EXCLUDED.start_time,
+			              location = EXCLUDED.location,
+			              status = CASE
+			                  WHEN EXCLUDED.status = 'canceled' AND matches.status = 'scheduled' THEN 'canceled'
+			                  WHEN EXCLUDED.status = 'scheduled' AND matches.canceled_by_calendar THEN 'scheduled'
+			                  ELSE matches.status
+			              END,
+			              canceled_by_calendar = CASE
+			                  WHEN EXCLUDED.status = 'canceled' AND matches.status = 'scheduled' THEN TRUE
+			                  WHEN EXCLUDED.status = 'scheduled' THEN FALSE
+			                  ELSE matches.canceled_by_calendar
+			              END,
+			              updated_at = NOW()
+			RETURNING xmax = 0`,
+			match.TournamentID,
+			match.TeamID,
+			match.OpponentName,
+			match.StartTime,
+			match.Location,
+			match.Status,
+			match.ExternalUID,
+			match.Home,
+		).Scan(&inserted); err != nil {
+			return 0, 0, err
+		}
+		if inserted {
+			created++
+		} else {
+			updated++
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return 0, 0, err
+	}
+	return created, updated, nil
+}
+
 func (r *MatchesRepo) Update(ctx context.Context, id int64, patch models.MatchPatch) error {
+	// A status set by hand is no longer the calendar's to restore.
+	var byCalendar *bool
+	if patch.Status != nil {
+		byCalendar = new(bool)
+	}
 	set, args := buildUpdateSet([]column{
+		{name: "canceled_by_calendar", value: byCalendar},
 		{name: "start_time", value: patch.StartTime},
 		{name: "location", value: patch.Location},
 		{name: "status", value: patch.Status},
@@ -724,6 +1070,7 @@ func (r *MatchesRepo) Update(ctx context.Context, id int64, patch models.MatchPa
 		{name: "score_final_us", value: patch.ScoreFinalUs},
 		{name: "score_final_them", value: patch.ScoreFinalThem},
 		{name: "opponent_name", value: patch.OpponentName},
+		{name: "stage", value: patch.Stage},
 	})
 	if len(set) == 0 {
 		return nil
@@ -740,6 +1087,147 @@ func (r *MatchesRepo) Update(ctx context.Context, id int64, patch models.MatchPa
 	return nil
 }
 
+// Regulations ----------------------------------------------------------------
+
+type RegulationsRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewRegulationsRepo(pool *pgxpool.Pool) repository.RegulationsRepository {
+	return &RegulationsRepo{pool: pool}
+}
+
+func (r *RegulationsRepo) Get(ctx context.Context, tournamentID int64) (*models.Regulations, error) {
+	var regulations models.Regulations
+	err := r.pool.QueryRow(ctx, `
+		SELECT tournament_id, periods, period_minutes, max_starters, max_bench, max_subs,
+		       rolling_subs, min_starters, roster_opens, roster_closes, min_squad, max_squad,
+		       born_from, born_to, max_overage, one_team_per_player, updated_at
+		FROM tournament_regulations
+		WHERE tournament_id = $1`, tournamentID).Scan(
+		&regulations.TournamentID,
+		&regulations.Periods,
+		&regulations.PeriodMinutes,
+		&regulations.MaxStarters,
+		&regulations.MaxBench,
+		&regulations.MaxSubs,
+		&regulations.RollingSubs,
+		&regulations.MinStarters,
+		&regulations.RosterOpens,
+		&regulations.RosterCloses,
+		&regulations.MinSquad,
+		&regulations.MaxSquad,
+		&regulations.BornFrom,
+		&regulations.BornTo,
+		&regulations.MaxOverage,
+		&regulations.OneTeamPerPlayer,
+		&regulations.UpdatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, err
+	}
+	return &regulations, nil
+}
+
+func (r *RegulationsRepo) Save(ctx context.Context, regulations models.Regulations) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO tournament_regulations (tournament_id, periods, period_minutes, max_starters, max_bench, max_subs, rolling_subs, min_starters,
+		                                    roster_opens, roster_closes, min_squad, max_squad, born_from, born_to, max_overage,
+		                                    one_team_per_player)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+		ON CONFLICT (tournament_id)
+		DO UPDATE SET periods = EXCLUDED.periods,
+		              period_minutes = EXCLUDED.period_minutes,
+		              max_starters = EXCLUDED.max_starters,
+		              max_bench = EXCLUDED.max_bench,
+		              max_subs = EXCLUDED.max_subs,
+		              rolling_subs = EXCLUDED.rolling_subs,
+		              min_starters = EXCLUDED.min_starters,
+		              roster_opens = EXCLUDED.roster_opens,
+		              roster_closes = EXCLUDED.roster_closes,
+		              min_squad = EXCLUDED.min_squad,
+		              max_squad = EXCLUDED.max_squad,
+		              born_from = EXCLUDED.born_from,
+		              born_to = EXCLUDED.born_to,
+		              max_overage = EXCLUDED.max_overage,
+		              one_team_per_player = EXCLUDED.one_team_per_player,
+		              updated_at = NOW()`,
+		regulations.TournamentID, regulations.Periods, regulations.PeriodMinutes, regulations.MaxStarters,
+		regulations.MaxBench, regulations.MaxSubs, regulations.RollingSubs, regulations.MinStarters,
+		regulations.RosterOpens, regulations.RosterCloses, regulations.MinSquad, regulations.MaxSquad,
+		regulations.BornFrom, regulations.BornTo, regulations.MaxOverage, regulations.OneTeamPerPlayer)
+	return err
+}
+
+// Brackets -------------------------------------------------------------------
+
+type BracketsRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewBracketsRepo(pool *pgxpool.Pool) repository.BracketsRepository {
+	return &BracketsRepo{pool: pool}
+}
+
+func (r *BracketsRepo) List(ctx context.Context, tournamentID int64) ([]models.BracketTie, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT tournament_id, stage, position, home_name, away_name, winner_name
+		FROM bracket_ties
+		WHERE tournament_id = $1
+		ORDER BY stage, position`, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []models.BracketTie
+	for rows.Next() {
+		var (
+			tie   models.BracketTie
+			stage string
+		)
+		if err := rows.Scan(&tie.TournamentID, &stage, &tie.Position, &tie.HomeName, &tie.AwayName, &tie.WinnerName); err != nil {
+			return nil, err
+		}
+		tie.Stage = models.MatchStage(stage)
+		items = append(items, tie)
+	}
+	return items, rows.Err()
+}
+
+func (r *BracketsRepo) Replace(ctx context.Context, tournamentID int64, ties []models.BracketTie) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if _, err := tx.Exec(ctx, `DELETE FROM bracket_ties WHERE tournament_id = $1`, tournamentID); err != nil {
+		return err
+	}
+	for _, tie := range ties {
+		if _, err := tx.Exec(ctx, `
+			INSERT INTO bracket_ties (tournament_id, stage, position, home_name, away_name, winner_name)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			tournamentID, tie.Stage, tie.Position, tie.HomeName, tie.AwayName, tie.WinnerName); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+func (r *BracketsRepo) SetWinner(ctx context.Context, tournamentID int64, stage models.MatchStage, position int, winner *string) error {
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO bracket_ties (tournament_id, stage, position, winner_name)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tournament_id, stage, position)
+		DO UPDATE SET winner_name = EXCLUDED.winner_name, updated_at = NOW()`,
+		tournamentID, stage, position, winner)
+	return err
+}
+
 // Lineups --------------------------------------------------------------------
 
 type LineupRepo struct {
@@ -761,6 +1249,8 @@ func (r *LineupRepo) Get(ctx context.Context, matchID int64) ([]models.MatchLine
 		       ON tr.tournament_id = m.tournament_id
 		      AND tr.team_id = m.team_id
 		      AND tr.player_id = ml.player_id
+		      AND tr.joined_on <= m.start_time::date
+		      AND (tr.left_on IS NULL OR tr.left_on > m.start_time::date)
 		WHERE ml.match_id = $1
 		ORDER BY ml.role, COALESCE(ml.number_override, tr.tournament_number, 999), p.full_name`, matchID)
 	if err != nil {
@@ -990,11 +1480,11 @@ func NewSessionsRepo(pool *pgxpool.Pool) repository.SessionsRepository {
 	return &SessionsRepo{pool: pool}
 }
 
-func (r *SessionsRepo) Get(ctx context.Context, adminID int64) (*models.AdminSession, error) {
+func (r *SessionsRepo) Get(ctx context.Context, key models.SessionKey) (*models.AdminSession, error) {
 	row := r.pool.QueryRow(ctx, `
-		SELECT admin_tg_id, current_flow, flow_state, updated_at
+		SELECT admin_tg_id, chat_id, current_flow, flow_state, updated_at
 		FROM admin_sessions
-		WHERE admin_tg_id = $1`, adminID)
+		WHERE admin_tg_id = $1 AND chat_id = $2`, key.AdminID, key.ChatID)
 	var (
 		session models.AdminSession
 		flow    *string
@@ -1002,6 +1492,7 @@ func (r *SessionsRepo) Get(ctx context.Context, adminID int64) (*models.AdminSes
 	)
 	if err := row.Scan(
 		&session.AdminID,
+		&session.ChatID,
 		&flow,
 		&state,
 		&session.UpdatedAt,
@@ -1018,25 +1509,115 @@ func (r *SessionsRepo) Get(ctx context.Context, adminID int64) (*models.AdminSes
 
 func (r *SessionsRepo) Upsert(ctx context.Context, session models.AdminSession) error {
 	_, err := r.pool.Exec(ctx, `
-		INSERT INTO admin_sessions (admin_tg_id, current_flow, flow_state, updated_at)
-		VALUES ($1, $2, $3, NOW())
-		ON CONFLICT (admin_tg_id)
+		INSERT INTO admin_sessions (admin_tg_id, chat_id, current_flow, flow_state, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (admin_tg_id, chat_id)
 		DO UPDATE SET current_flow = EXCLUDED.current_flow,
 		              flow_state = EXCLUDED.flow_state,
 		              updated_at = NOW()`,
 		session.AdminID,
+		session.ChatID,
 		session.CurrentFlow,
 		session.FlowState,
 	)
 	return err
 }
 
-func (r *SessionsRepo) Delete(ctx context.Context, adminID int64) error {
+func (r *SessionsRepo) Delete(ctx context.Context, key models.SessionKey) error {
 	_, err := r.pool.Exec(ctx, `
-		DELETE FROM admin_sessions WHERE admin_tg_id = $1`, adminID)
+		DELETE FROM admin_sessions WHERE admin_tg_id = $1 AND chat_id = $2`, key.AdminID, key.ChatID)
 	return err
 }
 
+func (r *SessionsRepo) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM admin_sessions WHERE updated_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ExpireWizards closes the wizards last worked with before the given time and
+// keeps the navigation history of their sessions. A wizard without its own
+// timestamp counts from the session update.
+func (r *SessionsRepo) ExpireWizards(ctx context.Context, before time.Time) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE admin_sessions
+		SET current_flow = NULL,
+		    flow_state = CASE
+		        WHEN flow_state ? 'wizard' OR flow_state ? 'nav' THEN NULLIF(flow_state - 'wizard', '{}'::jsonb)
+		    END
+		WHERE flow_state IS NOT NULL
+		  AND (flow_state ? 'wizard' OR NOT flow_state ? 'nav')
+		  AND COALESCE(
+		        NULLIF(COALESCE(flow_state->'wizard', flow_state)->>'touched', '0001-01-01T00:00:00Z')::timestamptz,
+		        updated_at
+		      ) < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Callbacks ------------------------------------------------------------------
+
+type CallbacksRepo struct {
+	pool *pgxpool.Pool
+}
+
+func NewCallbacksRepo(pool *pgxpool.Pool) repository.CallbacksRepository {
+	return &CallbacksRepo{pool: pool}
+}
+
+func (r *CallbacksRepo) Create(ctx context.Context, keyboard models.CallbackKeyboard) (int64, error) {
+	actions, err := json.Marshal(keyboard.Actions)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := r.pool.QueryRow(ctx, `
+		INSERT INTO callback_keyboards (actions, expires_at)
+		VALUES ($1, $2)
+		RETURNING id`,
+		actions,
+		keyboard.ExpiresAt,
+	).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *CallbacksRepo) Get(ctx context.Context, id int64) (*models.CallbackKeyboard, error) {
+	var (
+		keyboard models.CallbackKeyboard
+		actions  []byte
+	)
+	if err := r.pool.QueryRow(ctx, `
+		SELECT id, actions, expires_at
+		FROM callback_keyboards
+		WHERE id = $1 AND expires_at > NOW()`, id,
+	).Scan(&keyboard.ID, &actions, &keyboard.ExpiresAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, models.ErrNotFound
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(actions, &keyboard.Actions); err != nil {
+		return nil, err
+	}
+	return &keyboard, nil
+}
+
+func (r *CallbacksRepo) DeleteExpired(ctx context.Context) (int64, error) {
+	tag, err := r.pool.Exec(ctx, `
+		DELETE FROM callback_keyboards WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
 // Shared helpers -------------------------------------------------------------
 
 type column struct {
@@ -1082,6 +1663,20 @@ func buildUpdateSet(cols []column) (string, []any) {
 			clauses = append(clauses, fmt.Sprintf("%s=$%d", col.name, idx))
 			args = append(args, *v)
 			idx++
+		case *models.TournamentFormat:
+			if v == nil {
+				continue
+			}
+			clauses = append(clauses, fmt.Sprintf("%s=$%d", col.name, idx))
+			args = append(args, *v)
+			idx++
+		case *models.FormatConfig:
+			if v == nil {
+				continue
+			}
+			clauses = append(clauses, fmt.Sprintf("%s=$%d", col.name, idx))
+			args = append(args, *v)
+			idx++
 		case *models.LineupRole:
 			if v == nil {
 				continue
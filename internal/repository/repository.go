@@ -2,12 +2,15 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/dynamost/telegram-bot/internal/models"
 )
 
 type TeamsRepository interface {
 	ListActive(ctx context.Context) ([]models.Team, error)
+	// ListAll returns the inactive teams too.
+	ListAll(ctx context.Context) ([]models.Team, error)
 	Get(ctx context.Context, id int64) (*models.Team, error)
 	Create(ctx context.Context, team models.Team) (int64, error)
 	Update(ctx context.Context, id int64, patch models.TeamPatch) error
@@ -16,6 +19,7 @@ type TeamsRepository interface {
 type PlayersRepository interface {
 	List(ctx context.Context, pagination models.Pagination) ([]models.Player, error)
 	Count(ctx context.Context) (int, error)
+	Search(ctx context.Context, query string, limit int) ([]models.Player, error)
 	Get(ctx context.Context, id int64) (*models.Player, error)
 	Create(ctx context.Context, player models.Player) (int64, error)
 	Update(ctx context.Context, id int64, patch models.PlayerPatch) error
@@ -29,24 +33,66 @@ type TournamentsRepository interface {
 	Update(ctx context.Context, id int64, patch models.TournamentPatch) error
 }
 
+type RegulationsRepository interface {
+	// Get returns ErrNotFound when the tournament has no regulations yet.
+	Get(ctx context.Context, tournamentID int64) (*models.Regulations, error)
+	Save(ctx context.Context, regulations models.Regulations) error
+}
+
 type RostersRepository interface {
 	ListTeams(ctx context.Context, tournamentID int64) ([]models.TournamentTeam, error)
+	GetTeam(ctx context.Context, tournamentID, teamID int64) (*models.TournamentTeam, error)
+	RegisterTeam(ctx context.Context, team models.TournamentTeam) error
+	UnregisterTeam(ctx context.Context, tournamentID, teamID int64) error
+	// ListRoster returns the players currently in the team roster.
 	ListRoster(ctx context.Context, tournamentID, teamID int64) ([]models.TournamentRosterEntry, error)
-	AddPlayer(ctx context.Context, tournamentID, teamID, playerID int64, number *int) error
+	// ListRosterOn returns the players in the team on the given day: joined
+	// on or before it and not transferred out by then.
+	ListRosterOn(ctx context.Context, tournamentID, teamID int64, on time.Time) ([]models.TournamentRosterEntry, error)
+	// AddPlayer enters a player. The first entry in the team covers the
+	// tournament from its start, so that earlier matches can still be filled
+	// in, but never before the player left another team of the tournament; a
+	// player who was in the team before starts a new spell on the given day.
+	AddPlayer(ctx context.Context, tournamentID, teamID, playerID int64, number *int, on time.Time) error
+	// TransferPlayer closes the entry in the old team on the given day and
+	// opens one in the new team from that day.
+	TransferPlayer(ctx context.Context, tournamentID, playerID, fromTeamID, toTeamID int64, number *int, on time.Time) error
 	UpdateNumber(ctx context.Context, tournamentID, teamID, playerID int64, number *int) error
 	RemovePlayer(ctx context.Context, tournamentID, teamID, playerID int64) error
 	PlayerParticipation(ctx context.Context, tournamentID, teamID, playerID int64) (bool, error)
 	TeamPlayerCount(ctx context.Context, tournamentID, teamID int64) (int, error)
 	IsPlayerInRoster(ctx context.Context, tournamentID, teamID, playerID int64) (bool, error)
+	// IsPlayerInRosterOn reports whether the player was in the team on the
+	// given day, as ListRosterOn does.
+	IsPlayerInRosterOn(ctx context.Context, tournamentID, teamID, playerID int64, on time.Time) (bool, error)
 }
 
 type MatchesRepository interface {
 	List(ctx context.Context, tournamentID, teamID int64) ([]models.Match, error)
+	// ListAll returns the matches the filter selects, by start time.
+	ListAll(ctx context.Context, filter models.MatchFilter) ([]models.Match, error)
 	Get(ctx context.Context, id int64) (*models.Match, error)
 	Create(ctx context.Context, match models.Match) (int64, error)
+	// CreateMany inserts all matches in one transaction: either every match
+	// is created or none.
+	CreateMany(ctx context.Context, matches []models.Match) ([]int64, error)
+	// Import creates the matches and updates those whose external UID is
+	// already known, in one transaction. It reports how many were created and
+	// how many updated.
+	Import(ctx context.Context, matches []models.Match) (created, updated int, err error)
 	Update(ctx context.Context, id int64, patch models.MatchPatch) error
 }
 
+type BracketsRepository interface {
+	// List returns the stored ties of a tournament by stage and position.
+	List(ctx context.Context, tournamentID int64) ([]models.BracketTie, error)
+	// Replace drops the bracket of the tournament and stores the ties
+	// instead, in one transaction.
+	Replace(ctx context.Context, tournamentID int64, ties []models.BracketTie) error
+	// SetWinner records or, with nil, clears a result entered by hand.
+	SetWinner(ctx context.Context, tournamentID int64, stage models.MatchStage, position int, winner *string) error
+}
+
 type LineupRepository interface {
 	Get(ctx context.Context, matchID int64) ([]models.MatchLineup, error)
 	Upsert(ctx context.Context, matchID, playerID int64, role models.LineupRole, numberOverride *int, note *string) error
@@ -63,9 +109,17 @@ type EventsRepository interface {
 }
 
 type SessionsRepository interface {
-	Get(ctx context.Context, adminID int64) (*models.AdminSession, error)
+	Get(ctx context.Context, key models.SessionKey) (*models.AdminSession, error)
 	Upsert(ctx context.Context, session models.AdminSession) error
-	Delete(ctx context.Context, adminID int64) error
+	Delete(ctx context.Context, key models.SessionKey) error
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+	ExpireWizards(ctx context.Context, before time.Time) (int64, error)
+}
+
+type CallbacksRepository interface {
+	Create(ctx context.Context, keyboard models.CallbackKeyboard) (int64, error)
+	Get(ctx context.Context, id int64) (*models.CallbackKeyboard, error)
+	DeleteExpired(ctx context.Context) (int64, error)
 }
 
 type Logger interface {
@@ -0,0 +1,92 @@
+// Package schedule builds league calendars: who meets whom in every round
+// and on which day the round is played.
+package schedule
+
+import "time"
+
+// Pairing is a match of a round; Home and Away index the participants.
+type Pairing struct {
+	Home int
+	Away int
+}
+
+// RoundRobin pairs n participants so that each meets every other once per
+// leg. The circle method is used with home and away alternating, which leaves
+// n-2 breaks (two home or two away matches in a row) per leg, the minimum.
+// Later legs repeat the first one with home and away swapped. With an odd
+// count one participant rests in every round and that round has one pairing
+// fewer.
+func RoundRobin(n, legs int) [][]Pairing {
+	if n < 2 || legs < 1 {
+		return nil
+	}
+	size := n
+	if size%2 == 1 {
+		// The extra slot is the rest day.
+		size++
+	}
+	fixed := size - 1
+	first := make([][]Pairing, 0, fixed)
+	for r := 0; r < fixed; r++ {
+		round := make([]Pairing, 0, size/2)
+		pairing := Pairing{Home: r, Away: fixed}
+		if r%2 == 1 {
+			pairing = Pairing{Home: fixed, Away: r}
+		}
+		round = append(round, pairing)
+		for i := 1; i < size/2; i++ {
+			home, away := (r+i)%fixed, (r-i+fixed)%fixed
+			if i%2 == 1 {
+				home, away = away, home
+			}
+			round = append(round, Pairing{Home: home, Away: away})
+		}
+		first = append(first, dropRest(round, n))
+	}
+
+	rounds := make([][]Pairing, 0, fixed*legs)
+	for leg := 0; leg < legs; leg++ {
+		for _, round := range first {
+			if leg%2 == 0 {
+				rounds = append(rounds, round)
+				continue
+			}
+			mirrored := make([]Pairing, len(round))
+			for i, pairing := range round {
+				mirrored[i] = Pairing{Home: pairing.Away, Away: pairing.Home}
+			}
+			rounds = append(rounds, mirrored)
+		}
+	}
+	return rounds
+}
+
+// dropRest removes the pairing with the rest-day slot.
+func dropRest(round []Pairing, n int) []Pairing {
+	kept := round[:0]
+	for _, pairing := range round {
+		if pairing.Home < n && pairing.Away < n {
+			kept = append(kept, pairing)
+		}
+	}
+	return kept
+}
+
+// Matchdays picks a date for each of the rounds: the first weekday on or
+// after start, then the same weekday every week. Weeks whose matchday falls
+// on a blackout date are skipped.
+func Matchdays(start time.Time, weekday time.Weekday, rounds int, blackout []time.Time) []time.Time {
+	blocked := make(map[string]bool, len(blackout))
+	for _, date := range blackout {
+		blocked[date.Format("2006-01-02")] = true
+	}
+	day := start.AddDate(0, 0, (int(weekday)-int(start.Weekday())+7)%7)
+	days := make([]time.Time, 0, rounds)
+	for len(days) < rounds {
+		if !blocked[day.Format("2006-01-02")] {
+			days = append(days, day)
+		}
+		day = day.AddDate(0, 0, 7)
+	}
+	return days
+}
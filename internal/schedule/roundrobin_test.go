@@ -0,0 +1,133 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRoundRobin(t *testing.T) {
+	tests := []struct {
+		n, legs int
+	}{
+		{n: 2, legs: 1},
+		{n: 3, legs: 1},
+		{n: 4, legs: 1},
+		{n: 5, legs: 2},
+		{n: 6, legs: 2},
+		{n: 7, legs: 1},
+		{n: 8, legs: 2},
+		{n: 10, legs: 1},
+		{n: 12, legs: 3},
+	}
+	for _, tt := range tests {
+		rounds := RoundRobin(tt.n, tt.legs)
+		size := tt.n + tt.n%2
+		if want := (size - 1) * tt.legs; len(rounds) != want {
+			t.Errorf("n=%d legs=%d: %d rounds, want %d", tt.n, tt.legs, len(rounds), want)
+			continue
+		}
+
+		// meetings counts the matches of every pair; home those hosted by
+		// the lower index.
+		meetings := make(map[[2]int]int)
+		home := make(map[[2]int]int)
+		for r, round := range rounds {
+			if want := tt.n / 2; len(round) != want {
+				t.Errorf("n=%d legs=%d round %d: %d pairings, want %d", tt.n, tt.legs, r, len(round), want)
+			}
+			playing := make(map[int]bool)
+			for _, p := range round {
+				if p.Home == p.Away || p.Home < 0 || p.Away < 0 || p.Home >= tt.n || p.Away >= tt.n {
+					t.Fatalf("n=%d legs=%d round %d: bad pairing %+v", tt.n, tt.legs, r, p)
+				}
+				for _, team := range []int{p.Home, p.Away} {
+					if playing[team] {
+						t.Errorf("n=%d legs=%d round %d: %d plays twice", tt.n, tt.legs, r, team)
+					}
+					playing[team] = true
+				}
+				pair := [2]int{min(p.Home, p.Away), max(p.Home, p.Away)}
+				meetings[pair]++
+				if p.Home == pair[0] {
+					home[pair]++
+				}
+			}
+		}
+		for a := 0; a < tt.n; a++ {
+			for b := a + 1; b < tt.n; b++ {
+				pair := [2]int{a, b}
+				if meetings[pair] != tt.legs {
+					t.Errorf("n=%d legs=%d: %d and %d meet %d times, want %d", tt.n, tt.legs, a, b, meetings[pair], tt.legs)
+				}
+				if want := (tt.legs + 1) / 2; tt.legs > 1 && home[pair] != want && home[pair] != tt.legs-want {
+					t.Errorf("n=%d legs=%d: %d hosts %d %d times", tt.n, tt.legs, a, b, home[pair])
+				}
+			}
+		}
+
+		if tt.n%2 == 1 {
+			continue
+		}
+		perLeg := size - 1
+		for leg := 0; leg < tt.legs; leg++ {
+			if breaks := countBreaks(rounds[leg*perLeg:(leg+1)*perLeg], tt.n); breaks > tt.n-2 {
+				t.Errorf("n=%d legs=%d leg %d: %d breaks, want at most %d", tt.n, tt.legs, leg, breaks, tt.n-2)
+			}
+		}
+	}
+}
+
+// countBreaks counts the rounds in which a participant plays at home, or
+// away, for the second time in a row.
+func countBreaks(rounds [][]Pairing, n int) int {
+	last := make([]int, n) // 1 at home, -1 away, 0 not played yet
+	breaks := 0
+	for _, round := range rounds {
+		for _, p := range round {
+			for team, side := range map[int]int{p.Home: 1, p.Away: -1} {
+				if last[team] == side {
+					breaks++
+				}
+				last[team] = side
+			}
+		}
+	}
+	return breaks
+}
+
+func TestRoundRobinInvalid(t *testing.T) {
+	for _, tt := range [][2]int{{0, 1}, {1, 1}, {4, 0}} {
+		if rounds := RoundRobin(tt[0], tt[1]); rounds != nil {
+			t.Errorf("RoundRobin(%d, %d) = %v, want nil", tt[0], tt[1], rounds)
+		}
+	}
+}
+
+func TestMatchdays(t *testing.T) {
+	date := func(value string) time.Time {
+		day, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return day
+	}
+	// Wednesday.
+	start := date("2026-12-16")
+	days := Matchdays(start, time.Saturday, 4, []time.Time{date("2026-12-26"), date("2027-01-02"), date("2027-01-05")})
+	var got []string
+	for _, day := range days {
+		got = append(got, day.Format("2006-01-02"))
+	}
+	want := []string{"2026-12-19", "2027-01-09", "2027-01-16", "2027-01-23"}
+	if len(got) != len(want) {
+		t.Fatalf("Matchdays = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Matchdays = %v, want %v", got, want)
+		}
+	}
+	if days := Matchdays(start, time.Wednesday, 1, nil); !days[0].Equal(start) {
+		t.Errorf("Matchdays on the matchday = %v, want %v", days[0], start)
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+// maxBracketEntrants is the size of a bracket starting from the round of 16.
+const maxBracketEntrants = 16
+
+// DrawBracket pairs the entrants, listed by seed, into the ties of the first
+// stage with the standard seeding of a bracket of size 2^k: seed 1 meets seed
+// 2^k, and seeds 1 and 2 can only meet in the final. With n entrants the
+// seeds above n are byes, so the top 2^k-n seeds go through without playing
+// and meet first-round winners whenever there are enough first-round ties.
+func DrawBracket(tournamentID int64, entrants []string) ([]models.BracketTie, error) {
+	seen := make(map[string]bool, len(entrants))
+	for _, name := range entrants {
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			return nil, fmt.Errorf("entrant %q: %w", name, models.ErrValidation)
+		}
+		seen[key] = true
+	}
+	if len(entrants) < 2 || len(entrants) > maxBracketEntrants {
+		return nil, fmt.Errorf("bracket needs 2 to %d entrants: %w", maxBracketEntrants, models.ErrValidation)
+	}
+	size := 2
+	for size < len(entrants) {
+		size *= 2
+	}
+	stages := models.KnockoutStages
+	first := stages[len(stages)-bracketDepth(size)]
+
+	seeds := seedOrder(size)
+	ties := make([]models.BracketTie, 0, size/2)
+	for position := 0; position < size/2; position++ {
+		tie := models.BracketTie{TournamentID: tournamentID, Stage: first, Position: position}
+		// The higher seed comes first, so a bye leaves the away slot empty.
+		home := entrants[seeds[2*position]-1]
+		tie.HomeName = &home
+		if seed := seeds[2*position+1]; seed <= len(entrants) {
+			away := entrants[seed-1]
+			tie.AwayName = &away
+		}
+		ties = append(ties, tie)
+	}
+	return ties, nil
+}
+
+// seedOrder lists the seeds of a bracket of the given size by slot, e.g.
+// 1 8 4 5 2 7 3 6 for 8: each seed s of the smaller bracket is paired with
+// seed 2m+1-s.
+func seedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		slots := len(order) * 2
+		next := make([]int, 0, slots)
+		for _, seed := range order {
+			next = append(next, seed, slots+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// bracketDepth is the number of stages of a bracket of the given size.
+func bracketDepth(size int) int {
+	depth := 0
+	for ; size > 1; size /= 2 {
+		depth++
+	}
+	return depth
+}
+
+// resolveBracket fills the stages from the stored ties: each decided tie
+// sends its winner to the next stage, the winners of positions 2k and 2k+1
+// meeting at position k.
+func resolveBracket(tournamentID int64, stored []models.BracketTie, matches []models.Match, teams []models.TournamentTeam) *models.Bracket {
+	bracket := &models.Bracket{TournamentID: tournamentID}
+	manual := make(map[string]string)
+	var first []models.BracketTie
+	for _, tie := range stored {
+		if tie.WinnerName != nil {
+			manual[tieKey(tie.Stage, tie.Position)] = *tie.WinnerName
+		}
+		if tie.HomeName != nil || tie.AwayName != nil {
+			first = append(first, tie)
+		}
+	}
+	if len(first) == 0 {
+		return bracket
+	}
+	teamNames := make(map[int64]string, len(teams))
+	for _, team := range teams {
+		teamNames[team.TeamID] = team.TeamName
+	}
+
+	start := 0
+	for i, stage := range models.KnockoutStages {
+		if stage == first[0].Stage {
+			start = i
+		}
+	}
+	current := make([]models.ResolvedTie, 0, len(first))
+	for _, tie := range first {
+		current = append(current, models.ResolvedTie{Position: tie.Position, Home: deref(tie.HomeName), Away: deref(tie.AwayName)})
+	}
+	for n, stage := range models.KnockoutStages[start:] {
+		for i := range current {
+			decideTie(&current[i], stage, n == 0, manual, matches, teamNames)
+		}
+		bracket.Stages = append(bracket.Stages, models.BracketStage{Stage: stage, Ties: current})
+		if len(current) < 2 {
+			break
+		}
+		next := make([]models.ResolvedTie, len(current)/2)
+		for i := range next {
+			next[i] = models.ResolvedTie{Position: i, Home: current[2*i].Winner, Away: current[2*i+1].Winner}
+		}
+		current = next
+	}
+	return bracket
+}
+
+// decideTie finds the winner of a tie whose entrants are known. In the
+// opening stage a tie with one entrant is a bye; later an empty slot waits
+// for the previous stage.
+func decideTie(tie *models.ResolvedTie, stage models.MatchStage, opening bool, manual map[string]string, matches []models.Match, teamNames map[int64]string) {
+	switch {
+	case opening && tie.Away == "":
+		tie.Winner = tie.Home
+		return
+	case tie.Home == "" || tie.Away == "":
+		return
+	}
+	for i := range matches {
+		match := &matches[i]
+		if match.Stage == nil || *match.Stage != stage || match.Status == models.MatchStatusCanceled {
+			continue
+		}
+		us, them := teamNames[match.TeamID], match.OpponentName
+		if !sameTeams(us, them, tie.Home, tie.Away) {
+			continue
+		}
+		tie.Match = match
+		if won, ok := matchWinner(match); ok {
+			tie.Winner = them
+			if won {
+				tie.Winner = us
+			}
+			// The stored entrant spelling is kept.
+			for _, name := range []string{tie.Home, tie.Away} {
+				if strings.EqualFold(name, tie.Winner) {
+					tie.Winner = name
+				}
+			}
+			return
+		}
+	}
+	if winner, ok := manual[tieKey(stage, tie.Position)]; ok && (winner == tie.Home || winner == tie.Away) {
+		tie.Winner, tie.Manual = winner, true
+	}
+}
+
+func sameTeams(a, b, home, away string) bool {
+	return (strings.EqualFold(a, home) && strings.EqualFold(b, away)) ||
+		(strings.EqualFold(a, away) && strings.EqualFold(b, home))
+}
+
+// matchWinner tells whether the club team won: by the final score, then by
+// penalties, then by extra time.
+func matchWinner(match *models.Match) (bool, bool) {
+	if match.ScoreFinalUs != nil && match.ScoreFinalThem != nil && *match.ScoreFinalUs != *match.ScoreFinalThem {
+		return *match.ScoreFinalUs > *match.ScoreFinalThem, true
+	}
+	for _, score := range []*string{match.ScorePEN, match.ScoreET} {
+		if us, them, ok := splitScore(score); ok && us != them {
+			return us > them, true
+		}
+	}
+	return false, false
+}
+
+// splitScore reads "3:2" with the club team first.
+func splitScore(score *string) (int, int, bool) {
+	if score == nil {
+		return 0, 0, false
+	}
+	left, right, ok := strings.Cut(*score, ":")
+	if !ok {
+		return 0, 0, false
+	}
+	us, err := strconv.Atoi(strings.TrimSpace(left))
+	if err != nil {
+		return 0, 0, false
+	}
+	them, err := strconv.Atoi(strings.TrimSpace(right))
+	if err != nil {
+		return 0, 0, false
+	}
+	return us, them, true
+}
+
+func tieKey(stage models.MatchStage, position int) string {
+	return fmt.Sprintf("%s/%d", stage, position)
+}
+
+func deref(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
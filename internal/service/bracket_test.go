@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+// describeTies writes ties as "home-away", with an empty away for a bye.
+func describeTies(ties []models.BracketTie) []string {
+	described := make([]string, len(ties))
+	for i, tie := range ties {
+		described[i] = deref(tie.HomeName) + "-" + deref(tie.AwayName)
+	}
+	return described
+}
+
+func TestDrawBracket(t *testing.T) {
+	tests := []struct {
+		entrants string
+		stage    models.MatchStage
+		ties     string
+	}{
+		{entrants: "A B", stage: models.MatchStageFinal, ties: "A-B"},
+		{entrants: "A B C", stage: models.MatchStageSemiFinal, ties: "A- B-C"},
+		{entrants: "A B C D", stage: models.MatchStageSemiFinal, ties: "A-D B-C"},
+		{entrants: "A B C D E", stage: models.MatchStageQuarterFinal, ties: "A- D-E B- C-"},
+		{entrants: "A B C D E F", stage: models.MatchStageQuarterFinal, ties: "A- D-E B- C-F"},
+		{entrants: "A B C D E F G H", stage: models.MatchStageQuarterFinal, ties: "A-H D-E B-G C-F"},
+		{entrants: "A B C D E F G H I J K L", stage: models.MatchStageRoundOf16, ties: "A- H-I D- E-L B- G-J C- F-K"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.entrants, func(t *testing.T) {
+			ties, err := DrawBracket(7, strings.Fields(tt.entrants))
+			if err != nil {
+				t.Fatalf("DrawBracket: %v", err)
+			}
+			if got := strings.Join(describeTies(ties), " "); got != tt.ties {
+				t.Errorf("ties = %s, want %s", got, tt.ties)
+			}
+			for i, tie := range ties {
+				if tie.TournamentID != 7 || tie.Stage != tt.stage || tie.Position != i {
+					t.Errorf("tie %d = %d %s %d, want 7 %s %d", i, tie.TournamentID, tie.Stage, tie.Position, tt.stage, i)
+				}
+			}
+		})
+	}
+}
+
+// A team with a bye must not meet another one in the second round while
+// there are first-round ties to pair it with.
+func TestDrawBracketByesMeetWinners(t *testing.T) {
+	for n := 2; n <= maxBracketEntrants; n++ {
+		entrants := make([]string, n)
+		for i := range entrants {
+			entrants[i] = fmt.Sprintf("team %d", i+1)
+		}
+		ties, err := DrawBracket(1, entrants)
+		if err != nil {
+			t.Fatalf("DrawBracket(%d): %v", n, err)
+		}
+		played := 0
+		for _, tie := range ties {
+			if tie.AwayName != nil {
+				played++
+			}
+		}
+		byePairs := 0
+		for i := 0; i+1 < len(ties); i += 2 {
+			if ties[i].AwayName == nil && ties[i+1].AwayName == nil {
+				byePairs++
+			}
+		}
+		if want := max(0, len(ties)/2-played); byePairs != want {
+			t.Errorf("%d entrants: %d second-round ties between byes, want %d", n, byePairs, want)
+		}
+	}
+}
+
+func TestDrawBracketErrors(t *testing.T) {
+	tests := map[string][]string{
+		"one entrant":   {"A"},
+		"too many":      strings.Fields("A B C D E F G H I J K L M N O P Q"),
+		"duplicate":     {"Динамо", "Спартак", "динамо"},
+		"empty entrant": {"A", ""},
+	}
+	for name, entrants := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := DrawBracket(1, entrants); !errors.Is(err, models.ErrValidation) {
+				t.Errorf("DrawBracket(%q) error = %v, want ErrValidation", entrants, err)
+			}
+		})
+	}
+}
+
+func TestResolveBracket(t *testing.T) {
+	stage := func(stage models.MatchStage) *models.MatchStage { return &stage }
+	score := func(value int) *int { return &value }
+	name := func(value string) *string { return &value }
+	teams := []models.TournamentTeam{{TeamID: 1, TeamName: "Динамо"}}
+	semis := func(winner *string) []models.BracketTie {
+		return []models.BracketTie{
+			{Stage: models.MatchStageSemiFinal, Position: 0, HomeName: name("Динамо"), AwayName: name("Спартак")},
+			{Stage: models.MatchStageSemiFinal, Position: 1, HomeName: name("ЦСКА"), AwayName: name("Зенит"), WinnerName: winner},
+		}
+	}
+	semi := func(status models.MatchStatus, us, them int) models.Match {
+		return models.Match{
+			TeamID:         1,
+			OpponentName:   "спартак",
+			Status:         status,
+			Stage:          stage(models.MatchStageSemiFinal),
+			ScoreFinalUs:   score(us),
+			ScoreFinalThem: score(them),
+		}
+	}
+	tests := []struct {
+		name    string
+		stored  []models.BracketTie
+		matches []models.Match
+		// want lists the stages as "home-away>winner" ties.
+		want string
+	}{
+		{
+			name: "nothing drawn",
+		},
+		{
+			name:   "undecided",
+			stored: semis(nil),
+			want:   "sf: Динамо-Спартак> ЦСКА-Зенит> | final: ->",
+		},
+		{
+			name:    "club win and manual winner",
+			stored:  semis(name("Зенит")),
+			matches: []models.Match{semi(models.MatchStatusPlayed, 2, 1)},
+			want:    "sf: Динамо-Спартак>Динамо ЦСКА-Зенит>Зенит | final: Динамо-Зенит>",
+		},
+		{
+			name:    "club loss keeps the stored spelling",
+			stored:  semis(nil),
+			matches: []models.Match{semi(models.MatchStatusPlayed, 0, 3)},
+			want:    "sf: Динамо-Спартак>Спартак ЦСКА-Зенит> | final: Спартак->",
+		},
+		{
+			name:    "canceled match does not count",
+			stored:  semis(nil),
+			matches: []models.Match{semi(models.MatchStatusCanceled, 2, 1)},
+			want:    "sf: Динамо-Спартак> ЦСКА-Зенит> | final: ->",
+		},
+		{
+			name:   "manual winner outside the tie is ignored",
+			stored: semis(name("Динамо")),
+			want:   "sf: Динамо-Спартак> ЦСКА-Зенит> | final: ->",
+		},
+		{
+			name: "bye goes through",
+			stored: []models.BracketTie{
+				{Stage: models.MatchStageSemiFinal, Position: 0, HomeName: name("Динамо")},
+				{Stage: models.MatchStageSemiFinal, Position: 1, HomeName: name("ЦСКА"), AwayName: name("Зенит")},
+			},
+			want: "sf: Динамо->Динамо ЦСКА-Зенит> | final: Динамо->",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bracket := resolveBracket(7, tt.stored, tt.matches, teams)
+			if bracket.TournamentID != 7 {
+				t.Errorf("TournamentID = %d, want 7", bracket.TournamentID)
+			}
+			var stages []string
+			for _, stage := range bracket.Stages {
+				ties := []string{string(stage.Stage) + ":"}
+				for _, tie := range stage.Ties {
+					ties = append(ties, tie.Home+"-"+tie.Away+">"+tie.Winner)
+				}
+				stages = append(stages, strings.Join(ties, " "))
+			}
+			if got := strings.Join(stages, " | "); got != tt.want {
+				t.Errorf("bracket = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchWinner(t *testing.T) {
+	score := func(value int) *int { return &value }
+	text := func(value string) *string { return &value }
+	tests := []struct {
+		name    string
+		match   models.Match
+		won     bool
+		decided bool
+	}{
+		{name: "no score"},
+		{name: "final win", match: models.Match{ScoreFinalUs: score(3), ScoreFinalThem: score(1)}, won: true, decided: true},
+		{name: "final loss", match: models.Match{ScoreFinalUs: score(0), ScoreFinalThem: score(2)}, decided: true},
+		{name: "draw", match: models.Match{ScoreFinalUs: score(1), ScoreFinalThem: score(1)}},
+		{name: "penalties win", match: models.Match{ScoreFinalUs: score(1), ScoreFinalThem: score(1), ScorePEN: text("5:4")}, won: true, decided: true},
+		{name: "penalties loss", match: models.Match{ScoreFinalUs: score(1), ScoreFinalThem: score(1), ScorePEN: text(" 2 : 4 ")}, decided: true},
+		{name: "penalties before extra time", match: models.Match{ScoreET: text("2:1"), ScorePEN: text("3:4")}, decided: true},
+		{name: "extra time", match: models.Match{ScoreFT: text("1:1"), ScoreET: text("2:1")}, won: true, decided: true},
+		{name: "malformed score", match: models.Match{ScorePEN: text("4-3")}},
+		{name: "only the full time score", match: models.Match{ScoreFT: text("2:0")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			won, decided := matchWinner(&tt.match)
+			if won != tt.won || decided != tt.decided {
+				t.Errorf("matchWinner = %v, %v, want %v, %v", won, decided, tt.won, tt.decided)
+			}
+		})
+	}
+}
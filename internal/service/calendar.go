@@ -0,0 +1,112 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dynamost/telegram-bot/internal/ical"
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+const (
+	// matchDuration is the length of a calendar event; the schedule only
+	// knows kick-off times.
+	matchDuration = 90 * time.Minute
+	// calendarUIDDomain makes event UIDs globally unique and stable: they
+	// depend on the match id only.
+	calendarUIDDomain = "football-bot.dynamost"
+	feedSigSize       = 16
+)
+
+// render turns matches into a calendar. Team and tournament names are looked
+// up once per id.
+func (s *calendarService) render(ctx context.Context, name string, matches []models.Match) ([]byte, error) {
+	teams := make(map[int64]*models.Team)
+	tournaments := make(map[int64]*models.Tournament)
+	cal := ical.Calendar{Name: name}
+	for _, match := range matches {
+		team, ok := teams[match.TeamID]
+		if !ok {
+			var err error
+			if team, err = s.teams.Get(ctx, match.TeamID); err != nil {
+				return nil, err
+			}
+			teams[match.TeamID] = team
+		}
+		tournament, ok := tournaments[match.TournamentID]
+		if !ok {
+			var err error
+			if tournament, err = s.tournaments.Get(ctx, match.TournamentID); err != nil {
+				return nil, err
+			}
+			tournaments[match.TournamentID] = tournament
+		}
+		event := ical.Event{
+			UID:         fmt.Sprintf("match-%d@%s", match.ID, calendarUIDDomain),
+			Summary:     fmt.Sprintf("%s — %s", team.Name, match.OpponentName),
+			Description: matchDescription(tournament, &match),
+			Start:       match.StartTime,
+			End:         match.StartTime.Add(matchDuration),
+			Status:      ical.StatusConfirmed,
+			Modified:    match.UpdatedAt,
+		}
+		if match.Location != nil {
+			event.Location = *match.Location
+		}
+		if match.Status == models.MatchStatusCanceled {
+			event.Status = ical.StatusCancelled
+			event.Summary = "Отменён: " + event.Summary
+		}
+		cal.Events = append(cal.Events, event)
+	}
+	var buf bytes.Buffer
+	if err := ical.Write(&buf, cal); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func matchDescription(tournament *models.Tournament, match *models.Match) string {
+	lines := []string{"Турнир: " + tournament.Name}
+	if tournament.Format.Scored() && match.Status == models.MatchStatusPlayed && match.ScoreFinalUs != nil && match.ScoreFinalThem != nil {
+		lines = append(lines, fmt.Sprintf("Счёт: %d:%d", *match.ScoreFinalUs, *match.ScoreFinalThem))
+	}
+	return strings.Join(lines, "\n")
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+func calendarFileName(base string) string {
+	base = strings.Trim(unsafeFileChars.ReplaceAllString(base, "-"), "-")
+	if base == "" {
+		base = "calendar"
+	}
+	return base + ".ics"
+}
+
+// CalendarFeedPath is the URL path of the feed of a scope, below the public
+// base URL; the feed token goes into the "token" query parameter.
+func CalendarFeedPath(scope models.CalendarScope, id int64) string {
+	if scope == models.CalendarScopeClub {
+		return "/calendar/club.ics"
+	}
+	return fmt.Sprintf("/calendar/%s/%d.ics", scope, id)
+}
+
+func feedSignature(secret []byte, scope models.CalendarScope, id int64) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("feed:" + string(scope) + ":" + strconv.FormatInt(id, 10)))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:feedSigSize])
+}
+
+func checkFeedSignature(secret []byte, scope models.CalendarScope, id int64, token string) bool {
+	return hmac.Equal([]byte(token), []byte(feedSignature(secret, scope, id)))
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+// callbackSigSize is the number of HMAC bytes kept in a token: 96 bits are
+// plenty against guessing and keep the token around 25 characters.
+const callbackSigSize = 12
+
+// signCallbackToken encodes the keyboard id and button index as
+// "<id>.<index>.<signature>" with base36 numbers.
+func signCallbackToken(secret []byte, id int64, index int) string {
+	body := strconv.FormatInt(id, 36) + "." + strconv.FormatInt(int64(index), 36)
+	return body + "." + callbackSignature(secret, body)
+}
+
+func parseCallbackToken(secret []byte, token string) (int64, int, error) {
+	dot := strings.LastIndexByte(token, '.')
+	if dot < 0 {
+		return 0, 0, fmt.Errorf("callback token: %w", models.ErrValidation)
+	}
+	body, sig := token[:dot], token[dot+1:]
+	if !hmac.Equal([]byte(sig), []byte(callbackSignature(secret, body))) {
+		return 0, 0, fmt.Errorf("callback token signature: %w", models.ErrValidation)
+	}
+	idPart, indexPart, ok := strings.Cut(body, ".")
+	if !ok {
+		return 0, 0, fmt.Errorf("callback token: %w", models.ErrValidation)
+	}
+	id, err := strconv.ParseInt(idPart, 36, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("callback token id: %w", models.ErrValidation)
+	}
+	index, err := strconv.ParseInt(indexPart, 36, 32)
+	if err != nil || index < 0 {
+		return 0, 0, fmt.Errorf("callback token index: %w", models.ErrValidation)
+	}
+	return id, int(index), nil
+}
+
+func callbackSignature(secret []byte, body string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:callbackSigSize])
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+// Eligibility applies the age rules of a tournament to one team roster.
+type Eligibility struct {
+	Regulations models.Regulations
+	// OverageLeft is how many more overage players the team may enter.
+	OverageLeft int
+}
+
+func newEligibility(r models.Regulations, roster []models.TournamentRosterEntry) *Eligibility {
+	overage := 0
+	for _, entry := range roster {
+		if r.AgeStatus(entry.BirthDate) == models.AgeOverage {
+			overage++
+		}
+	}
+	left := r.MaxOverage - overage
+	if left < 0 {
+		left = 0
+	}
+	return &Eligibility{Regulations: r, OverageLeft: left}
+}
+
+// Status tells how the player fits, counting an overage player without a
+// free overage place as ineligible.
+func (e *Eligibility) Status(player models.Player) models.AgeStatus {
+	status := e.Regulations.AgeStatus(player.BirthDate)
+	if status == models.AgeOverage && e.OverageLeft <= 0 {
+		return models.AgeIneligible
+	}
+	return status
+}
+
+// Check explains why the player may not enter the roster.
+func (e *Eligibility) Check(player models.Player) error {
+	r := e.Regulations
+	switch r.AgeStatus(player.BirthDate) {
+	case models.AgeIneligible:
+		if player.BirthDate == nil {
+			return fmt.Errorf("%s has no birth date, the tournament has age limits: %w", player.FullName, models.ErrValidation)
+		}
+		return fmt.Errorf("%s, born %d, is too young, players born up to %d allowed: %w", player.FullName, player.BirthDate.Year(), *r.BornTo, models.ErrValidation)
+	case models.AgeOverage:
+		if e.OverageLeft <= 0 {
+			return fmt.Errorf("%s, born %d, is overage and all %d overage places are taken: %w", player.FullName, player.BirthDate.Year(), r.MaxOverage, models.ErrValidation)
+		}
+	}
+	return nil
+}
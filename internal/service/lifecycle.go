@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+	"github.com/dynamost/telegram-bot/internal/repository"
+)
+
+type directorKey struct{}
+
+// AsDirector marks the request as made by a club director, who may do what
+// the tournament rules otherwise forbid, such as reopening a finished
+// tournament.
+func AsDirector(ctx context.Context) context.Context {
+	return context.WithValue(ctx, directorKey{}, true)
+}
+
+// IsDirector reports whether the request was made by a director.
+func IsDirector(ctx context.Context) bool {
+	director, _ := ctx.Value(directorKey{}).(bool)
+	return director
+}
+
+// transitions lists the allowed status changes. Reopening a finished
+// tournament is reserved for directors.
+var transitions = map[models.TournamentStatus][]models.TournamentStatus{
+	models.TournamentStatusPlanned:  {models.TournamentStatusActive},
+	models.TournamentStatusActive:   {models.TournamentStatusFinished},
+	models.TournamentStatusFinished: {models.TournamentStatusActive},
+}
+
+// CanTransition reports whether a tournament may move from one status to
+// another, regardless of the preconditions of the target status.
+func CanTransition(from, to models.TournamentStatus) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ensureEditable rejects changes to the data of a finished tournament.
+func ensureEditable(ctx context.Context, repo repository.TournamentsRepository, tournamentID int64) error {
+	tournament, err := repo.Get(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	if tournament.Status == models.TournamentStatusFinished {
+		return fmt.Errorf("%s: %w", tournament.Name, models.ErrLocked)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+// maxShirtNumber is the highest number offered as free.
+const maxShirtNumber = 99
+
+// freeNumbersShown is how many free numbers are suggested.
+const freeNumbersShown = 10
+
+// freeNumbers returns the lowest shirt numbers nobody holds.
+func freeNumbers(taken map[int]string) []int {
+	var free []int
+	for number := 1; number <= maxShirtNumber && len(free) < freeNumbersShown; number++ {
+		if _, ok := taken[number]; !ok {
+			free = append(free, number)
+		}
+	}
+	return free
+}
+
+// rosterNumbers maps the numbers of a team roster to their holders, leaving
+// out the given player.
+func rosterNumbers(entries []models.TournamentRosterEntry, except int64) map[int]string {
+	taken := make(map[int]string, len(entries))
+	for _, entry := range entries {
+		if entry.PlayerID != except && entry.TournamentNumber != nil {
+			taken[*entry.TournamentNumber] = entry.PlayerName
+		}
+	}
+	return taken
+}
+
+// lineupNumber is the number a player wears in the match: the override or,
+// without one, the roster number.
+func lineupNumber(entry models.MatchLineup) *int {
+	if entry.NumberOverride != nil {
+		return entry.NumberOverride
+	}
+	return entry.RosterNumber
+}
+
+// lineupNumbers maps the numbers worn in a match to their holders, leaving
+// out the given player.
+func lineupNumbers(lineup []models.MatchLineup, except int64) map[int]string {
+	taken := make(map[int]string, len(lineup))
+	for _, entry := range lineup {
+		if number := lineupNumber(entry); entry.PlayerID != except && number != nil {
+			taken[*number] = entry.PlayerName
+		}
+	}
+	return taken
+}
+
+// checkNumber names the holder when the number is already taken.
+func checkNumber(taken map[int]string, number *int) error {
+	if number == nil {
+		return nil
+	}
+	if holder, ok := taken[*number]; ok {
+		return fmt.Errorf("number %d is taken by %s: %w", *number, holder, models.ErrConflict)
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+func validateRegulations(r models.Regulations) error {
+	switch {
+	case r.Periods < 1 || r.Periods > 4:
+		return fmt.Errorf("periods: %w", models.ErrValidation)
+	case r.PeriodMinutes < 1 || r.PeriodMinutes > 60:
+		return fmt.Errorf("period_minutes: %w", models.ErrValidation)
+	case r.MaxStarters < 1 || r.MaxStarters > 11:
+		return fmt.Errorf("max_starters: %w", models.ErrValidation)
+	case r.MinStarters < 1 || r.MinStarters > r.MaxStarters:
+		return fmt.Errorf("min_starters must be between 1 and max_starters: %w", models.ErrValidation)
+	case r.MaxBench != nil && *r.MaxBench < 0:
+		return fmt.Errorf("max_bench: %w", models.ErrValidation)
+	case r.MaxSubs != nil && *r.MaxSubs < 0:
+		return fmt.Errorf("max_subs: %w", models.ErrValidation)
+	case r.RosterOpens != nil && r.RosterCloses != nil && r.RosterCloses.Before(*r.RosterOpens):
+		return fmt.Errorf("roster window closes before it opens: %w", models.ErrValidation)
+	case r.MinSquad != nil && *r.MinSquad < 1, r.MaxSquad != nil && *r.MaxSquad < 1:
+		return fmt.Errorf("squad size: %w", models.ErrValidation)
+	case r.MinSquad != nil && r.MaxSquad != nil && *r.MinSquad > *r.MaxSquad:
+		return fmt.Errorf("min_squad is above max_squad: %w", models.ErrValidation)
+	case r.BornFrom != nil && r.BornTo != nil && *r.BornFrom > *r.BornTo:
+		return fmt.Errorf("born_from is after born_to: %w", models.ErrValidation)
+	case r.MaxOverage < 0:
+		return fmt.Errorf("max_overage: %w", models.ErrValidation)
+	}
+	return nil
+}
+
+// checkRosterWindow rejects roster changes outside the registration window.
+func checkRosterWindow(r models.Regulations, now time.Time) error {
+	switch r.RosterWindowOn(now) {
+	case models.RosterWindowNotOpened:
+		return fmt.Errorf("roster registration opens on %s: %w", r.RosterOpens.Format("02.01.2006"), models.ErrValidation)
+	case models.RosterWindowClosed:
+		return fmt.Errorf("roster registration closed on %s, ask a director: %w", r.RosterCloses.Format("02.01.2006"), models.ErrValidation)
+	}
+	return nil
+}
+
+// checkLineupRole reports whether the player fits the lineup in the role
+// without breaking the starter and bench limits.
+func checkLineupRole(r models.Regulations, lineup []models.MatchLineup, playerID int64, role models.LineupRole) error {
+	count := 1
+	for _, entry := range lineup {
+		if entry.PlayerID != playerID && entry.Role == role {
+			count++
+		}
+	}
+	if role == models.LineupRoleStart && count > r.MaxStarters {
+		return fmt.Errorf("starting lineup is full, %d players allowed: %w", r.MaxStarters, models.ErrValidation)
+	}
+	if role == models.LineupRoleSub && r.MaxBench != nil && count > *r.MaxBench {
+		return fmt.Errorf("bench is full, %d players allowed: %w", *r.MaxBench, models.ErrValidation)
+	}
+	return nil
+}
+
+// checkLineupComplete refuses events for a match whose lineup has fewer
+// starters than the regulations require. Matches without a lineup are not
+// checked: not every team keeps one.
+func checkLineupComplete(r models.Regulations, lineup []models.MatchLineup) error {
+	if len(lineup) == 0 {
+		return nil
+	}
+	starters := 0
+	for _, entry := range lineup {
+		if entry.Role == models.LineupRoleStart {
+			starters++
+		}
+	}
+	if starters < r.MinStarters {
+		return fmt.Errorf("lineup has %d starters, at least %d required: %w", starters, r.MinStarters, models.ErrValidation)
+	}
+	return nil
+}
+
+// checkEventTime rejects minutes past the end of the match. Stoppage time
+// ("45+2") counts to the minute it was added to; knockout matches may go to
+// extra time of a third of the regular time.
+func checkEventTime(r models.Regulations, match *models.Match, text string) error {
+	minute, ok := eventMinute(text)
+	if !ok {
+		return nil
+	}
+	limit := r.Duration()
+	if match.Stage != nil && *match.Stage != models.MatchStageGroup {
+		limit += r.Duration() / 3
+	}
+	if minute > limit {
+		return fmt.Errorf("minute %d is past the %d minutes of the match: %w", minute, limit, models.ErrValidation)
+	}
+	return nil
+}
+
+// eventMinute reads the minute an event time starts with: 12, 45+2, 90'.
+func eventMinute(text string) (int, bool) {
+	text = strings.TrimSpace(text)
+	end := 0
+	for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+		end++
+	}
+	if end == 0 {
+		return 0, false
+	}
+	minute, err := strconv.Atoi(text[:end])
+	return minute, err == nil
+}
+
+// checkSub applies the substitution limits to the subs recorded so far.
+// Without rolling subs a player who went off cannot come back; when the
+// lineup is kept, the player going off has to be on the pitch. Errors name
+// the players by names, keyed by player ID.
+func checkSub(r models.Regulations, lineup []models.MatchLineup, events []models.MatchEvent, outID, inID int64, names map[int64]string) error {
+	onPitch := make(map[int64]bool, len(lineup))
+	for _, entry := range lineup {
+		if entry.Role == models.LineupRoleStart {
+			onPitch[entry.PlayerID] = true
+		}
+	}
+	wentOff := make(map[int64]bool)
+	subs := 0
+	for _, event := range events {
+		if event.EventType != models.MatchEventSub || event.PlayerMainID == nil || event.PlayerAltID == nil {
+			continue
+		}
+		subs++
+		onPitch[*event.PlayerMainID] = false
+		wentOff[*event.PlayerMainID] = true
+		onPitch[*event.PlayerAltID] = true
+	}
+	if r.MaxSubs != nil && subs >= *r.MaxSubs {
+		return fmt.Errorf("all %d substitutions are used: %w", *r.MaxSubs, models.ErrValidation)
+	}
+	if !r.RollingSubs && wentOff[inID] {
+		return fmt.Errorf("%s was substituted and cannot return: %w", names[inID], models.ErrValidation)
+	}
+	if len(lineup) > 0 {
+		if !onPitch[outID] {
+			return fmt.Errorf("%s is not on the pitch: %w", names[outID], models.ErrValidation)
+		}
+		if onPitch[inID] {
+			return fmt.Errorf("%s is already on the pitch: %w", names[inID], models.ErrValidation)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+)
+
+// normalizeSearchQuery lower-cases the query, folds ё into е and collapses
+// whitespace so that it matches the expression index on players.full_name.
+func normalizeSearchQuery(query string) string {
+	query = strings.ToLower(strings.TrimSpace(query))
+	query = strings.ReplaceAll(query, "ё", "е")
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func hasLatin(value string) bool {
+	for _, r := range value {
+		if r < unicode.MaxASCII && unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Longest sequences go first so that "shch" wins over "sh" and "ch".
+var translitDigraphs = []struct {
+	latin    string
+	cyrillic string
+}{
+	{"shch", "щ"},
+	{"sch", "щ"},
+	{"zh", "ж"},
+	{"kh", "х"},
+	{"ts", "ц"},
+	{"ch", "ч"},
+	{"sh", "ш"},
+	{"yu", "ю"},
+	{"ju", "ю"},
+	{"ya", "я"},
+	{"ja", "я"},
+	{"yo", "е"},
+	{"jo", "е"},
+	{"ye", "е"},
+	{"ay", "ай"},
+	{"ey", "ей"},
+	{"iy", "ий"},
+	{"oy", "ой"},
+	{"uy", "уй"},
+	{"yy", "ый"},
+}
+
+var translitLetters = map[rune]string{
+	'a': "а", 'b': "б", 'c': "к", 'd': "д", 'e': "е", 'f': "ф", 'g': "г",
+	'h': "х", 'i': "и", 'j': "й", 'k': "к", 'l': "л", 'm': "м", 'n': "н",
+	'o': "о", 'p': "п", 'q': "к", 'r': "р", 's': "с", 't': "т", 'u': "у",
+	'v': "в", 'w': "в", 'x': "кс", 'y': "ы", 'z': "з", '\'': "ь",
+}
+
+// transliterate converts a Latin spelling of a Russian name ("Fyodorov",
+// "Shchukin") into Cyrillic. Non-Latin characters are kept as is.
+func transliterate(value string) string {
+	var builder strings.Builder
+	rest := value
+	for rest != "" {
+		matched := false
+		for _, d := range translitDigraphs {
+			if strings.HasPrefix(rest, d.latin) {
+				builder.WriteString(d.cyrillic)
+				rest = rest[len(d.latin):]
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		r := []rune(rest)[0]
+		if cyr, ok := translitLetters[r]; ok {
+			builder.WriteString(cyr)
+		} else {
+			builder.WriteRune(r)
+		}
+		rest = rest[len(string(r)):]
+	}
+	return builder.String()
+}
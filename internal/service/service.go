@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/dynamost/telegram-bot/internal/models"
@@ -14,6 +15,8 @@ import (
 
 type TeamsService interface {
 	ListActive(ctx context.Context) ([]models.Team, error)
+	// ListAll returns the inactive teams too.
+	ListAll(ctx context.Context) ([]models.Team, error)
 	Get(ctx context.Context, id int64) (*models.Team, error)
 	Create(ctx context.Context, input CreateTeamInput) (int64, error)
 	Update(ctx context.Context, id int64, patch models.TeamPatch) error
@@ -38,6 +41,10 @@ func (s *teamsService) ListActive(ctx context.Context) ([]models.Team, error) {
 	return s.repo.ListActive(ctx)
 }
 
+func (s *teamsService) ListAll(ctx context.Context) ([]models.Team, error) {
+	return s.repo.ListAll(ctx)
+}
+
 func (s *teamsService) Get(ctx context.Context, id int64) (*models.Team, error) {
 	return s.repo.Get(ctx, id)
 }
@@ -66,6 +73,7 @@ func (s *teamsService) Update(ctx context.Context, id int64, patch models.TeamPa
 
 type PlayersService interface {
 	List(ctx context.Context, page, perPage int) ([]models.Player, bool, error)
+	Search(ctx context.Context, query string, limit int) ([]models.Player, error)
 	Get(ctx context.Context, id int64) (*models.Player, error)
 	Create(ctx context.Context, input CreatePlayerInput) (int64, error)
 	Update(ctx context.Context, id int64, patch models.PlayerPatch) error
@@ -102,6 +110,41 @@ func (s *playersService) List(ctx context.Context, page, perPage int) ([]models.
 	return items, next, nil
 }
 
+func (s *playersService) Search(ctx context.Context, query string, limit int) ([]models.Player, error) {
+	normalized := normalizeSearchQuery(query)
+	if normalized == "" {
+		return nil, fmt.Errorf("query: %w", models.ErrValidation)
+	}
+	if limit <= 0 || limit > 50 {
+		limit = 20
+	}
+	variants := []string{normalized}
+	if hasLatin(normalized) {
+		if cyr := transliterate(normalized); cyr != normalized {
+			variants = append(variants, cyr)
+		}
+	}
+	seen := make(map[int64]struct{})
+	var result []models.Player
+	for _, variant := range variants {
+		items, err := s.repo.Search(ctx, variant, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range items {
+			if _, ok := seen[item.ID]; ok {
+				continue
+			}
+			seen[item.ID] = struct{}{}
+			result = append(result, item)
+		}
+	}
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result, nil
+}
+
 func (s *playersService) Get(ctx context.Context, id int64) (*models.Player, error) {
 	return s.repo.Get(ctx, id)
 }
@@ -135,23 +178,37 @@ type TournamentsService interface {
 	Get(ctx context.Context, id int64) (*models.Tournament, error)
 	Create(ctx context.Context, input CreateTournamentInput) (int64, error)
 	Update(ctx context.Context, id int64, patch models.TournamentPatch) error
+	// SetStatus moves the tournament along its lifecycle: planned, active,
+	// finished. Starting needs a registered team and finishing needs every
+	// match played or canceled; only directors may reopen a finished
+	// tournament.
+	SetStatus(ctx context.Context, id int64, status models.TournamentStatus) error
+	// Regulations returns the match rules of the tournament, the defaults
+	// when none were set.
+	Regulations(ctx context.Context, id int64) (*models.Regulations, error)
+	SetRegulations(ctx context.Context, regulations models.Regulations) error
 }
 
 type CreateTournamentInput struct {
-	Name      string
-	Type      *string
-	Status    models.TournamentStatus
-	StartDate *time.Time
-	EndDate   *time.Time
-	Note      *string
+	Name   string
+	Format models.TournamentFormat
+	// FormatConfig defaults to the usual settings of the format.
+	FormatConfig *models.FormatConfig
+	Status       models.TournamentStatus
+	StartDate    *time.Time
+	EndDate      *time.Time
+	Note         *string
 }
 
 type tournamentsService struct {
-	repo repository.TournamentsRepository
+	repo            repository.TournamentsRepository
+	regulationsRepo repository.RegulationsRepository
+	rostersRepo     repository.RostersRepository
+	matchesRepo     repository.MatchesRepository
 }
 
-func NewTournamentsService(repo repository.TournamentsRepository) TournamentsService {
-	return &tournamentsService{repo: repo}
+func NewTournamentsService(repo repository.TournamentsRepository, regulations repository.RegulationsRepository, rosters repository.RostersRepository, matches repository.MatchesRepository) TournamentsService {
+	return &tournamentsService{repo: repo, regulationsRepo: regulations, rostersRepo: rosters, matchesRepo: matches}
 }
 
 func (s *tournamentsService) List(ctx context.Context, status *models.TournamentStatus) ([]models.Tournament, error) {
@@ -169,58 +226,480 @@ func (s *tournamentsService) Create(ctx context.Context, input CreateTournamentI
 	if input.Status == "" {
 		input.Status = models.TournamentStatusPlanned
 	}
+	// Starting goes through SetStatus, which checks that a team is registered.
+	if input.Status != models.TournamentStatusPlanned {
+		return 0, fmt.Errorf("status %q: a tournament starts planned: %w", input.Status, models.ErrValidation)
+	}
+	if input.Format == "" {
+		input.Format = models.TournamentFormatLeague
+	}
+	config := models.DefaultFormatConfig(input.Format)
+	if input.FormatConfig != nil {
+		config = *input.FormatConfig
+	}
+	if err := validateFormat(input.Format, config); err != nil {
+		return 0, err
+	}
 	tournament := models.Tournament{
-		Name:      input.Name,
-		Type:      input.Type,
-		Status:    input.Status,
-		StartDate: input.StartDate,
-		EndDate:   input.EndDate,
-		Note:      input.Note,
+		Name:         input.Name,
+		Format:       input.Format,
+		FormatConfig: config,
+		Status:       input.Status,
+		StartDate:    input.StartDate,
+		EndDate:      input.EndDate,
+		Note:         input.Note,
 	}
 	return s.repo.Create(ctx, tournament)
 }
 
+// Update switching the format without new settings resets them to the
+// defaults of the new format. Only the status of a finished tournament can
+// change.
 func (s *tournamentsService) Update(ctx context.Context, id int64, patch models.TournamentPatch) error {
+	if patch.Status != nil {
+		if err := s.checkTransition(ctx, id, *patch.Status); err != nil {
+			return err
+		}
+	}
+	if patch.Name != nil || patch.Format != nil || patch.FormatConfig != nil || patch.StartDate.Set || patch.EndDate.Set || patch.Note.Set {
+		if err := ensureEditable(ctx, s.repo, id); err != nil {
+			return err
+		}
+	}
+	if patch.Format != nil || patch.FormatConfig != nil {
+		current, err := s.repo.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		format, config := current.Format, current.FormatConfig
+		if patch.Format != nil && *patch.Format != format {
+			format, config = *patch.Format, models.DefaultFormatConfig(*patch.Format)
+		}
+		if patch.FormatConfig != nil {
+			config = *patch.FormatConfig
+		}
+		if err := validateFormat(format, config); err != nil {
+			return err
+		}
+		patch.FormatConfig = &config
+	}
 	return s.repo.Update(ctx, id, patch)
 }
 
+func (s *tournamentsService) SetStatus(ctx context.Context, id int64, status models.TournamentStatus) error {
+	if err := s.checkTransition(ctx, id, status); err != nil {
+		return err
+	}
+	return s.repo.Update(ctx, id, models.TournamentPatch{Status: &status})
+}
+
+// checkTransition applies the lifecycle rules to a status change. Setting
+// the current status again is allowed.
+func (s *tournamentsService) checkTransition(ctx context.Context, id int64, to models.TournamentStatus) error {
+	tournament, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	from := tournament.Status
+	if from == to {
+		return nil
+	}
+	if !CanTransition(from, to) {
+		return fmt.Errorf("status cannot change from %s to %s: %w", from, to, models.ErrValidation)
+	}
+	switch {
+	case from == models.TournamentStatusFinished:
+		if !IsDirector(ctx) {
+			return fmt.Errorf("reopen tournament: %w", models.ErrForbidden)
+		}
+	case to == models.TournamentStatusActive:
+		teams, err := s.rostersRepo.ListTeams(ctx, id)
+		if err != nil {
+			return err
+		}
+		if len(teams) == 0 {
+			return fmt.Errorf("no teams registered: %w", models.ErrValidation)
+		}
+	case to == models.TournamentStatusFinished:
+		matches, err := s.matchesRepo.ListAll(ctx, models.MatchFilter{TournamentID: id})
+		if err != nil {
+			return err
+		}
+		pending := 0
+		for _, match := range matches {
+			if match.Status == models.MatchStatusScheduled {
+				pending++
+			}
+		}
+		if pending > 0 {
+			return fmt.Errorf("%d matches are neither played nor canceled: %w", pending, models.ErrValidation)
+		}
+	}
+	return nil
+}
+
+func (s *tournamentsService) Regulations(ctx context.Context, id int64) (*models.Regulations, error) {
+	return loadRegulations(ctx, s.regulationsRepo, id)
+}
+
+func (s *tournamentsService) SetRegulations(ctx context.Context, regulations models.Regulations) error {
+	if err := validateRegulations(regulations); err != nil {
+		return err
+	}
+	if err := ensureEditable(ctx, s.repo, regulations.TournamentID); err != nil {
+		return err
+	}
+	return s.regulationsRepo.Save(ctx, regulations)
+}
+
+// loadRegulations falls back to the defaults for tournaments without
+// regulations.
+func loadRegulations(ctx context.Context, repo repository.RegulationsRepository, tournamentID int64) (*models.Regulations, error) {
+	regulations, err := repo.Get(ctx, tournamentID)
+	if errors.Is(err, models.ErrNotFound) {
+		defaults := models.DefaultRegulations(tournamentID)
+		return &defaults, nil
+	}
+	return regulations, err
+}
+
+func validateFormat(format models.TournamentFormat, config models.FormatConfig) error {
+	if !format.Valid() {
+		return fmt.Errorf("format %q: %w", format, models.ErrValidation)
+	}
+	if format.HasStandings() {
+		if config.PointsWin <= config.PointsDraw || config.PointsDraw < config.PointsLoss || config.PointsLoss < 0 {
+			return fmt.Errorf("points must rank win over draw over loss: %w", models.ErrValidation)
+		}
+	}
+	if format == models.TournamentFormatLeague && (config.Legs < 1 || config.Legs > 4) {
+		return fmt.Errorf("legs: %w", models.ErrValidation)
+	}
+	if format == models.TournamentFormatGroupPlayoff && (config.Groups < 1 || config.AdvancePerGroup < 1) {
+		return fmt.Errorf("groups: %w", models.ErrValidation)
+	}
+	return nil
+}
+
 // Rosters --------------------------------------------------------------------
 
 type RostersService interface {
 	ListTeamsInTournament(ctx context.Context, tournamentID int64) ([]models.TournamentTeam, error)
+	GetTournamentTeam(ctx context.Context, tournamentID, teamID int64) (*models.TournamentTeam, error)
+	// RegisterTeam enters a team into a tournament or updates its group and
+	// registration date.
+	RegisterTeam(ctx context.Context, input RegisterTeamInput) error
+	// UnregisterTeam withdraws a team that has neither players nor matches in
+	// the tournament.
+	UnregisterTeam(ctx context.Context, tournamentID, teamID int64) error
 	ListRoster(ctx context.Context, tournamentID, teamID int64) ([]models.TournamentRosterEntry, error)
+	// ListRosterOn returns the roster as of the given day: players who had
+	// joined the team by then and were not yet transferred out.
+	ListRosterOn(ctx context.Context, tournamentID, teamID int64, on time.Time) ([]models.TournamentRosterEntry, error)
 	AddPlayer(ctx context.Context, tournamentID, teamID, playerID int64, number *int) error
+	// TransferPlayer moves a player to another team of the tournament. The
+	// old entry is kept with the day it ended, so earlier lineups and events
+	// still count for the old team.
+	TransferPlayer(ctx context.Context, input TransferInput) error
 	UpdateNumber(ctx context.Context, tournamentID, teamID, playerID int64, number *int) error
 	RemovePlayer(ctx context.Context, tournamentID, teamID, playerID int64) error
 	EnsureTeamHasPlayers(ctx context.Context, tournamentID, teamID int64) (bool, error)
 	IsPlayerInRoster(ctx context.Context, tournamentID, teamID, playerID int64) (bool, error)
+	// FreeNumbers suggests the lowest numbers nobody in the team roster has.
+	FreeNumbers(ctx context.Context, tournamentID, teamID int64) ([]int, error)
+	// Eligibility returns the age rules of the tournament together with the
+	// overage places the team has left.
+	Eligibility(ctx context.Context, tournamentID, teamID int64) (*Eligibility, error)
+}
+
+type RegisterTeamInput struct {
+	TournamentID int64
+	TeamID       int64
+	GroupName    *string
+	// RegisteredOn defaults to today.
+	RegisteredOn *time.Time
+}
+
+type TransferInput struct {
+	TournamentID int64
+	PlayerID     int64
+	FromTeamID   int64
+	ToTeamID     int64
+	// Number is the player's number in the new team.
+	Number *int
+	// On is the day of the transfer, today by default.
+	On *time.Time
 }
 
 type rostersService struct {
-	repo repository.RostersRepository
+	repo            repository.RostersRepository
+	matchesRepo     repository.MatchesRepository
+	tournamentsRepo repository.TournamentsRepository
+	regulationsRepo repository.RegulationsRepository
+	playersRepo     repository.PlayersRepository
+	now             func() time.Time
 }
 
-func NewRostersService(repo repository.RostersRepository) RostersService {
-	return &rostersService{repo: repo}
+func NewRostersService(repo repository.RostersRepository, matches repository.MatchesRepository, tournaments repository.TournamentsRepository, regulations repository.RegulationsRepository, players repository.PlayersRepository) RostersService {
+	return &rostersService{repo: repo, matchesRepo: matches, tournamentsRepo: tournaments, regulationsRepo: regulations, playersRepo: players, now: time.Now}
 }
 
 func (s *rostersService) ListTeamsInTournament(ctx context.Context, tournamentID int64) ([]models.TournamentTeam, error) {
 	return s.repo.ListTeams(ctx, tournamentID)
 }
 
+func (s *rostersService) GetTournamentTeam(ctx context.Context, tournamentID, teamID int64) (*models.TournamentTeam, error) {
+	return s.repo.GetTeam(ctx, tournamentID, teamID)
+}
+
+func (s *rostersService) RegisterTeam(ctx context.Context, input RegisterTeamInput) error {
+	if input.TournamentID == 0 || input.TeamID == 0 {
+		return fmt.Errorf("tournament/team: %w", models.ErrValidation)
+	}
+	if err := ensureEditable(ctx, s.tournamentsRepo, input.TournamentID); err != nil {
+		return err
+	}
+	team := models.TournamentTeam{
+		TournamentID: input.TournamentID,
+		TeamID:       input.TeamID,
+		GroupName:    input.GroupName,
+		RegisteredOn: s.now(),
+	}
+	if input.RegisteredOn != nil {
+		team.RegisteredOn = *input.RegisteredOn
+	}
+	return s.repo.RegisterTeam(ctx, team)
+}
+
+func (s *rostersService) UnregisterTeam(ctx context.Context, tournamentID, teamID int64) error {
+	if err := ensureEditable(ctx, s.tournamentsRepo, tournamentID); err != nil {
+		return err
+	}
+	players, err := s.repo.TeamPlayerCount(ctx, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
+	if players > 0 {
+		return fmt.Errorf("team has players in roster: %w", models.ErrValidation)
+	}
+	matches, err := s.matchesRepo.List(ctx, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
+	if len(matches) > 0 {
+		return fmt.Errorf("team has matches in tournament: %w", models.ErrValidation)
+	}
+	return s.repo.UnregisterTeam(ctx, tournamentID, teamID)
+}
+
+// ensureRegistered rejects roster changes for a team that is not entered in
+// the tournament.
+func (s *rostersService) ensureRegistered(ctx context.Context, tournamentID, teamID int64) error {
+	if _, err := s.repo.GetTeam(ctx, tournamentID, teamID); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return fmt.Errorf("team is not registered in tournament: %w", models.ErrValidation)
+		}
+		return err
+	}
+	return nil
+}
+
 func (s *rostersService) ListRoster(ctx context.Context, tournamentID, teamID int64) ([]models.TournamentRosterEntry, error) {
 	return s.repo.ListRoster(ctx, tournamentID, teamID)
 }
 
+func (s *rostersService) ListRosterOn(ctx context.Context, tournamentID, teamID int64, on time.Time) ([]models.TournamentRosterEntry, error) {
+	return s.repo.ListRosterOn(ctx, tournamentID, teamID, on)
+}
+
 func (s *rostersService) AddPlayer(ctx context.Context, tournamentID, teamID, playerID int64, number *int) error {
-	return s.repo.AddPlayer(ctx, tournamentID, teamID, playerID, number)
+	if err := ensureEditable(ctx, s.tournamentsRepo, tournamentID); err != nil {
+		return err
+	}
+	if err := s.ensureRegistered(ctx, tournamentID, teamID); err != nil {
+		return err
+	}
+	inRoster, err := s.repo.IsPlayerInRoster(ctx, tournamentID, teamID, playerID)
+	if err != nil {
+		return err
+	}
+	if inRoster {
+		return fmt.Errorf("player is already in the roster: %w", models.ErrConflict)
+	}
+	if err := s.checkOneTeam(ctx, tournamentID, playerID, teamID); err != nil {
+		return err
+	}
+	if err := s.checkSquad(ctx, tournamentID, teamID, 1); err != nil {
+		return err
+	}
+	if err := s.checkEligible(ctx, tournamentID, teamID, playerID); err != nil {
+		return err
+	}
+	if err := s.checkNumber(ctx, tournamentID, teamID, playerID, number); err != nil {
+		return err
+	}
+	err = s.repo.AddPlayer(ctx, tournamentID, teamID, playerID, number, s.now())
+	return s.numberTaken(ctx, err, tournamentID, teamID, playerID, number)
+}
+
+func (s *rostersService) TransferPlayer(ctx context.Context, input TransferInput) error {
+	if input.TournamentID == 0 || input.PlayerID == 0 || input.FromTeamID == 0 || input.ToTeamID == 0 {
+		return fmt.Errorf("tournament/team/player: %w", models.ErrValidation)
+	}
+	if input.FromTeamID == input.ToTeamID {
+		return fmt.Errorf("player is already in this team: %w", models.ErrValidation)
+	}
+	if err := ensureEditable(ctx, s.tournamentsRepo, input.TournamentID); err != nil {
+		return err
+	}
+	inRoster, err := s.repo.IsPlayerInRoster(ctx, input.TournamentID, input.FromTeamID, input.PlayerID)
+	if err != nil {
+		return err
+	}
+	if !inRoster {
+		return fmt.Errorf("player is not in the team roster: %w", models.ErrValidation)
+	}
+	if err := s.ensureRegistered(ctx, input.TournamentID, input.ToTeamID); err != nil {
+		return err
+	}
+	if inRoster, err = s.repo.IsPlayerInRoster(ctx, input.TournamentID, input.ToTeamID, input.PlayerID); err != nil {
+		return err
+	}
+	if inRoster {
+		return fmt.Errorf("player is already in the new team roster: %w", models.ErrConflict)
+	}
+	if err := s.checkOneTeam(ctx, input.TournamentID, input.PlayerID, input.FromTeamID, input.ToTeamID); err != nil {
+		return err
+	}
+	if err := s.checkSquad(ctx, input.TournamentID, input.FromTeamID, -1); err != nil {
+		return err
+	}
+	if err := s.checkSquad(ctx, input.TournamentID, input.ToTeamID, 1); err != nil {
+		return err
+	}
+	if err := s.checkEligible(ctx, input.TournamentID, input.ToTeamID, input.PlayerID); err != nil {
+		return err
+	}
+	if err := s.checkNumber(ctx, input.TournamentID, input.ToTeamID, input.PlayerID, input.Number); err != nil {
+		return err
+	}
+	on := s.now()
+	if input.On != nil {
+		on = *input.On
+	}
+	err = s.repo.TransferPlayer(ctx, input.TournamentID, input.PlayerID, input.FromTeamID, input.ToTeamID, input.Number, on)
+	return s.numberTaken(ctx, err, input.TournamentID, input.ToTeamID, input.PlayerID, input.Number)
+}
+
+// checkOneTeam enforces the one-team-per-player rule: the player may only
+// be in the rosters of the given teams.
+func (s *rostersService) checkOneTeam(ctx context.Context, tournamentID, playerID int64, teamIDs ...int64) error {
+	regulations, err := loadRegulations(ctx, s.regulationsRepo, tournamentID)
+	if err != nil {
+		return err
+	}
+	if !regulations.OneTeamPerPlayer {
+		return nil
+	}
+	entries, err := s.playersRepo.ListAssignments(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.TournamentID != tournamentID || entry.LeftOn != nil || slices.Contains(teamIDs, entry.TeamID) {
+			continue
+		}
+		return fmt.Errorf("player is in the roster of %s, transfer them instead: %w", entry.TeamName, models.ErrConflict)
+	}
+	return nil
 }
 
 func (s *rostersService) UpdateNumber(ctx context.Context, tournamentID, teamID, playerID int64, number *int) error {
-	return s.repo.UpdateNumber(ctx, tournamentID, teamID, playerID, number)
+	if err := ensureEditable(ctx, s.tournamentsRepo, tournamentID); err != nil {
+		return err
+	}
+	if err := s.checkNumber(ctx, tournamentID, teamID, playerID, number); err != nil {
+		return err
+	}
+	err := s.repo.UpdateNumber(ctx, tournamentID, teamID, playerID, number)
+	return s.numberTaken(ctx, err, tournamentID, teamID, playerID, number)
+}
+
+// checkNumber keeps numbers unique within the team roster.
+func (s *rostersService) checkNumber(ctx context.Context, tournamentID, teamID, playerID int64, number *int) error {
+	if number == nil {
+		return nil
+	}
+	entries, err := s.repo.ListRoster(ctx, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
+	return checkNumber(rosterNumbers(entries, playerID), number)
+}
+
+// numberTaken names the holder when the write lost a race for the number
+// checked just before.
+func (s *rostersService) numberTaken(ctx context.Context, err error, tournamentID, teamID, playerID int64, number *int) error {
+	if !errors.Is(err, models.ErrConflict) {
+		return err
+	}
+	if taken := s.checkNumber(ctx, tournamentID, teamID, playerID, number); errors.Is(taken, models.ErrConflict) {
+		return taken
+	}
+	return err
+}
+
+func (s *rostersService) FreeNumbers(ctx context.Context, tournamentID, teamID int64) ([]int, error) {
+	entries, err := s.repo.ListRoster(ctx, tournamentID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return freeNumbers(rosterNumbers(entries, 0)), nil
+}
+
+func (s *rostersService) Eligibility(ctx context.Context, tournamentID, teamID int64) (*Eligibility, error) {
+	regulations, err := loadRegulations(ctx, s.regulationsRepo, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := s.repo.ListRoster(ctx, tournamentID, teamID)
+	if err != nil {
+		return nil, err
+	}
+	return newEligibility(*regulations, entries), nil
+}
+
+// checkEligible applies the age rules to a player entering the roster. The
+// player's own entry, if any, does not use up an overage place.
+func (s *rostersService) checkEligible(ctx context.Context, tournamentID, teamID, playerID int64) error {
+	regulations, err := loadRegulations(ctx, s.regulationsRepo, tournamentID)
+	if err != nil {
+		return err
+	}
+	if !regulations.HasAgeLimits() {
+		return nil
+	}
+	player, err := s.playersRepo.Get(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	entries, err := s.repo.ListRoster(ctx, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
+	var others []models.TournamentRosterEntry
+	for _, entry := range entries {
+		if entry.PlayerID != playerID {
+			others = append(others, entry)
+		}
+	}
+	return newEligibility(*regulations, others).Check(*player)
 }
 
 func (s *rostersService) RemovePlayer(ctx context.Context, tournamentID, teamID, playerID int64) error {
+	if err := ensureEditable(ctx, s.tournamentsRepo, tournamentID); err != nil {
+		return err
+	}
 	involved, err := s.repo.PlayerParticipation(ctx, tournamentID, teamID, playerID)
 	if err != nil {
 		return err
@@ -228,9 +707,43 @@ func (s *rostersService) RemovePlayer(ctx context.Context, tournamentID, teamID,
 	if involved {
 		return fmt.Errorf("player has participation records: %w", models.ErrValidation)
 	}
+	if err := s.checkSquad(ctx, tournamentID, teamID, -1); err != nil {
+		return err
+	}
 	return s.repo.RemovePlayer(ctx, tournamentID, teamID, playerID)
 }
 
+// checkSquad applies the registration window and the squad size limits to a
+// roster growing or shrinking by delta. A complete squad may not drop below
+// the minimum, while one still being built may change freely. Directors are
+// not bound by these rules.
+func (s *rostersService) checkSquad(ctx context.Context, tournamentID, teamID int64, delta int) error {
+	if IsDirector(ctx) {
+		return nil
+	}
+	regulations, err := loadRegulations(ctx, s.regulationsRepo, tournamentID)
+	if err != nil {
+		return err
+	}
+	if err := checkRosterWindow(*regulations, s.now()); err != nil {
+		return err
+	}
+	if regulations.MinSquad == nil && regulations.MaxSquad == nil {
+		return nil
+	}
+	count, err := s.repo.TeamPlayerCount(ctx, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
+	if limit := regulations.MaxSquad; delta > 0 && limit != nil && count+delta > *limit {
+		return fmt.Errorf("roster is full, %d players allowed: %w", *limit, models.ErrValidation)
+	}
+	if limit := regulations.MinSquad; delta < 0 && limit != nil && count >= *limit && count+delta < *limit {
+		return fmt.Errorf("roster cannot drop below %d players: %w", *limit, models.ErrValidation)
+	}
+	return nil
+}
+
 func (s *rostersService) EnsureTeamHasPlayers(ctx context.Context, tournamentID, teamID int64) (bool, error) {
 	count, err := s.repo.TeamPlayerCount(ctx, tournamentID, teamID)
 	if err != nil {
@@ -246,10 +759,25 @@ func (s *rostersService) IsPlayerInRoster(ctx context.Context, tournamentID, tea
 // Matches --------------------------------------------------------------------
 
 type MatchesService interface {
+	// List returns the matches of a team in a tournament by start time.
 	List(ctx context.Context, tournamentID, teamID int64) ([]models.Match, error)
+	// ListAll returns the matches the filter selects, by start time.
+	ListAll(ctx context.Context, filter models.MatchFilter) ([]models.Match, error)
 	Get(ctx context.Context, id int64) (*models.Match, error)
 	Create(ctx context.Context, input CreateMatchInput) (int64, error)
+	// CreateMany validates every input first and then creates all matches in
+	// one transaction.
+	CreateMany(ctx context.Context, inputs []CreateMatchInput) ([]int64, error)
+	// Import creates or, by ExternalUID, updates matches from a calendar in
+	// one transaction. Scores are never touched: an input with
+	// MatchStatusCanceled cancels the match it updates only while it is
+	// scheduled, and a scheduled input restores a match canceled that way.
+	Import(ctx context.Context, inputs []CreateMatchInput) (created, updated int, err error)
 	Update(ctx context.Context, id int64, patch models.MatchPatch) error
+	// Standings totals the played matches of the club teams registered in a
+	// tournament, with points as the tournament format awards them, ordered
+	// by group and then by points.
+	Standings(ctx context.Context, tournamentID int64) ([]models.StandingRow, error)
 }
 
 type CreateMatchInput struct {
@@ -259,41 +787,97 @@ type CreateMatchInput struct {
 	StartTime    time.Time
 	Location     *string
 	Status       models.MatchStatus
+	// ExternalUID links the match to a calendar event; see Import.
+	ExternalUID *string
+	Home        *bool
+	Stage       *models.MatchStage
 }
 
 type matchesService struct {
-	repo        repository.MatchesRepository
-	rostersRepo repository.RostersRepository
+	repo            repository.MatchesRepository
+	rostersRepo     repository.RostersRepository
+	tournamentsRepo repository.TournamentsRepository
 }
 
-func NewMatchesService(repo repository.MatchesRepository, rosters repository.RostersRepository) MatchesService {
-	return &matchesService{repo: repo, rostersRepo: rosters}
+func NewMatchesService(repo repository.MatchesRepository, rosters repository.RostersRepository, tournaments repository.TournamentsRepository) MatchesService {
+	return &matchesService{repo: repo, rostersRepo: rosters, tournamentsRepo: tournaments}
 }
 
 func (s *matchesService) List(ctx context.Context, tournamentID, teamID int64) ([]models.Match, error) {
+	if tournamentID == 0 || teamID == 0 {
+		return nil, fmt.Errorf("tournament and team are required: %w", models.ErrValidation)
+	}
 	return s.repo.List(ctx, tournamentID, teamID)
 }
 
+func (s *matchesService) ListAll(ctx context.Context, filter models.MatchFilter) ([]models.Match, error) {
+	return s.repo.ListAll(ctx, filter)
+}
+
 func (s *matchesService) Get(ctx context.Context, id int64) (*models.Match, error) {
 	return s.repo.Get(ctx, id)
 }
 
 func (s *matchesService) Create(ctx context.Context, input CreateMatchInput) (int64, error) {
+	match, err := s.prepare(ctx, input)
+	if err != nil {
+		return 0, err
+	}
+	return s.repo.Create(ctx, match)
+}
+
+func (s *matchesService) CreateMany(ctx context.Context, inputs []CreateMatchInput) ([]int64, error) {
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("no matches: %w", models.ErrValidation)
+	}
+	matches := make([]models.Match, 0, len(inputs))
+	for i, input := range inputs {
+		match, err := s.prepare(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("match %d: %w", i+1, err)
+		}
+		matches = append(matches, match)
+	}
+	return s.repo.CreateMany(ctx, matches)
+}
+
+func (s *matchesService) Import(ctx context.Context, inputs []CreateMatchInput) (int, int, error) {
+	if len(inputs) == 0 {
+		return 0, 0, fmt.Errorf("no matches: %w", models.ErrValidation)
+	}
+	matches := make([]models.Match, 0, len(inputs))
+	for i, input := range inputs {
+		if input.ExternalUID == nil || *input.ExternalUID == "" {
+			return 0, 0, fmt.Errorf("match %d: external_uid: %w", i+1, models.ErrValidation)
+		}
+		match, err := s.prepare(ctx, input)
+		if err != nil {
+			return 0, 0, fmt.Errorf("match %d: %w", i+1, err)
+		}
+		matches = append(matches, match)
+	}
+	return s.repo.Import(ctx, matches)
+}
+
+// prepare validates the input and builds the match to insert.
+func (s *matchesService) prepare(ctx context.Context, input CreateMatchInput) (models.Match, error) {
 	if input.TournamentID == 0 || input.TeamID == 0 {
-		return 0, fmt.Errorf("tournament/team: %w", models.ErrValidation)
+		return models.Match{}, fmt.Errorf("tournament/team: %w", models.ErrValidation)
 	}
 	if input.Opponent == "" {
-		return 0, fmt.Errorf("opponent: %w", models.ErrValidation)
+		return models.Match{}, fmt.Errorf("opponent: %w", models.ErrValidation)
 	}
 	if input.StartTime.IsZero() {
-		return 0, fmt.Errorf("start_time: %w", models.ErrValidation)
+		return models.Match{}, fmt.Errorf("start_time: %w", models.ErrValidation)
 	}
-	hasPlayers, err := s.rostersRepo.TeamPlayerCount(ctx, input.TournamentID, input.TeamID)
-	if err != nil {
-		return 0, err
+	if err := ensureEditable(ctx, s.tournamentsRepo, input.TournamentID); err != nil {
+		return models.Match{}, err
 	}
-	if hasPlayers == 0 {
-		return 0, fmt.Errorf("team has no players in roster: %w", models.ErrValidation)
+	if _, err := s.rostersRepo.GetTeam(ctx, input.TournamentID, input.TeamID); err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			return models.Match{}, fmt.Errorf("team is not registered in tournament: %w", models.ErrValidation)
+		}
+		return models.Match{}, err
 	}
 	match := models.Match{
 		TournamentID: input.TournamentID,
@@ -302,14 +886,28 @@ func (s *matchesService) Create(ctx context.Context, input CreateMatchInput) (in
 		StartTime:    input.StartTime,
 		Location:     input.Location,
 		Status:       input.Status,
+		ExternalUID:  input.ExternalUID,
+		Home:         input.Home,
+		Stage:        input.Stage,
 	}
 	if match.Status == "" {
 		match.Status = models.MatchStatusScheduled
 	}
-	return s.repo.Create(ctx, match)
+	return match, nil
 }
 
 func (s *matchesService) Update(ctx context.Context, id int64, patch models.MatchPatch) error {
+	match, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := ensureEditable(ctx, s.tournamentsRepo, match.TournamentID); err != nil {
+		return err
+	}
+	tournament, err := s.tournamentsRepo.Get(ctx, match.TournamentID)
+	if err != nil {
+		return err
+	}
 	if patch.Status != nil && *patch.Status == models.MatchStatusCanceled {
 		patch.ScoreHT = models.NewOptionalString(nil)
 		patch.ScoreFT = models.NewOptionalString(nil)
@@ -318,9 +916,40 @@ func (s *matchesService) Update(ctx context.Context, id int64, patch models.Matc
 		patch.ScoreFinalUs = models.NewOptionalInt(nil)
 		patch.ScoreFinalThem = models.NewOptionalInt(nil)
 	}
+	if scoresGiven(patch) && !tournament.Format.Scored() {
+		return fmt.Errorf("scores are not kept in %s tournaments: %w", tournament.Format, models.ErrValidation)
+	}
 	return s.repo.Update(ctx, id, patch)
 }
 
+// scoresGiven reports whether the patch sets any score; clearing is allowed
+// in every format.
+func scoresGiven(patch models.MatchPatch) bool {
+	for _, score := range []models.OptionalString{patch.ScoreHT, patch.ScoreFT, patch.ScoreET, patch.ScorePEN} {
+		if score.Set && score.Value != nil {
+			return true
+		}
+	}
+	return (patch.ScoreFinalUs.Set && patch.ScoreFinalUs.Value != nil) ||
+		(patch.ScoreFinalThem.Set && patch.ScoreFinalThem.Value != nil)
+}
+
+func (s *matchesService) Standings(ctx context.Context, tournamentID int64) ([]models.StandingRow, error) {
+	tournament, err := s.tournamentsRepo.Get(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.rostersRepo.ListTeams(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.repo.ListAll(ctx, models.MatchFilter{TournamentID: tournamentID})
+	if err != nil {
+		return nil, err
+	}
+	return standings(tournament, teams, matches), nil
+}
+
 // Lineup ---------------------------------------------------------------------
 
 type LineupService interface {
@@ -328,16 +957,20 @@ type LineupService interface {
 	Upsert(ctx context.Context, matchID, playerID int64, role models.LineupRole, numberOverride *int, note *string) error
 	Update(ctx context.Context, matchID, playerID int64, patch models.LineupPatch) error
 	Remove(ctx context.Context, matchID, playerID int64) error
+	// FreeNumbers suggests the lowest numbers nobody wears in the match.
+	FreeNumbers(ctx context.Context, matchID int64) ([]int, error)
 }
 
 type lineupService struct {
-	repo        repository.LineupRepository
-	matchesRepo repository.MatchesRepository
-	rosterRepo  repository.RostersRepository
+	repo            repository.LineupRepository
+	matchesRepo     repository.MatchesRepository
+	rosterRepo      repository.RostersRepository
+	regulationsRepo repository.RegulationsRepository
+	tournamentsRepo repository.TournamentsRepository
 }
 
-func NewLineupService(repo repository.LineupRepository, matches repository.MatchesRepository, rosters repository.RostersRepository) LineupService {
-	return &lineupService{repo: repo, matchesRepo: matches, rosterRepo: rosters}
+func NewLineupService(repo repository.LineupRepository, matches repository.MatchesRepository, rosters repository.RostersRepository, regulations repository.RegulationsRepository, tournaments repository.TournamentsRepository) LineupService {
+	return &lineupService{repo: repo, matchesRepo: matches, rosterRepo: rosters, regulationsRepo: regulations, tournamentsRepo: tournaments}
 }
 
 func (s *lineupService) Get(ctx context.Context, matchID int64) ([]models.MatchLineup, error) {
@@ -349,7 +982,10 @@ func (s *lineupService) Upsert(ctx context.Context, matchID, playerID int64, rol
 	if err != nil {
 		return err
 	}
-	inRoster, err := s.rosterRepo.IsPlayerInRoster(ctx, match.TournamentID, match.TeamID, playerID)
+	if err := ensureEditable(ctx, s.tournamentsRepo, match.TournamentID); err != nil {
+		return err
+	}
+	inRoster, err := s.rosterRepo.IsPlayerInRosterOn(ctx, match.TournamentID, match.TeamID, playerID, match.StartTime)
 	if err != nil {
 		return err
 	}
@@ -359,22 +995,114 @@ func (s *lineupService) Upsert(ctx context.Context, matchID, playerID int64, rol
 	if role != models.LineupRoleStart && role != models.LineupRoleSub {
 		return fmt.Errorf("invalid role: %w", models.ErrValidation)
 	}
+	if err := s.checkRole(ctx, match, playerID, role); err != nil {
+		return err
+	}
+	number := numberOverride
+	if number == nil {
+		entries, err := s.rosterRepo.ListRosterOn(ctx, match.TournamentID, match.TeamID, match.StartTime)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if entry.PlayerID == playerID {
+				number = entry.TournamentNumber
+			}
+		}
+	}
+	if err := s.checkNumber(ctx, matchID, playerID, number); err != nil {
+		return err
+	}
 	return s.repo.Upsert(ctx, matchID, playerID, role, numberOverride, note)
 }
 
 func (s *lineupService) Update(ctx context.Context, matchID, playerID int64, patch models.LineupPatch) error {
+	match, err := s.editableMatch(ctx, matchID)
+	if err != nil {
+		return err
+	}
 	if patch.Role != nil {
 		if *patch.Role != models.LineupRoleStart && *patch.Role != models.LineupRoleSub {
 			return fmt.Errorf("invalid role: %w", models.ErrValidation)
 		}
+		if err := s.checkRole(ctx, match, playerID, *patch.Role); err != nil {
+			return err
+		}
+	}
+	if patch.NumberOverride.Set {
+		number := patch.NumberOverride.Value
+		if number == nil {
+			// Without the override the roster number is worn again.
+			lineup, err := s.repo.Get(ctx, matchID)
+			if err != nil {
+				return err
+			}
+			for _, entry := range lineup {
+				if entry.PlayerID == playerID {
+					number = entry.RosterNumber
+				}
+			}
+		}
+		if err := s.checkNumber(ctx, matchID, playerID, number); err != nil {
+			return err
+		}
 	}
 	return s.repo.Update(ctx, matchID, playerID, patch)
 }
 
+// checkNumber keeps the numbers worn in a match unique, overrides included.
+func (s *lineupService) checkNumber(ctx context.Context, matchID, playerID int64, number *int) error {
+	if number == nil {
+		return nil
+	}
+	lineup, err := s.repo.Get(ctx, matchID)
+	if err != nil {
+		return err
+	}
+	return checkNumber(lineupNumbers(lineup, playerID), number)
+}
+
+func (s *lineupService) FreeNumbers(ctx context.Context, matchID int64) ([]int, error) {
+	lineup, err := s.repo.Get(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+	return freeNumbers(lineupNumbers(lineup, 0)), nil
+}
+
+// checkRole keeps the lineup within the starter and bench limits of the
+// tournament regulations.
+func (s *lineupService) checkRole(ctx context.Context, match *models.Match, playerID int64, role models.LineupRole) error {
+	regulations, err := loadRegulations(ctx, s.regulationsRepo, match.TournamentID)
+	if err != nil {
+		return err
+	}
+	lineup, err := s.repo.Get(ctx, match.ID)
+	if err != nil {
+		return err
+	}
+	return checkLineupRole(*regulations, lineup, playerID, role)
+}
+
 func (s *lineupService) Remove(ctx context.Context, matchID, playerID int64) error {
+	if _, err := s.editableMatch(ctx, matchID); err != nil {
+		return err
+	}
 	return s.repo.Remove(ctx, matchID, playerID)
 }
 
+// editableMatch returns the match unless its tournament is finished.
+func (s *lineupService) editableMatch(ctx context.Context, matchID int64) (*models.Match, error) {
+	match, err := s.matchesRepo.Get(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureEditable(ctx, s.tournamentsRepo, match.TournamentID); err != nil {
+		return nil, err
+	}
+	return match, nil
+}
+
 // Events ---------------------------------------------------------------------
 
 type EventsService interface {
@@ -385,13 +1113,16 @@ type EventsService interface {
 }
 
 type eventsService struct {
-	repo        repository.EventsRepository
-	matchesRepo repository.MatchesRepository
-	rosterRepo  repository.RostersRepository
+	repo            repository.EventsRepository
+	matchesRepo     repository.MatchesRepository
+	rosterRepo      repository.RostersRepository
+	lineupRepo      repository.LineupRepository
+	regulationsRepo repository.RegulationsRepository
+	tournamentsRepo repository.TournamentsRepository
 }
 
-func NewEventsService(repo repository.EventsRepository, matches repository.MatchesRepository, rosters repository.RostersRepository) EventsService {
-	return &eventsService{repo: repo, matchesRepo: matches, rosterRepo: rosters}
+func NewEventsService(repo repository.EventsRepository, matches repository.MatchesRepository, rosters repository.RostersRepository, lineup repository.LineupRepository, regulations repository.RegulationsRepository, tournaments repository.TournamentsRepository) EventsService {
+	return &eventsService{repo: repo, matchesRepo: matches, rosterRepo: rosters, lineupRepo: lineup, regulationsRepo: regulations, tournamentsRepo: tournaments}
 }
 
 func (s *eventsService) List(ctx context.Context, matchID int64) ([]models.MatchEvent, error) {
@@ -409,6 +1140,9 @@ func (s *eventsService) AddGoal(ctx context.Context, matchID, playerID int64, ti
 	if err := s.ensureRoster(ctx, match, []int64{playerID}); err != nil {
 		return err
 	}
+	if _, _, err := s.checkRegulations(ctx, match, timeText); err != nil {
+		return err
+	}
 	event := models.MatchEvent{
 		MatchID:       matchID,
 		EventType:     models.MatchEventGoal,
@@ -433,6 +1167,9 @@ func (s *eventsService) AddCard(ctx context.Context, matchID, playerID int64, ca
 	if err := s.ensureRoster(ctx, match, []int64{playerID}); err != nil {
 		return err
 	}
+	if _, _, err := s.checkRegulations(ctx, match, timeText); err != nil {
+		return err
+	}
 	event := models.MatchEvent{
 		MatchID:       matchID,
 		EventType:     models.MatchEventCard,
@@ -458,6 +1195,25 @@ func (s *eventsService) AddSub(ctx context.Context, matchID, playerOutID, player
 	if playerOutID == playerInID {
 		return fmt.Errorf("players identical: %w", models.ErrValidation)
 	}
+	regulations, lineup, err := s.checkRegulations(ctx, match, timeText)
+	if err != nil {
+		return err
+	}
+	events, err := s.repo.List(ctx, matchID)
+	if err != nil {
+		return err
+	}
+	roster, err := s.rosterRepo.ListRosterOn(ctx, match.TournamentID, match.TeamID, match.StartTime)
+	if err != nil {
+		return err
+	}
+	names := make(map[int64]string, len(roster))
+	for _, entry := range roster {
+		names[entry.PlayerID] = entry.PlayerName
+	}
+	if err := checkSub(*regulations, lineup, events, playerOutID, playerInID, names); err != nil {
+		return err
+	}
 	event := models.MatchEvent{
 		MatchID:       matchID,
 		EventType:     models.MatchEventSub,
@@ -469,9 +1225,33 @@ func (s *eventsService) AddSub(ctx context.Context, matchID, playerOutID, player
 	return err
 }
 
+// checkRegulations rejects events of finished tournaments and validates the
+// event time and the lineup against the
+// tournament regulations and returns both for further checks.
+func (s *eventsService) checkRegulations(ctx context.Context, match *models.Match, timeText string) (*models.Regulations, []models.MatchLineup, error) {
+	if err := ensureEditable(ctx, s.tournamentsRepo, match.TournamentID); err != nil {
+		return nil, nil, err
+	}
+	regulations, err := loadRegulations(ctx, s.regulationsRepo, match.TournamentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkEventTime(*regulations, match, timeText); err != nil {
+		return nil, nil, err
+	}
+	lineup, err := s.lineupRepo.Get(ctx, match.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkLineupComplete(*regulations, lineup); err != nil {
+		return nil, nil, err
+	}
+	return regulations, lineup, nil
+}
+
 func (s *eventsService) ensureRoster(ctx context.Context, match *models.Match, playerIDs []int64) error {
 	for _, id := range playerIDs {
-		inRoster, err := s.rosterRepo.IsPlayerInRoster(ctx, match.TournamentID, match.TeamID, id)
+		inRoster, err := s.rosterRepo.IsPlayerInRosterOn(ctx, match.TournamentID, match.TeamID, id, match.StartTime)
 		if err != nil {
 			return err
 		}
@@ -485,9 +1265,13 @@ func (s *eventsService) ensureRoster(ctx context.Context, match *models.Match, p
 // Sessions -------------------------------------------------------------------
 
 type SessionService interface {
-	Get(ctx context.Context, adminID int64) (*models.AdminSession, error)
+	Get(ctx context.Context, key models.SessionKey) (*models.AdminSession, error)
 	Save(ctx context.Context, session models.AdminSession) error
-	Delete(ctx context.Context, adminID int64) error
+	Delete(ctx context.Context, key models.SessionKey) error
+	DeleteStale(ctx context.Context, before time.Time) (int64, error)
+	// ExpireWizards closes wizards idle since before, keeping the navigation
+	// history.
+	ExpireWizards(ctx context.Context, before time.Time) (int64, error)
 }
 
 type sessionService struct {
@@ -498,8 +1282,8 @@ func NewSessionService(repo repository.SessionsRepository) SessionService {
 	return &sessionService{repo: repo}
 }
 
-func (s *sessionService) Get(ctx context.Context, adminID int64) (*models.AdminSession, error) {
-	session, err := s.repo.Get(ctx, adminID)
+func (s *sessionService) Get(ctx context.Context, key models.SessionKey) (*models.AdminSession, error) {
+	session, err := s.repo.Get(ctx, key)
 	if err != nil {
 		if errors.Is(err, models.ErrNotFound) {
 			return nil, nil
@@ -513,6 +1297,227 @@ func (s *sessionService) Save(ctx context.Context, session models.AdminSession)
 	return s.repo.Upsert(ctx, session)
 }
 
-func (s *sessionService) Delete(ctx context.Context, adminID int64) error {
-	return s.repo.Delete(ctx, adminID)
+func (s *sessionService) Delete(ctx context.Context, key models.SessionKey) error {
+	return s.repo.Delete(ctx, key)
+}
+
+func (s *sessionService) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	return s.repo.DeleteStale(ctx, before)
+}
+
+func (s *sessionService) ExpireWizards(ctx context.Context, before time.Time) (int64, error) {
+	return s.repo.ExpireWizards(ctx, before)
+}
+
+// Callbacks ------------------------------------------------------------------
+
+// callbackTTL bounds how long an inline keyboard stays usable.
+const callbackTTL = 7 * 24 * time.Hour
+
+// CallbackService keeps the actions behind inline buttons in the database and
+// hands out short signed tokens to put into callback_data instead.
+type CallbackService interface {
+	Register(ctx context.Context, actions []models.NavigationEntry) ([]string, error)
+	Resolve(ctx context.Context, token string) (*models.NavigationEntry, error)
+	Purge(ctx context.Context) (int64, error)
+}
+
+type callbackService struct {
+	repo   repository.CallbacksRepository
+	secret []byte
+	now    func() time.Time
+}
+
+func NewCallbackService(repo repository.CallbacksRepository, secret []byte) CallbackService {
+	return &callbackService{repo: repo, secret: secret, now: time.Now}
+}
+
+// Register stores the actions of one keyboard and returns a token per action,
+// in the same order.
+func (s *callbackService) Register(ctx context.Context, actions []models.NavigationEntry) ([]string, error) {
+	if len(actions) == 0 {
+		return nil, nil
+	}
+	id, err := s.repo.Create(ctx, models.CallbackKeyboard{
+		Actions:   actions,
+		ExpiresAt: s.now().Add(callbackTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]string, len(actions))
+	for i := range actions {
+		tokens[i] = signCallbackToken(s.secret, id, i)
+	}
+	return tokens, nil
+}
+
+// Resolve returns the action behind a token. Forged tokens fail with
+// ErrValidation, expired or unknown ones with ErrNotFound.
+func (s *callbackService) Resolve(ctx context.Context, token string) (*models.NavigationEntry, error) {
+	id, index, err := parseCallbackToken(s.secret, token)
+	if err != nil {
+		return nil, err
+	}
+	keyboard, err := s.repo.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if index >= len(keyboard.Actions) {
+		return nil, fmt.Errorf("callback %d/%d: %w", id, index, models.ErrNotFound)
+	}
+	action := keyboard.Actions[index]
+	return &action, nil
+}
+
+func (s *callbackService) Purge(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx)
+}
+
+// Brackets -------------------------------------------------------------------
+
+// BracketsService keeps the knockout bracket of a cup. Winners are carried
+// to the next stage as soon as a tie is decided: by the club match of the tie
+// or by a result entered by hand for ties between other teams.
+type BracketsService interface {
+	Get(ctx context.Context, tournamentID int64) (*models.Bracket, error)
+	// Create draws a new bracket, replacing the old one. Entrants are paired
+	// in the order given; when their number is not a power of two the first
+	// ones get a bye.
+	Create(ctx context.Context, tournamentID int64, entrants []string) error
+	// SetWinner enters the result of a tie without a club match; an empty
+	// winner clears it.
+	SetWinner(ctx context.Context, tournamentID int64, stage models.MatchStage, position int, winner string) error
+}
+
+type bracketsService struct {
+	repo            repository.BracketsRepository
+	matchesRepo     repository.MatchesRepository
+	rostersRepo     repository.RostersRepository
+	tournamentsRepo repository.TournamentsRepository
+}
+
+func NewBracketsService(repo repository.BracketsRepository, matches repository.MatchesRepository, rosters repository.RostersRepository, tournaments repository.TournamentsRepository) BracketsService {
+	return &bracketsService{repo: repo, matchesRepo: matches, rostersRepo: rosters, tournamentsRepo: tournaments}
+}
+
+func (s *bracketsService) Get(ctx context.Context, tournamentID int64) (*models.Bracket, error) {
+	ties, err := s.repo.List(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := s.matchesRepo.ListAll(ctx, models.MatchFilter{TournamentID: tournamentID})
+	if err != nil {
+		return nil, err
+	}
+	teams, err := s.rostersRepo.ListTeams(ctx, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	return resolveBracket(tournamentID, ties, matches, teams), nil
+}
+
+func (s *bracketsService) Create(ctx context.Context, tournamentID int64, entrants []string) error {
+	if err := ensureEditable(ctx, s.tournamentsRepo, tournamentID); err != nil {
+		return err
+	}
+	ties, err := DrawBracket(tournamentID, entrants)
+	if err != nil {
+		return err
+	}
+	return s.repo.Replace(ctx, tournamentID, ties)
+}
+
+func (s *bracketsService) SetWinner(ctx context.Context, tournamentID int64, stage models.MatchStage, position int, winner string) error {
+	if err := ensureEditable(ctx, s.tournamentsRepo, tournamentID); err != nil {
+		return err
+	}
+	bracket, err := s.Get(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	tie := bracket.Tie(stage, position)
+	if tie == nil {
+		return models.ErrNotFound
+	}
+	if winner == "" {
+		return s.repo.SetWinner(ctx, tournamentID, stage, position, nil)
+	}
+	if tie.Home == "" || tie.Away == "" {
+		return fmt.Errorf("tie entrants are not known yet: %w", models.ErrValidation)
+	}
+	if tie.Match != nil && !tie.Manual && tie.Winner != "" {
+		return fmt.Errorf("tie is decided by its match: %w", models.ErrValidation)
+	}
+	if winner != tie.Home && winner != tie.Away {
+		return fmt.Errorf("winner must play in the tie: %w", models.ErrValidation)
+	}
+	return s.repo.SetWinner(ctx, tournamentID, stage, position, &winner)
+}
+
+// Calendars ------------------------------------------------------------------
+
+// CalendarService exports match schedules as iCalendar files and signs the
+// URLs of the public feeds.
+type CalendarService interface {
+	// Build renders the matches of a team, a tournament or the whole club (id
+	// is ignored) and returns the file with a suggested file name.
+	Build(ctx context.Context, scope models.CalendarScope, id int64) ([]byte, string, error)
+	// FeedToken is the secret part of the feed URL of a scope.
+	FeedToken(scope models.CalendarScope, id int64) string
+	// CheckFeedToken reports whether token opens the feed of the scope.
+	CheckFeedToken(scope models.CalendarScope, id int64, token string) bool
+}
+
+type calendarService struct {
+	matches     MatchesService
+	teams       repository.TeamsRepository
+	tournaments repository.TournamentsRepository
+	secret      []byte
+}
+
+func NewCalendarService(matches MatchesService, teams repository.TeamsRepository, tournaments repository.TournamentsRepository, secret []byte) CalendarService {
+	return &calendarService{matches: matches, teams: teams, tournaments: tournaments, secret: secret}
+}
+
+func (s *calendarService) Build(ctx context.Context, scope models.CalendarScope, id int64) ([]byte, string, error) {
+	var (
+		filter         models.MatchFilter
+		name, fileName string
+	)
+	switch scope {
+	case models.CalendarScopeTeam:
+		team, err := s.teams.Get(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.TeamID, name, fileName = id, team.Name, "team-"+team.ShortCode
+	case models.CalendarScopeTournament:
+		tournament, err := s.tournaments.Get(ctx, id)
+		if err != nil {
+			return nil, "", err
+		}
+		filter.TournamentID, name, fileName = id, tournament.Name, fmt.Sprintf("tournament-%d", id)
+	case models.CalendarScopeClub:
+		name, fileName = "Матчи клуба", "club"
+	default:
+		return nil, "", fmt.Errorf("calendar scope %q: %w", scope, models.ErrValidation)
+	}
+	matches, err := s.matches.ListAll(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := s.render(ctx, name, matches)
+	if err != nil {
+		return nil, "", err
+	}
+	return data, calendarFileName(fileName), nil
+}
+
+func (s *calendarService) FeedToken(scope models.CalendarScope, id int64) string {
+	return feedSignature(s.secret, scope, id)
+}
+
+func (s *calendarService) CheckFeedToken(scope models.CalendarScope, id int64, token string) bool {
+	return checkFeedSignature(s.secret, scope, id, token)
 }
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"sort"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+// standings totals the played matches with a final score for each club team.
+// Opponents are not tracked, so this is not a league table: the rows come
+// group by group, teams without a group first, and by points within a group.
+// Knockout matches are left out: they decide the bracket, not the results.
+func standings(tournament *models.Tournament, teams []models.TournamentTeam, matches []models.Match) []models.StandingRow {
+	rows := make([]models.StandingRow, 0, len(teams))
+	index := make(map[int64]int, len(teams))
+	for _, team := range teams {
+		index[team.TeamID] = len(rows)
+		rows = append(rows, models.StandingRow{TeamID: team.TeamID, TeamName: team.TeamName, GroupName: team.GroupName})
+	}
+	config := tournament.FormatConfig
+	for _, match := range matches {
+		i, ok := index[match.TeamID]
+		if !ok || match.Status != models.MatchStatusPlayed || match.ScoreFinalUs == nil || match.ScoreFinalThem == nil {
+			continue
+		}
+		if match.Stage != nil && *match.Stage != models.MatchStageGroup {
+			continue
+		}
+		row := &rows[i]
+		us, them := *match.ScoreFinalUs, *match.ScoreFinalThem
+		row.Played++
+		row.GoalsFor += us
+		row.GoalsAgainst += them
+		switch {
+		case us > them:
+			row.Won++
+			row.Points += config.PointsWin
+		case us == them:
+			row.Drawn++
+			row.Points += config.PointsDraw
+		default:
+			row.Lost++
+			row.Points += config.PointsLoss
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if ga, gb := deref(a.GroupName), deref(b.GroupName); ga != gb {
+			return ga < gb
+		}
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if diff := (a.GoalsFor - a.GoalsAgainst) - (b.GoalsFor - b.GoalsAgainst); diff != 0 {
+			return diff > 0
+		}
+		return a.GoalsFor > b.GoalsFor
+	})
+	return rows
+}
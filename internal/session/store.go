@@ -3,6 +3,7 @@ package session
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/dynamost/telegram-bot/internal/models"
 	"github.com/dynamost/telegram-bot/internal/service"
@@ -21,8 +22,8 @@ type persistedSession struct {
 	Nav    []models.NavigationEntry `json:"nav,omitempty"`
 }
 
-func (s *Store) Load(ctx context.Context, adminID int64, wizardOut any, navOut *[]models.NavigationEntry) (*models.AdminSession, error) {
-	session, err := s.sessions.Get(ctx, adminID)
+func (s *Store) Load(ctx context.Context, key models.SessionKey, wizardOut any, navOut *[]models.NavigationEntry) (*models.AdminSession, error) {
+	session, err := s.sessions.Get(ctx, key)
 	if err != nil || session == nil {
 		if navOut != nil {
 			*navOut = nil
@@ -64,7 +65,7 @@ func (s *Store) Load(ctx context.Context, adminID int64, wizardOut any, navOut *
 	return session, nil
 }
 
-func (s *Store) Save(ctx context.Context, adminID int64, flowName *string, wizardState any, nav []models.NavigationEntry) error {
+func (s *Store) Save(ctx context.Context, key models.SessionKey, flowName *string, wizardState any, nav []models.NavigationEntry) error {
 	var payload []byte
 	if wizardState != nil || len(nav) > 0 {
 		env := persistedSession{}
@@ -87,12 +88,44 @@ func (s *Store) Save(ctx context.Context, adminID int64, flowName *string, wizar
 		payload = buf
 	}
 	return s.sessions.Save(ctx, models.AdminSession{
-		AdminID:     adminID,
+		AdminID:     key.AdminID,
+		ChatID:      key.ChatID,
 		CurrentFlow: flowName,
 		FlowState:   payload,
 	})
 }
 
-func (s *Store) Clear(ctx context.Context, adminID int64) error {
-	return s.sessions.Delete(ctx, adminID)
+// SaveNav replaces the navigation history and keeps the stored wizard.
+func (s *Store) SaveNav(ctx context.Context, key models.SessionKey, nav []models.NavigationEntry) error {
+	session, err := s.sessions.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	var (
+		flowName *string
+		wizard   any
+	)
+	if session != nil && session.CurrentFlow != nil && len(session.FlowState) > 0 {
+		var envelope persistedSession
+		if err := json.Unmarshal(session.FlowState, &envelope); err == nil && len(envelope.Wizard) > 0 {
+			flowName = session.CurrentFlow
+			wizard = envelope.Wizard
+		}
+	}
+	return s.Save(ctx, key, flowName, wizard, nav)
+}
+
+// DeleteStale removes sessions that have not been touched since before.
+func (s *Store) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	return s.sessions.DeleteStale(ctx, before)
+}
+
+// ExpireWizards closes wizards idle since before and keeps the navigation
+// history of their sessions.
+func (s *Store) ExpireWizards(ctx context.Context, before time.Time) (int64, error) {
+	return s.sessions.ExpireWizards(ctx, before)
+}
+
+func (s *Store) Clear(ctx context.Context, key models.SessionKey) error {
+	return s.sessions.Delete(ctx, key)
 }
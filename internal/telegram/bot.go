@@ -21,19 +21,31 @@ import (
 const (
 	perPage     = 20
 	maxNavDepth = 10
+	searchLimit = 15
+)
+
+const (
+	pickerPlayers   = "players"
+	pickerRosterAdd = "roster_add"
 )
 
 const (
 	flowCreateTournament   = "create_tournament"
 	flowEditTournament     = "edit_tournament"
+	flowRegulations        = "regulations"
 	flowCreateTeam         = "create_team"
 	flowEditTeam           = "edit_team"
 	flowCreatePlayer       = "create_player"
 	flowEditPlayer         = "edit_player"
+	flowRegisterTeam       = "register_team"
 	flowRosterAddPlayer    = "roster_add_player"
 	flowRosterChangeNumber = "roster_change_number"
 	flowMatchCreate        = "match_create"
 	flowMatchEdit          = "match_edit"
+	flowMatchImport        = "match_import"
+	flowMatchImportICS     = "match_import_ics"
+	flowMatchLeague        = "match_league"
+	flowBracket            = "bracket"
 	flowLineupNumber       = "lineup_number"
 	flowEventGoal          = "event_goal"
 	flowEventCard          = "event_card"
@@ -49,43 +61,132 @@ type Services struct {
 	Lineup      service.LineupService
 	Events      service.EventsService
 	Sessions    *session.Store
+	Callbacks   service.CallbackService
+	Calendars   service.CalendarService
+	Brackets    service.BracketsService
+}
+
+// Options holds tunables that are not required to construct a bot.
+type Options struct {
+	// Workers is the number of goroutines handling updates concurrently.
+	Workers int
+	// WizardTTL is how long an untouched wizard stays open.
+	WizardTTL time.Duration
+	// FeedURL is the public base URL of the calendar feeds. When set, sent
+	// calendars come with a subscription link.
+	FeedURL string
+	// Directors are admins who may override the tournament rules.
+	Directors []int64
 }
 
 type navEntry = models.NavigationEntry
 
-type Bot struct {
-	api     *tgbotapi.BotAPI
-	admins  map[int64]struct{}
-	svc     Services
-	logger  repository.Logger
-	loc     *time.Location
-	timeNow func() time.Time
-	navMu   sync.Mutex
-	nav     map[int64][]navEntry
+// playerPicker remembers which player-picking screen is open in a chat so that
+// a plain text message can be treated as a search query.
+type playerPicker struct {
+	Mode         string
+	TournamentID int64
+	TeamID       int64
 }
 
-func NewBot(api *tgbotapi.BotAPI, adminIDs []int64, loc *time.Location, svc Services, logger repository.Logger) *Bot {
+type Bot struct {
+	api       *tgbotapi.BotAPI
+	admins    map[int64]struct{}
+	directors map[int64]struct{}
+	svc       Services
+	logger    repository.Logger
+	loc       *time.Location
+	timeNow   func() time.Time
+	workers   int
+	wizardTTL time.Duration
+	feedURL   string
+	out       *sender
+	navMu     sync.Mutex
+	nav       map[models.SessionKey][]navEntry
+	pickMu    sync.Mutex
+	pickers   map[models.SessionKey]playerPicker
+
+	screenMu sync.Mutex
+	screens  map[models.SessionKey]liveScreen
+
+	flows map[string]*wizardFlow
+}
+
+func NewBot(api *tgbotapi.BotAPI, adminIDs []int64, loc *time.Location, svc Services, logger repository.Logger, opts Options) *Bot {
 	adminMap := make(map[int64]struct{}, len(adminIDs))
 	for _, id := range adminIDs {
 		adminMap[id] = struct{}{}
 	}
-	return &Bot{
-		api:     api,
-		admins:  adminMap,
-		loc:     loc,
-		svc:     svc,
-		logger:  logger,
-		timeNow: time.Now,
-		nav:     make(map[int64][]navEntry),
+	directorMap := make(map[int64]struct{}, len(opts.Directors))
+	for _, id := range opts.Directors {
+		adminMap[id] = struct{}{}
+		directorMap[id] = struct{}{}
+	}
+	if opts.Workers < 1 {
+		opts.Workers = 1
+	}
+	if opts.WizardTTL <= 0 {
+		opts.WizardTTL = defaultWizardTTL
 	}
+	b := &Bot{
+		api:       api,
+		admins:    adminMap,
+		directors: directorMap,
+		loc:       loc,
+		svc:       svc,
+		logger:    logger,
+		timeNow:   time.Now,
+		workers:   opts.Workers,
+		wizardTTL: opts.WizardTTL,
+		feedURL:   opts.FeedURL,
+		out:       newSender(api, logger),
+		nav:       make(map[models.SessionKey][]navEntry),
+		pickers:   make(map[models.SessionKey]playerPicker),
+		screens:   make(map[models.SessionKey]liveScreen),
+	}
+	b.flows = b.wizardFlows()
+	return b
+}
+
+type sessionKeyCtx struct{}
+
+// withSessionKey records whose update is being handled, so that screens and
+// pickers stay apart for admins sharing a group chat.
+func withSessionKey(ctx context.Context, key models.SessionKey) context.Context {
+	return context.WithValue(ctx, sessionKeyCtx{}, key)
+}
+
+// sessionKeyFor is the session of the admin handling ctx in the chat.
+func sessionKeyFor(ctx context.Context, chatID int64) models.SessionKey {
+	key, _ := ctx.Value(sessionKeyCtx{}).(models.SessionKey)
+	return models.SessionKey{AdminID: key.AdminID, ChatID: chatID}
 }
 
-func (b *Bot) pushNav(ctx context.Context, adminID int64, entry navEntry) {
+func (b *Bot) setPicker(key models.SessionKey, picker playerPicker) {
+	b.pickMu.Lock()
+	b.pickers[key] = picker
+	b.pickMu.Unlock()
+}
+
+func (b *Bot) clearPicker(key models.SessionKey) {
+	b.pickMu.Lock()
+	delete(b.pickers, key)
+	b.pickMu.Unlock()
+}
+
+func (b *Bot) activePicker(key models.SessionKey) (playerPicker, bool) {
+	b.pickMu.Lock()
+	defer b.pickMu.Unlock()
+	picker, ok := b.pickers[key]
+	return picker, ok
+}
+
+func (b *Bot) pushNav(ctx context.Context, key models.SessionKey, entry navEntry) {
 	if entry.Action == "" {
 		return
 	}
 	b.navMu.Lock()
-	stack := b.nav[adminID]
+	stack := b.nav[key]
 	if len(stack) > 0 {
 		last := stack[len(stack)-1]
 		if last.Action == entry.Action && compareParamMaps(last.Params, entry.Params) {
@@ -97,16 +198,16 @@ func (b *Bot) pushNav(ctx context.Context, adminID int64, entry navEntry) {
 	if len(stack) > maxNavDepth {
 		stack = stack[1:]
 	}
-	b.nav[adminID] = stack
+	b.nav[key] = stack
 	snapshot := make([]navEntry, len(stack))
 	copy(snapshot, stack)
 	b.navMu.Unlock()
-	b.persistNav(ctx, adminID, snapshot)
+	b.persistNav(ctx, key, snapshot)
 }
 
-func (b *Bot) popNav(ctx context.Context, adminID int64) (navEntry, bool) {
+func (b *Bot) popNav(ctx context.Context, key models.SessionKey) (navEntry, bool) {
 	b.navMu.Lock()
-	stack := b.nav[adminID]
+	stack := b.nav[key]
 	if len(stack) == 0 {
 		b.navMu.Unlock()
 		return navEntry{}, false
@@ -114,28 +215,28 @@ func (b *Bot) popNav(ctx context.Context, adminID int64) (navEntry, bool) {
 	entry := stack[len(stack)-1]
 	stack = stack[:len(stack)-1]
 	if len(stack) == 0 {
-		delete(b.nav, adminID)
+		delete(b.nav, key)
 	} else {
-		b.nav[adminID] = stack
+		b.nav[key] = stack
 	}
 	snapshot := make([]navEntry, len(stack))
 	copy(snapshot, stack)
 	b.navMu.Unlock()
-	b.persistNav(ctx, adminID, snapshot)
+	b.persistNav(ctx, key, snapshot)
 	return entry, true
 }
 
-func (b *Bot) clearNav(ctx context.Context, adminID int64) {
+func (b *Bot) clearNav(ctx context.Context, key models.SessionKey) {
 	b.navMu.Lock()
-	_, existed := b.nav[adminID]
-	delete(b.nav, adminID)
+	_, existed := b.nav[key]
+	delete(b.nav, key)
 	b.navMu.Unlock()
 	if existed {
-		b.persistNav(ctx, adminID, nil)
+		b.persistNav(ctx, key, nil)
 	}
 }
 
-func (b *Bot) persistNav(ctx context.Context, adminID int64, stack []navEntry) {
+func (b *Bot) persistNav(ctx context.Context, key models.SessionKey, stack []navEntry) {
 	var entries []models.NavigationEntry
 	if len(stack) > 0 {
 		entries = make([]models.NavigationEntry, len(stack))
@@ -143,15 +244,15 @@ func (b *Bot) persistNav(ctx context.Context, adminID int64, stack []navEntry) {
 			entries[i] = models.NavigationEntry(entry)
 		}
 	}
-	if err := b.svc.Sessions.Save(ctx, adminID, nil, nil, entries); err != nil {
-		b.logger.Error(err, "persist_nav", "nav", int64(len(stack)), adminID)
+	if err := b.svc.Sessions.SaveNav(ctx, key, entries); err != nil {
+		b.logger.Error(err, "persist_nav", "nav", int64(len(stack)), key.AdminID)
 	}
 }
 
-func (b *Bot) snapshotNav(adminID int64) []models.NavigationEntry {
+func (b *Bot) snapshotNav(key models.SessionKey) []models.NavigationEntry {
 	b.navMu.Lock()
 	defer b.navMu.Unlock()
-	stack := b.nav[adminID]
+	stack := b.nav[key]
 	if len(stack) == 0 {
 		return nil
 	}
@@ -162,11 +263,11 @@ func (b *Bot) snapshotNav(adminID int64) []models.NavigationEntry {
 	return entries
 }
 
-func (b *Bot) restoreNav(adminID int64, entries []models.NavigationEntry) {
+func (b *Bot) restoreNav(key models.SessionKey, entries []models.NavigationEntry) {
 	b.navMu.Lock()
 	defer b.navMu.Unlock()
 	if len(entries) == 0 {
-		delete(b.nav, adminID)
+		delete(b.nav, key)
 		return
 	}
 	if len(entries) > maxNavDepth {
@@ -176,11 +277,11 @@ func (b *Bot) restoreNav(adminID int64, entries []models.NavigationEntry) {
 	for i, entry := range entries {
 		stack[i] = navEntry(entry)
 	}
-	b.nav[adminID] = stack
+	b.nav[key] = stack
 }
 
-func (b *Bot) saveSession(ctx context.Context, adminID int64, flowName *string, state any) error {
-	return b.svc.Sessions.Save(ctx, adminID, flowName, state, b.snapshotNav(adminID))
+func (b *Bot) saveSession(ctx context.Context, key models.SessionKey, flowName *string, state any) error {
+	return b.svc.Sessions.Save(ctx, key, flowName, state, b.snapshotNav(key))
 }
 
 func (b *Bot) handleNavEntry(ctx context.Context, chatID int64, entry navEntry) error {
@@ -203,36 +304,111 @@ func (b *Bot) handleNavEntry(ctx context.Context, chatID int64, entry navEntry)
 	case "roster_open_tournament":
 		tournamentID := parseInt64(entry.Params["id"])
 		return b.sendRosterTeams(ctx, chatID, tournamentID)
+	case "open_tournament":
+		return b.showTournament(ctx, chatID, parseInt64(entry.Params["id"]))
 	default:
 		b.sendSimple(chatID, "Вернуться не удалось.")
 		return nil
 	}
 }
 
+// Run receives updates with long polling until ctx is canceled.
 func (b *Bot) Run(ctx context.Context) error {
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
 	updateConfig := tgbotapi.NewUpdate(0)
 	updateConfig.Timeout = 30
 	updates := b.api.GetUpdatesChan(updateConfig)
 
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		case update := <-updates:
-			if err := b.handleUpdate(ctx, update); err != nil {
-				b.logger.Error(err, "handle_update", "update", int64(update.UpdateID), 0)
+	accepted := make(chan tgbotapi.Update)
+	go func() {
+		defer close(accepted)
+		last := 0
+		forward := func(update tgbotapi.Update) {
+			accepted <- update
+			last = update.UpdateID
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				b.api.StopReceivingUpdates()
+				// The poller moves its offset past an update as soon as it
+				// buffers it, so everything it still holds is handled here.
+				for update := range updates {
+					forward(update)
+				}
+				b.confirmUpdates(last)
+				return
+			case update, ok := <-updates:
+				if !ok {
+					return
+				}
+				forward(update)
 			}
 		}
+	}()
+	if err := b.Serve(ctx, accepted); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// confirmUpdates tells Telegram that updates up to last are handled; the
+// poller only does so with its next request, which never comes after shutdown.
+func (b *Bot) confirmUpdates(last int) {
+	if last == 0 {
+		return
+	}
+	if _, err := b.api.GetUpdates(tgbotapi.UpdateConfig{Offset: last + 1, Limit: 1}); err != nil {
+		b.logger.Error(err, "confirm_updates", "update", int64(last), 0)
+	}
+}
+
+// Serve handles updates from any source (polling or webhook) until the
+// channel is closed; the caller closes it on shutdown. Updates are processed
+// concurrently but in order for each user; every update received, queued and
+// in-flight ones included, is handled before Serve returns.
+func (b *Bot) Serve(ctx context.Context, updates <-chan tgbotapi.Update) error {
+	// Handlers keep running after shutdown is requested so that a half-applied
+	// wizard step is not cut off mid-way.
+	handleCtx := context.WithoutCancel(ctx)
+	pool := newDispatcher(b.workers, func(update tgbotapi.Update) {
+		b.processUpdate(handleCtx, update)
+	})
+	defer pool.stop()
+	go b.housekeeping(ctx)
+
+	for update := range updates {
+		pool.dispatch(handleCtx, update)
+	}
+	return nil
+}
+
+func (b *Bot) processUpdate(ctx context.Context, update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.Error(fmt.Errorf("panic: %v", r), "handle_update", "update", int64(update.UpdateID), updateUserID(update))
+		}
+	}()
+	if err := b.handleUpdate(ctx, update); err != nil {
+		b.logger.Error(err, "handle_update", "update", int64(update.UpdateID), updateUserID(update))
 	}
 }
 
 func (b *Bot) handleUpdate(ctx context.Context, update tgbotapi.Update) error {
+	if b.isDirector(updateUserID(update)) {
+		ctx = service.AsDirector(ctx)
+	}
 	if update.Message != nil {
 		return b.handleMessage(ctx, update.Message)
 	}
 	if update.CallbackQuery != nil {
 		return b.handleCallback(ctx, update.CallbackQuery)
 	}
+	if update.InlineQuery != nil {
+		return b.handleInlineQuery(ctx, update.InlineQuery)
+	}
 	return nil
 }
 
@@ -242,17 +418,27 @@ func (b *Bot) handleMessage(ctx context.Context, msg *tgbotapi.Message) error {
 	}
 	adminID := msg.From.ID
 	if !b.isAdmin(adminID) {
-		reply := tgbotapi.NewMessage(msg.Chat.ID, "У вас нет прав. Обратитесь к директору клуба.")
-		reply.ReplyToMessageID = msg.MessageID
-		_, _ = b.api.Send(reply)
+		// In a group the rest of the staff talk among themselves.
+		if msg.Chat.IsPrivate() {
+			reply := tgbotapi.NewMessage(msg.Chat.ID, "У вас нет прав. Обратитесь к директору клуба.")
+			reply.ReplyToMessageID = msg.MessageID
+			_, _ = b.out.Send(reply)
+		}
 		return nil
 	}
+	// In a group every admin has a session of their own, separate from the
+	// one in their private chat with the bot.
+	key := models.SessionKey{AdminID: adminID, ChatID: msg.Chat.ID}
+	ctx = withSessionKey(ctx, key)
+	// The admin's own message now sits below the screen.
+	b.detachScreen(msg.Chat.ID)
 
 	if msg.IsCommand() {
-		b.clearNav(ctx, adminID)
+		b.clearNav(ctx, key)
+		b.clearPicker(key)
 		switch msg.Command() {
 		case "start":
-			b.sendSimple(msg.Chat.ID, "Доступные разделы: /tournaments, /teams, /players, /tournament_rosters, /games.")
+			b.sendSimple(msg.Chat.ID, "Доступные разделы: /tournaments, /teams, /players, /tournament_rosters, /games.\nПоиск игрока: /find <имя>.\nКалендарь матчей клуба: /calendar.\nОтменить ввод: /cancel.")
 		case "tournaments":
 			return b.sendTournamentList(ctx, msg.Chat.ID, 1)
 		case "teams":
@@ -263,6 +449,25 @@ func (b *Bot) handleMessage(ctx context.Context, msg *tgbotapi.Message) error {
 			return b.sendRosterTournaments(ctx, msg.Chat.ID)
 		case "games":
 			return b.sendGamesTournaments(ctx, msg.Chat.ID)
+		case "cancel":
+			state, err := b.loadWizard(ctx, key)
+			if err != nil && !errors.Is(err, errWizardExpired) {
+				return err
+			}
+			if state == nil {
+				b.sendSimple(msg.Chat.ID, "Нечего отменять.")
+				return nil
+			}
+			return b.cancelWizard(ctx, key)
+		case "calendar":
+			return b.sendCalendar(ctx, msg.Chat.ID, models.CalendarScopeClub, 0)
+		case "find":
+			query := strings.TrimSpace(msg.CommandArguments())
+			if query == "" {
+				b.sendSimple(msg.Chat.ID, "Укажите имя: /find Иванов")
+				return nil
+			}
+			return b.sendPlayerSearch(ctx, msg.Chat.ID, playerPicker{Mode: pickerPlayers}, query)
 		default:
 			b.sendSimple(msg.Chat.ID, "Неизвестная команда.")
 		}
@@ -270,19 +475,26 @@ func (b *Bot) handleMessage(ctx context.Context, msg *tgbotapi.Message) error {
 	}
 
 	// Attempt to continue a wizard flow.
-	sessionState := &wizardState{}
-	var navState []models.NavigationEntry
-	stored, err := b.svc.Sessions.Load(ctx, adminID, sessionState, &navState)
+	state, err := b.loadWizard(ctx, key)
+	if errors.Is(err, errWizardExpired) {
+		b.sendSimple(msg.Chat.ID, "Незавершённый ввод устарел и был отменён. Начните заново из меню.")
+		return nil
+	}
 	if err != nil {
 		return err
 	}
-	b.restoreNav(adminID, navState)
-	if stored == nil || sessionState.Flow == "" {
+	if state == nil {
+		if picker, ok := b.activePicker(key); ok && strings.TrimSpace(msg.Text) != "" {
+			return b.sendPlayerSearch(ctx, msg.Chat.ID, picker, msg.Text)
+		}
 		// Plain message without wizard – ignore.
 		return nil
 	}
+	if msg.Document != nil {
+		return b.handleWizardDocument(ctx, key, state, msg.Document)
+	}
 
-	return b.advanceWizard(ctx, msg, sessionState)
+	return b.handleWizardInput(ctx, key, state, msg.Text)
 }
 
 func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) error {
@@ -291,25 +503,32 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 	}
 	adminID := cb.From.ID
 	if !b.isAdmin(adminID) {
-		_, _ = b.api.Request(tgbotapi.NewCallback(cb.ID, "Недостаточно прав"))
+		_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, "Недостаточно прав"))
 		return nil
 	}
 
-	payload, err := parseCallback(cb.Data)
+	payload, err := b.resolveCallback(ctx, cb.Data)
 	if err != nil {
-		_, _ = b.api.Request(tgbotapi.NewCallback(cb.ID, "Некорректная кнопка"))
+		if !errors.Is(err, models.ErrNotFound) && !errors.Is(err, models.ErrValidation) {
+			return err
+		}
+		_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, "Кнопка устарела, откройте раздел заново."))
 		return nil
 	}
+	key := models.SessionKey{AdminID: adminID, ChatID: cb.Message.Chat.ID}
+	ctx = withSessionKey(ctx, key)
+	b.clearPicker(key)
+	b.setScreen(key, cb.Message.MessageID)
 
 	switch payload.Action {
 	case "open_tournament":
 		id, _ := strconv.ParseInt(payload.Params["id"], 10, 64)
 		page := parseIntParam(payload.Params, "page", 1)
-		b.pushNav(ctx, adminID, navEntry{
+		b.pushNav(ctx, key, navEntry{
 			Action: "tournaments_page",
 			Params: map[string]string{"page": strconv.Itoa(page)},
 		})
-		if err := b.showTournament(ctx, cb.Message.Chat.ID, cb.Message.MessageID, id); err != nil {
+		if err := b.showTournament(ctx, cb.Message.Chat.ID, id); err != nil {
 			return err
 		}
 	case "tournaments_page":
@@ -319,21 +538,36 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 		}
 		return b.sendTournamentList(ctx, cb.Message.Chat.ID, page)
 	case "tournaments_start_create":
-		return b.startTournamentWizard(ctx, cb.Message.Chat.ID, cb.From.ID)
+		return b.startTournamentWizard(ctx, key)
 	case "tournament_edit":
 		tournamentID := parseInt64(payload.Params["id"])
-		return b.startTournamentEditWizard(ctx, cb.Message.Chat.ID, cb.From.ID, tournamentID)
+		return b.startTournamentEditWizard(ctx, key, tournamentID)
+	case "tournament_regulations":
+		tournamentID := parseInt64(payload.Params["id"])
+		return b.startRegulationsWizard(ctx, key, tournamentID)
+	case "tournament_status":
+		tournamentID := parseInt64(payload.Params["id"])
+		status := models.TournamentStatus(payload.Params["to"])
+		if status == models.TournamentStatusFinished && payload.Params["confirm"] == "" {
+			return b.confirmFinishTournament(ctx, cb.Message.Chat.ID, tournamentID)
+		}
+		if status != "" {
+			if err := b.svc.Tournaments.SetStatus(ctx, tournamentID, status); err != nil {
+				b.sendSimple(cb.Message.Chat.ID, fmt.Sprintf("Не удалось сменить статус: %v", err))
+			}
+		}
+		return b.showTournament(ctx, cb.Message.Chat.ID, tournamentID)
 	case "teams_start_create":
-		return b.startTeamWizard(ctx, cb.Message.Chat.ID, cb.From.ID)
+		return b.startTeamWizard(ctx, key)
 	case "team_open":
 		teamID := parseInt64(payload.Params["id"])
-		b.pushNav(ctx, adminID, navEntry{Action: "teams_menu"})
+		b.pushNav(ctx, key, navEntry{Action: "teams_menu"})
 		return b.showTeam(ctx, cb.Message.Chat.ID, teamID)
 	case "teams_menu":
 		return b.sendTeams(ctx, cb.Message.Chat.ID)
 	case "team_edit":
 		teamID := parseInt64(payload.Params["id"])
-		return b.startTeamEditWizard(ctx, cb.Message.Chat.ID, cb.From.ID, teamID)
+		return b.startTeamEditWizard(ctx, key, teamID)
 	case "players_page":
 		page, _ := strconv.Atoi(payload.Params["page"])
 		if page < 1 {
@@ -341,11 +575,11 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 		}
 		return b.sendPlayersPage(ctx, cb.Message.Chat.ID, page)
 	case "players_start_create":
-		return b.startPlayerWizard(ctx, cb.Message.Chat.ID, cb.From.ID)
+		return b.startPlayerWizard(ctx, key)
 	case "player_open":
 		playerID := parseInt64(payload.Params["id"])
 		page := parseIntParam(payload.Params, "page", 1)
-		b.pushNav(ctx, adminID, navEntry{
+		b.pushNav(ctx, key, navEntry{
 			Action: "players_menu",
 			Params: map[string]string{"page": strconv.Itoa(page)},
 		})
@@ -359,18 +593,32 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 	case "player_edit":
 		playerID := parseInt64(payload.Params["id"])
 		page := parseInt64(payload.Params["page"])
-		return b.startPlayerEditWizard(ctx, cb.Message.Chat.ID, cb.From.ID, playerID, int(page))
+		return b.startPlayerEditWizard(ctx, key, playerID, int(page))
 	case "roster_open_tournament":
 		tournamentID := parseInt64(payload.Params["id"])
 		return b.sendRosterTeams(ctx, cb.Message.Chat.ID, tournamentID)
 	case "roster_open_team":
 		tournamentID := parseInt64(payload.Params["t"])
 		teamID := parseInt64(payload.Params["team"])
-		b.pushNav(ctx, adminID, navEntry{
+		b.pushNav(ctx, key, navEntry{
 			Action: "roster_open_tournament",
 			Params: map[string]string{"id": strconv.FormatInt(tournamentID, 10)},
 		})
 		return b.showRoster(ctx, cb.Message.Chat.ID, tournamentID, teamID)
+	case "team_register":
+		tournamentID := parseInt64(payload.Params["t"])
+		teamID := parseInt64(payload.Params["team"])
+		return b.startRegisterTeamWizard(ctx, key, tournamentID, teamID)
+	case "team_unregister":
+		tournamentID := parseInt64(payload.Params["t"])
+		teamID := parseInt64(payload.Params["team"])
+		if err := b.svc.Rosters.UnregisterTeam(ctx, tournamentID, teamID); err != nil {
+			b.sendSimple(cb.Message.Chat.ID, fmt.Sprintf("Не удалось снять команду: %v", err))
+			return b.showRoster(ctx, cb.Message.Chat.ID, tournamentID, teamID)
+		}
+		b.sendSimple(cb.Message.Chat.ID, "Команда снята с турнира.")
+		b.popNav(ctx, key)
+		return b.sendRosterTeams(ctx, cb.Message.Chat.ID, tournamentID)
 	case "roster_add_player":
 		tournamentID := parseInt64(payload.Params["t"])
 		teamID := parseInt64(payload.Params["team"])
@@ -383,12 +631,12 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 		tournamentID := parseInt64(payload.Params["t"])
 		teamID := parseInt64(payload.Params["team"])
 		playerID := parseInt64(payload.Params["player"])
-		return b.startRosterAddWizard(ctx, cb.Message.Chat.ID, cb.From.ID, tournamentID, teamID, playerID)
+		return b.startRosterAddWizard(ctx, key, tournamentID, teamID, playerID)
 	case "roster_change_number":
 		tournamentID := parseInt64(payload.Params["t"])
 		teamID := parseInt64(payload.Params["team"])
 		playerID := parseInt64(payload.Params["player"])
-		return b.startRosterChangeWizard(ctx, cb.Message.Chat.ID, cb.From.ID, tournamentID, teamID, playerID)
+		return b.startRosterChangeWizard(ctx, key, tournamentID, teamID, playerID)
 	case "roster_remove_player":
 		tournamentID := parseInt64(payload.Params["t"])
 		teamID := parseInt64(payload.Params["team"])
@@ -399,13 +647,29 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 			b.sendSimple(cb.Message.Chat.ID, "Игрок удалён из заявки.")
 		}
 		return b.showRoster(ctx, cb.Message.Chat.ID, tournamentID, teamID)
+	case "roster_transfer":
+		tournamentID := parseInt64(payload.Params["t"])
+		teamID := parseInt64(payload.Params["team"])
+		playerID := parseInt64(payload.Params["player"])
+		return b.sendRosterTransferTeams(ctx, cb.Message.Chat.ID, tournamentID, teamID, playerID)
+	case "roster_transfer_to":
+		tournamentID := parseInt64(payload.Params["t"])
+		teamID := parseInt64(payload.Params["team"])
+		playerID := parseInt64(payload.Params["player"])
+		toTeamID := parseInt64(payload.Params["to"])
+		if err := b.transferPlayer(ctx, tournamentID, teamID, toTeamID, playerID); err != nil {
+			b.sendSimple(cb.Message.Chat.ID, fmt.Sprintf("Не удалось перевести игрока: %v", err))
+		} else {
+			b.sendSimple(cb.Message.Chat.ID, "Игрок переведён. Прошлые матчи остаются за прежней командой.")
+		}
+		return b.showRoster(ctx, cb.Message.Chat.ID, tournamentID, teamID)
 	case "games_open_tournament":
 		tournamentID := parseInt64(payload.Params["id"])
 		return b.sendGamesTeams(ctx, cb.Message.Chat.ID, tournamentID)
 	case "games_open_team":
 		tournamentID := parseInt64(payload.Params["t"])
 		teamID := parseInt64(payload.Params["team"])
-		b.pushNav(ctx, adminID, navEntry{
+		b.pushNav(ctx, key, navEntry{
 			Action: "games_open_tournament",
 			Params: map[string]string{"id": strconv.FormatInt(tournamentID, 10)},
 		})
@@ -413,14 +677,47 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 	case "match_start_create":
 		tournamentID := parseInt64(payload.Params["t"])
 		teamID := parseInt64(payload.Params["team"])
-		return b.startMatchCreateWizard(ctx, cb.Message.Chat.ID, cb.From.ID, tournamentID, teamID)
+		return b.startMatchCreateWizard(ctx, key, tournamentID, teamID)
+	case "match_start_import":
+		tournamentID := parseInt64(payload.Params["t"])
+		teamID := parseInt64(payload.Params["team"])
+		return b.startMatchImportWizard(ctx, key, tournamentID, teamID)
+	case "calendar_send":
+		_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, ""))
+		scope := models.CalendarScope(payload.Params["scope"])
+		return b.sendCalendar(ctx, cb.Message.Chat.ID, scope, parseInt64(payload.Params["id"]))
+	case "match_start_ics":
+		tournamentID := parseInt64(payload.Params["t"])
+		teamID := parseInt64(payload.Params["team"])
+		return b.startCalendarImportWizard(ctx, key, tournamentID, teamID)
+	case "bracket_open":
+		tournamentID := parseInt64(payload.Params["id"])
+		b.pushNav(ctx, key, navEntry{
+			Action: "open_tournament",
+			Params: map[string]string{"id": strconv.FormatInt(tournamentID, 10)},
+		})
+		return b.sendBracket(ctx, cb.Message.Chat.ID, tournamentID)
+	case "bracket_tie":
+		tournamentID := parseInt64(payload.Params["t"])
+		position, _ := strconv.Atoi(payload.Params["p"])
+		return b.sendBracketTie(ctx, cb.Message.Chat.ID, tournamentID, models.MatchStage(payload.Params["s"]), position)
+	case "bracket_win":
+		tournamentID := parseInt64(payload.Params["t"])
+		position, _ := strconv.Atoi(payload.Params["p"])
+		return b.setBracketWinner(ctx, cb.Message.Chat.ID, tournamentID, models.MatchStage(payload.Params["s"]), position, payload.Params["w"])
+	case "bracket_start_create":
+		return b.startBracketWizard(ctx, key, parseInt64(payload.Params["t"]))
+	case "match_start_league":
+		tournamentID := parseInt64(payload.Params["t"])
+		teamID := parseInt64(payload.Params["team"])
+		return b.startLeagueWizard(ctx, key, tournamentID, teamID)
 	case "open_match":
 		matchID := parseInt64(payload.Params["id"])
 		match, err := b.svc.Matches.Get(ctx, matchID)
 		if err != nil {
 			return err
 		}
-		b.pushNav(ctx, adminID, navEntry{
+		b.pushNav(ctx, key, navEntry{
 			Action: "games_open_team",
 			Params: map[string]string{
 				"t":    strconv.FormatInt(match.TournamentID, 10),
@@ -430,7 +727,7 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 		return b.renderMatch(ctx, cb.Message.Chat.ID, match)
 	case "match_edit":
 		matchID := parseInt64(payload.Params["id"])
-		return b.startMatchEditWizard(ctx, cb.Message.Chat.ID, cb.From.ID, matchID)
+		return b.startMatchEditWizard(ctx, key, matchID)
 	case "match_lineup_menu":
 		matchID := parseInt64(payload.Params["match"])
 		return b.sendLineupMenu(ctx, cb.Message.Chat.ID, matchID)
@@ -456,7 +753,7 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 	case "match_lineup_number":
 		matchID := parseInt64(payload.Params["match"])
 		playerID := parseInt64(payload.Params["player"])
-		return b.startLineupNumberWizard(ctx, cb.Message.Chat.ID, cb.From.ID, matchID, playerID)
+		return b.startLineupNumberWizard(ctx, key, matchID, playerID)
 	case "match_events_menu":
 		matchID := parseInt64(payload.Params["match"])
 		return b.sendEventsMenu(ctx, cb.Message.Chat.ID, matchID)
@@ -466,7 +763,7 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 	case "match_events_goal_pick":
 		matchID := parseInt64(payload.Params["match"])
 		playerID := parseInt64(payload.Params["player"])
-		return b.startEventGoalWizard(ctx, cb.Message.Chat.ID, cb.From.ID, matchID, playerID)
+		return b.startEventGoalWizard(ctx, key, matchID, playerID)
 	case "match_events_add_card":
 		matchID := parseInt64(payload.Params["match"])
 		return b.sendEventsCardPlayerList(ctx, cb.Message.Chat.ID, matchID)
@@ -478,7 +775,7 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 		matchID := parseInt64(payload.Params["match"])
 		playerID := parseInt64(payload.Params["player"])
 		cardType := payload.Params["type"]
-		return b.startEventCardWizard(ctx, cb.Message.Chat.ID, cb.From.ID, matchID, playerID, cardType)
+		return b.startEventCardWizard(ctx, key, matchID, playerID, cardType)
 	case "match_events_add_sub":
 		matchID := parseInt64(payload.Params["match"])
 		return b.sendEventSubOutList(ctx, cb.Message.Chat.ID, matchID)
@@ -490,7 +787,7 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 		matchID := parseInt64(payload.Params["match"])
 		outID := parseInt64(payload.Params["out"])
 		inID := parseInt64(payload.Params["player"])
-		return b.startEventSubWizard(ctx, cb.Message.Chat.ID, cb.From.ID, matchID, outID, inID)
+		return b.startEventSubWizard(ctx, key, matchID, outID, inID)
 	case "match_status_set":
 		matchID := parseInt64(payload.Params["id"])
 		status := payload.Params["status"]
@@ -498,17 +795,20 @@ func (b *Bot) handleCallback(ctx context.Context, cb *tgbotapi.CallbackQuery) er
 	case "match_scores_reset":
 		matchID := parseInt64(payload.Params["id"])
 		return b.resetMatchScores(ctx, cb.Message.Chat.ID, matchID)
+	case "wz_pick", "wz_skip", "wz_clear", "wz_back", "wz_confirm", "wz_cancel", "wz_resume", "wz_restart",
+		"wz_cal", "wz_hour", "wz_noop":
+		return b.handleWizardCallback(ctx, cb, payload)
 	case "nav_back":
-		entry, ok := b.popNav(ctx, adminID)
+		entry, ok := b.popNav(ctx, key)
 		if !ok {
 			b.sendSimple(cb.Message.Chat.ID, "История экранов пуста.")
 			return nil
 		}
 		return b.handleNavEntry(ctx, cb.Message.Chat.ID, entry)
 	default:
-		_, _ = b.api.Request(tgbotapi.NewCallback(cb.ID, "Функция в разработке"))
+		_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, "Функция в разработке"))
 	}
-	_, _ = b.api.Request(tgbotapi.NewCallback(cb.ID, ""))
+	_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, ""))
 	return nil
 }
 
@@ -517,13 +817,21 @@ func (b *Bot) isAdmin(id int64) bool {
 	return ok
 }
 
+func (b *Bot) isDirector(id int64) bool {
+	_, ok := b.directors[id]
+	return ok
+}
+
 // ----------------------------------------------------------------------------
 // Renderers
 
+// sendSimple posts a notice or prompt below the current screen.
 func (b *Bot) sendSimple(chatID int64, text string) {
 	msg := tgbotapi.NewMessage(chatID, text)
 	msg.ParseMode = "Markdown"
-	_, _ = b.api.Send(msg)
+	if _, err := b.out.Send(msg); err == nil {
+		b.detachScreen(chatID)
+	}
 }
 
 func (b *Bot) sendTournamentList(ctx context.Context, chatID int64, page int) error {
@@ -572,24 +880,24 @@ func (b *Bot) sendTournamentList(ctx context.Context, chatID int64, page int) er
 		tgbotapi.NewInlineKeyboardButtonData("➕ Создать турнир", "tournaments_start_create"),
 	})
 
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
-func (b *Bot) showTournament(ctx context.Context, chatID int64, messageID int, id int64) error {
+func (b *Bot) showTournament(ctx context.Context, chatID int64, id int64) error {
 	t, err := b.svc.Tournaments.Get(ctx, id)
 	if err != nil {
 		return err
 	}
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("*%s*\n", escape(t.Name)))
-	if t.Type != nil {
-		builder.WriteString(fmt.Sprintf("_%s_\n", escape(*t.Type)))
+	builder.WriteString(fmt.Sprintf("_%s_\n", escape(describeFormat(t))))
+	if regulations, err := b.svc.Tournaments.Regulations(ctx, t.ID); err == nil {
+		builder.WriteString(fmt.Sprintf("Регламент: %s\n", escape(describeRegulations(regulations))))
 	}
 	builder.WriteString(fmt.Sprintf("Статус: %s\n", t.Status))
+	if t.Status == models.TournamentStatusFinished {
+		builder.WriteString("_Турнир завершён: заявки, матчи и события закрыты для изменений._\n")
+	}
 	if t.StartDate != nil {
 		builder.WriteString(fmt.Sprintf("Старт: %s\n", t.StartDate.Format("02.01.2006")))
 	}
@@ -599,6 +907,18 @@ func (b *Bot) showTournament(ctx context.Context, chatID int64, messageID int, i
 	if t.Note != nil && *t.Note != "" {
 		builder.WriteString(fmt.Sprintf("Заметка: %s\n", escape(*t.Note)))
 	}
+	if t.Format.HasStandings() {
+		if rows, err := b.svc.Matches.Standings(ctx, t.ID); err == nil && len(rows) > 0 {
+			builder.WriteString("\n*Результаты команд клуба:*\n")
+			writeStandings(&builder, rows)
+		}
+	}
+	if t.Format.HasBracket() {
+		if bracket, err := b.svc.Brackets.Get(ctx, t.ID); err == nil && len(bracket.Stages) > 0 {
+			builder.WriteString("\n*Сетка:*\n")
+			b.writeBracket(&builder, bracket)
+		}
+	}
 	teams, err := b.svc.Rosters.ListTeamsInTournament(ctx, t.ID)
 	if err == nil && len(teams) > 0 {
 		builder.WriteString("\n*Команды в турнире:*\n")
@@ -617,20 +937,80 @@ func (b *Bot) showTournament(ctx context.Context, chatID int64, messageID int, i
 			}
 		}
 	}
-	msg := tgbotapi.NewEditMessageText(chatID, messageID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{
-		InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{
-			{tgbotapi.NewInlineKeyboardButtonData("✏ Редактировать", fmt.Sprintf("tournament_edit|id=%d", t.ID))},
-			{
-				tgbotapi.NewInlineKeyboardButtonData("👥 Заявки", fmt.Sprintf("roster_open_tournament|id=%d", t.ID)),
-				tgbotapi.NewInlineKeyboardButtonData("🏟 Матчи", fmt.Sprintf("games_open_tournament|id=%d", t.ID)),
-			},
-			{tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "nav_back")},
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{
+			tgbotapi.NewInlineKeyboardButtonData("✏ Редактировать", fmt.Sprintf("tournament_edit|id=%d", t.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("📋 Регламент", fmt.Sprintf("tournament_regulations|id=%d", t.ID)),
+		},
+		{
+			tgbotapi.NewInlineKeyboardButtonData("📅 Календарь", fmt.Sprintf("calendar_send|scope=tournament|id=%d", t.ID)),
+		},
+		{
+			tgbotapi.NewInlineKeyboardButtonData("👥 Заявки", fmt.Sprintf("roster_open_tournament|id=%d", t.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("🏟 Матчи", fmt.Sprintf("games_open_tournament|id=%d", t.ID)),
 		},
 	}
-	_, err = b.api.Send(msg)
-	return err
+	if t.Format.HasBracket() {
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("🏆 Сетка плей-офф", fmt.Sprintf("bracket_open|id=%d", t.ID)),
+		})
+	}
+	switch {
+	case t.Status == models.TournamentStatusPlanned:
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("▶ Начать турнир", fmt.Sprintf("tournament_status|id=%d|to=%s", t.ID, models.TournamentStatusActive)),
+		})
+	case t.Status == models.TournamentStatusActive:
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("🏁 Завершить турнир", fmt.Sprintf("tournament_status|id=%d|to=%s", t.ID, models.TournamentStatusFinished)),
+		})
+	case t.Status == models.TournamentStatusFinished && service.IsDirector(ctx):
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("↩ Открыть заново", fmt.Sprintf("tournament_status|id=%d|to=%s", t.ID, models.TournamentStatusActive)),
+		})
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "nav_back"),
+	})
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
+}
+
+// confirmFinishTournament asks before locking the tournament: only a
+// director can reopen it.
+func (b *Bot) confirmFinishTournament(ctx context.Context, chatID int64, tournamentID int64) error {
+	t, err := b.svc.Tournaments.Get(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	text := fmt.Sprintf("Завершить турнир *%s*?\nПосле этого заявки, матчи, составы и события нельзя будет менять. Открыть турнир заново сможет только директор.", escape(t.Name))
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		[]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("🏁 Завершить", fmt.Sprintf("tournament_status|id=%d|to=%s|confirm=1", tournamentID, models.TournamentStatusFinished)),
+			tgbotapi.NewInlineKeyboardButtonData("Отмена", fmt.Sprintf("tournament_status|id=%d", tournamentID)),
+		},
+	)
+	return b.render(ctx, chatID, text, keyboard)
+}
+
+// writeStandings lists the club teams group by group, by points: played,
+// won-drawn-lost, goals and points.
+func writeStandings(builder *strings.Builder, rows []models.StandingRow) {
+	place := 0
+	for i, row := range rows {
+		if i == 0 || !sameGroup(row.GroupName, rows[i-1].GroupName) {
+			place = 0
+			if row.GroupName != nil {
+				builder.WriteString(fmt.Sprintf("_Группа %s_\n", escape(*row.GroupName)))
+			}
+		}
+		place++
+		builder.WriteString(fmt.Sprintf("%d. %s — И %d, %d-%d-%d, мячи %d:%d, *%d*\n",
+			place, escape(row.TeamName), row.Played, row.Won, row.Drawn, row.Lost, row.GoalsFor, row.GoalsAgainst, row.Points))
+	}
+}
+
+func sameGroup(a, b *string) bool {
+	return derefString(a) == derefString(b)
 }
 
 func (b *Bot) sendTeams(ctx context.Context, chatID int64) error {
@@ -653,11 +1033,7 @@ func (b *Bot) sendTeams(ctx context.Context, chatID int64) error {
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("➕ Создать команду", "teams_start_create"),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
 func (b *Bot) showTeam(ctx context.Context, chatID int64, teamID int64) error {
@@ -702,18 +1078,15 @@ func (b *Bot) showTeam(ctx context.Context, chatID int64, teamID int64) error {
 			}
 		}
 	}
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(
 		[]tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData("✏ Редактировать", fmt.Sprintf("team_edit|id=%d", team.ID)),
+			tgbotapi.NewInlineKeyboardButtonData("📅 Календарь", fmt.Sprintf("calendar_send|scope=team|id=%d", team.ID)),
 		},
 		[]tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "nav_back"),
 		},
-	)
-	_, err = b.api.Send(msg)
-	return err
+	))
 }
 
 func (b *Bot) sendPlayersPage(ctx context.Context, chatID int64, page int) error {
@@ -723,6 +1096,7 @@ func (b *Bot) sendPlayersPage(ctx context.Context, chatID int64, page int) error
 	}
 	var builder strings.Builder
 	builder.WriteString(fmt.Sprintf("*Игроки — страница %d*\n", page))
+	builder.WriteString("_Для поиска отправьте часть имени._\n")
 	if len(items) == 0 {
 		builder.WriteString("Пока пусто.")
 	}
@@ -752,10 +1126,8 @@ func (b *Bot) sendPlayersPage(ctx context.Context, chatID int64, page int) error
 		tgbotapi.NewInlineKeyboardButtonData("➕ Создать игрока", "players_start_create"),
 	})
 	markup.InlineKeyboard = append(markup.InlineKeyboard, keyboard...)
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = markup
-	_, err = b.api.Send(msg)
+	err = b.render(ctx, chatID, builder.String(), markup)
+	b.setPicker(sessionKeyFor(ctx, chatID), playerPicker{Mode: pickerPlayers})
 	return err
 }
 
@@ -772,6 +1144,8 @@ func (b *Bot) showPlayer(ctx context.Context, chatID int64, playerID int64, page
 	builder.WriteString(fmt.Sprintf("*%s*\n", escape(player.FullName)))
 	if player.BirthDate != nil {
 		builder.WriteString(fmt.Sprintf("Дата рождения: %s\n", player.BirthDate.Format("02.01.2006")))
+		today := b.timeNow().In(b.loc)
+		builder.WriteString(fmt.Sprintf("Возраст: %d, категория %s\n", playerAge(*player.BirthDate, today), ageCategory(player.BirthDate.Year(), today.Year())))
 	}
 	if player.Position != nil && *player.Position != "" {
 		builder.WriteString(fmt.Sprintf("Позиция: %s\n", escape(*player.Position)))
@@ -787,29 +1161,28 @@ func (b *Bot) showPlayer(ctx context.Context, chatID int64, playerID int64, page
 	if len(assignments) > 0 {
 		builder.WriteString("\n*Заявки:*\n")
 		for _, a := range assignments {
-			title := escape(a.PlayerName)
+			title := escape(a.TeamName)
 			line := fmt.Sprintf("- Турнир #%d, Команда %s", a.TournamentID, title)
 			if a.TournamentNumber != nil {
 				line += fmt.Sprintf(", № %d", *a.TournamentNumber)
 			}
+			if a.LeftOn != nil {
+				line += fmt.Sprintf(", %s–%s, переведён", a.JoinedOn.Format("02.01.2006"), a.LeftOn.Format("02.01.2006"))
+			}
 			builder.WriteString(line + "\n")
 		}
 	}
 	if page < 1 {
 		page = 1
 	}
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(
 		[]tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData("✏ Редактировать", fmt.Sprintf("player_edit|id=%d|page=%d", player.ID, page)),
 		},
 		[]tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "nav_back"),
 		},
-	)
-	_, err = b.api.Send(msg)
-	return err
+	))
 }
 
 func (b *Bot) sendRosterTournaments(ctx context.Context, chatID int64) error {
@@ -830,13 +1203,7 @@ func (b *Bot) sendRosterTournaments(ctx context.Context, chatID int64) error {
 				fmt.Sprintf("roster_open_tournament|id=%d", t.ID)),
 		})
 	}
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	if len(keyboard) > 0 {
-		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	}
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
 func (b *Bot) sendRosterTeams(ctx context.Context, chatID int64, tournamentID int64) error {
@@ -867,20 +1234,20 @@ func (b *Bot) sendRosterTeams(ctx context.Context, chatID int64, tournamentID in
 	var builder strings.Builder
 	builder.WriteString("*Заявка — выберите команду*\n")
 	if len(teams) == 0 {
-		builder.WriteString("В этом турнире пока нет заявленных команд.\n")
+		builder.WriteString("В этом турнире пока нет зарегистрированных команд.\n")
 	}
 	if len(available) > 0 {
-		builder.WriteString("\n*Команды без заявки:*\n")
+		builder.WriteString("\n*Добавить команду в турнир:*\n")
 		for _, team := range available {
 			builder.WriteString(fmt.Sprintf("- %s\n", escape(team.Name)))
 		}
-		builder.WriteString("Нажмите, чтобы начать заполнять заявку.\n")
+		builder.WriteString("Составы можно заполнять после регистрации команды.\n")
 	}
 	keyboard := make([][]tgbotapi.InlineKeyboardButton, 0, len(teams)+len(available)+1)
 	for _, team := range teams {
 		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData(
-				fmt.Sprintf("%s (`%s`)", escape(team.TeamName), escape(team.ShortCode)),
+				tournamentTeamLabel(team),
 				fmt.Sprintf("roster_open_team|t=%d|team=%d", tournamentID, team.TeamID)),
 		})
 	}
@@ -892,26 +1259,54 @@ func (b *Bot) sendRosterTeams(ctx context.Context, chatID int64, tournamentID in
 		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData(
 				label,
-				fmt.Sprintf("roster_open_team|t=%d|team=%d", tournamentID, team.ID)),
+				fmt.Sprintf("team_register|t=%d|team=%d", tournamentID, team.ID)),
 		})
 	}
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "tournament_rosters"),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
+}
+
+// tournamentTeamLabel names a registered team on a button, with its group.
+func tournamentTeamLabel(team models.TournamentTeam) string {
+	label := team.TeamName
+	if team.ShortCode != "" {
+		label = fmt.Sprintf("%s (%s)", team.TeamName, team.ShortCode)
+	}
+	if team.GroupName != nil {
+		label = fmt.Sprintf("[%s] %s", *team.GroupName, label)
+	}
+	return label
 }
 
 func (b *Bot) showRoster(ctx context.Context, chatID int64, tournamentID, teamID int64) error {
+	team, err := b.svc.Rosters.GetTournamentTeam(ctx, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
 	entries, err := b.svc.Rosters.ListRoster(ctx, tournamentID, teamID)
 	if err != nil {
 		return err
 	}
 	var builder strings.Builder
-	builder.WriteString("*Состав заявки*\n")
+	builder.WriteString(fmt.Sprintf("*Состав заявки — %s*\n", escape(team.TeamName)))
+	if team.GroupName != nil {
+		builder.WriteString(fmt.Sprintf("Группа: %s\n", escape(*team.GroupName)))
+	}
+	builder.WriteString(fmt.Sprintf("Зарегистрирована: %s\n", team.RegisteredOn.Format("02.01.2006")))
+	if regulations, err := b.svc.Tournaments.Regulations(ctx, tournamentID); err == nil {
+		builder.WriteString(fmt.Sprintf("Заявка: %s\n", rosterWindowStatus(regulations, b.timeNow().In(b.loc))))
+		count := fmt.Sprintf("Игроков: %d", len(entries))
+		if regulations.MaxSquad != nil {
+			count += fmt.Sprintf(" из %d", *regulations.MaxSquad)
+		}
+		if regulations.MinSquad != nil {
+			count += fmt.Sprintf(", минимум %d", *regulations.MinSquad)
+		}
+		builder.WriteString(count + "\n")
+	}
+	builder.WriteString("\n")
 	if len(entries) == 0 {
 		builder.WriteString("Игроков пока нет.\n")
 	}
@@ -929,17 +1324,96 @@ func (b *Bot) showRoster(ctx context.Context, chatID int64, tournamentID, teamID
 	for _, entry := range entries {
 		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData("✏ Номер", fmt.Sprintf("roster_change_number|t=%d|team=%d|player=%d", tournamentID, teamID, entry.PlayerID)),
+			tgbotapi.NewInlineKeyboardButtonData("🔁 Перевести", fmt.Sprintf("roster_transfer|t=%d|team=%d|player=%d", tournamentID, teamID, entry.PlayerID)),
 			tgbotapi.NewInlineKeyboardButtonData("🗑 Удалить", fmt.Sprintf("roster_remove_player|t=%d|team=%d|player=%d", tournamentID, teamID, entry.PlayerID)),
 		})
 	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("✏ Группа и дата", fmt.Sprintf("team_register|t=%d|team=%d", tournamentID, teamID)),
+	})
+	if len(entries) == 0 {
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("🚫 Снять с турнира", fmt.Sprintf("team_unregister|t=%d|team=%d", tournamentID, teamID)),
+		})
+	}
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "nav_back"),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
+}
+
+func (b *Bot) sendRosterTransferTeams(ctx context.Context, chatID int64, tournamentID, teamID, playerID int64) error {
+	entries, err := b.svc.Rosters.ListRoster(ctx, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
+	playerName := ""
+	for _, entry := range entries {
+		if entry.PlayerID == playerID {
+			playerName = entry.PlayerName
+		}
+	}
+	teams, err := b.svc.Rosters.ListTeamsInTournament(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("*Перевод — %s*\n", escape(playerName)))
+	builder.WriteString("Выберите новую команду. Номер сохранится, если в ней он свободен.\n")
+	keyboard := make([][]tgbotapi.InlineKeyboardButton, 0, len(teams))
+	for _, team := range teams {
+		if team.TeamID == teamID {
+			continue
+		}
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData(
+				"➡ "+tournamentTeamLabel(team),
+				fmt.Sprintf("roster_transfer_to|t=%d|team=%d|player=%d|to=%d", tournamentID, teamID, playerID, team.TeamID)),
+		})
+	}
+	if len(keyboard) == 0 {
+		builder.WriteString("Других команд в турнире нет.\n")
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⬅ К заявке", fmt.Sprintf("roster_open_team|t=%d|team=%d", tournamentID, teamID)),
+	})
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
+}
+
+// transferPlayer moves a player to another team as of today, keeping the
+// number when nobody in the new team has it.
+func (b *Bot) transferPlayer(ctx context.Context, tournamentID, fromTeamID, toTeamID, playerID int64) error {
+	current, err := b.svc.Rosters.ListRoster(ctx, tournamentID, fromTeamID)
+	if err != nil {
+		return err
+	}
+	var number *int
+	for _, entry := range current {
+		if entry.PlayerID == playerID {
+			number = entry.TournamentNumber
+		}
+	}
+	if number != nil {
+		target, err := b.svc.Rosters.ListRoster(ctx, tournamentID, toTeamID)
+		if err != nil {
+			return err
+		}
+		for _, entry := range target {
+			if entry.TournamentNumber != nil && *entry.TournamentNumber == *number {
+				number = nil
+				break
+			}
+		}
+	}
+	today := b.timeNow().In(b.loc)
+	return b.svc.Rosters.TransferPlayer(ctx, service.TransferInput{
+		TournamentID: tournamentID,
+		PlayerID:     playerID,
+		FromTeamID:   fromTeamID,
+		ToTeamID:     toTeamID,
+		Number:       number,
+		On:           &today,
+	})
 }
 
 func (b *Bot) sendRosterAddPlayerList(ctx context.Context, chatID int64, tournamentID, teamID int64, page int) error {
@@ -951,26 +1425,42 @@ func (b *Bot) sendRosterAddPlayerList(ctx context.Context, chatID int64, tournam
 	for _, entry := range current {
 		inRoster[entry.PlayerID] = struct{}{}
 	}
+	eligibility, err := b.svc.Rosters.Eligibility(ctx, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
 	players, hasNext, err := b.svc.Players.List(ctx, page, perPage)
 	if err != nil {
 		return err
 	}
 	var builder strings.Builder
 	builder.WriteString("*Выберите игрока*\n")
+	builder.WriteString("_Для поиска отправьте часть имени._\n")
+	if ages := describeAgeLimits(&eligibility.Regulations); ages != "" {
+		builder.WriteString(fmt.Sprintf("Возраст: %s\n", ages))
+	}
 	keyboard := [][]tgbotapi.InlineKeyboardButton{}
-	showPlayers := players
-	for _, player := range showPlayers {
+	hidden := 0
+	for _, player := range players {
 		if _, exists := inRoster[player.ID]; exists {
 			builder.WriteString(fmt.Sprintf("✅ %s\n", escape(player.FullName)))
 			continue
 		}
-		builder.WriteString(fmt.Sprintf("- %s\n", escape(player.FullName)))
+		line, ok := eligibleLine(eligibility, player)
+		if !ok {
+			hidden++
+			continue
+		}
+		builder.WriteString(line + "\n")
 		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData(
 				fmt.Sprintf("➕ %s", truncateLabel(player.FullName, 25)),
 				fmt.Sprintf("roster_add_pick|t=%d|team=%d|player=%d", tournamentID, teamID, player.ID)),
 		})
 	}
+	if hidden > 0 {
+		builder.WriteString(fmt.Sprintf("_Не подходят по возрасту: %d._\n", hidden))
+	}
 	pagination := []tgbotapi.InlineKeyboardButton{}
 	if page > 1 {
 		pagination = append(pagination, tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", fmt.Sprintf("roster_add_player|t=%d|team=%d|page=%d", tournamentID, teamID, page-1)))
@@ -984,10 +1474,100 @@ func (b *Bot) sendRosterAddPlayerList(ctx context.Context, chatID int64, tournam
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ К заявке", fmt.Sprintf("roster_open_team|t=%d|team=%d", tournamentID, teamID)),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
+	err = b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
+	b.setPicker(sessionKeyFor(ctx, chatID), playerPicker{Mode: pickerRosterAdd, TournamentID: tournamentID, TeamID: teamID})
+	return err
+}
+
+// eligibleLine describes a player in the roster picker and reports whether
+// the age rules let the team enter them.
+func eligibleLine(eligibility *service.Eligibility, player models.Player) (string, bool) {
+	name := escape(player.FullName)
+	if player.BirthDate != nil {
+		name += fmt.Sprintf(" (%d)", player.BirthDate.Year())
+	}
+	switch eligibility.Status(player) {
+	case models.AgeIneligible:
+		return fmt.Sprintf("🚫 %s — не подходит по возрасту", name), false
+	case models.AgeOverage:
+		return fmt.Sprintf("- %s — старше возраста, мест осталось: %d", name, eligibility.OverageLeft), true
+	}
+	return "- " + name, true
+}
+
+func (b *Bot) sendPlayerSearch(ctx context.Context, chatID int64, picker playerPicker, query string) error {
+	query = strings.TrimSpace(query)
+	players, err := b.svc.Players.Search(ctx, query, searchLimit)
+	if err != nil {
+		if errors.Is(err, models.ErrValidation) {
+			b.sendSimple(chatID, "Введите часть имени игрока.")
+			return nil
+		}
+		return err
+	}
+	inRoster := map[int64]struct{}{}
+	var eligibility *service.Eligibility
+	if picker.Mode == pickerRosterAdd {
+		current, err := b.svc.Rosters.ListRoster(ctx, picker.TournamentID, picker.TeamID)
+		if err != nil {
+			return err
+		}
+		for _, entry := range current {
+			inRoster[entry.PlayerID] = struct{}{}
+		}
+		if eligibility, err = b.svc.Rosters.Eligibility(ctx, picker.TournamentID, picker.TeamID); err != nil {
+			return err
+		}
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("*Поиск: %s*\n", escape(query)))
+	if len(players) == 0 {
+		builder.WriteString("Никого не нашли. Попробуйте другое написание.\n")
+	}
+	keyboard := make([][]tgbotapi.InlineKeyboardButton, 0, len(players)+1)
+	for _, p := range players {
+		if _, exists := inRoster[p.ID]; exists {
+			builder.WriteString(fmt.Sprintf("✅ %s\n", escape(p.FullName)))
+			continue
+		}
+		line := fmt.Sprintf("- %s", escape(p.FullName))
+		if p.BirthDate != nil {
+			line += fmt.Sprintf(" (%d)", p.BirthDate.Year())
+		}
+		if eligibility != nil {
+			var eligible bool
+			if line, eligible = eligibleLine(eligibility, p); !eligible {
+				builder.WriteString(line + "\n")
+				continue
+			}
+		}
+		builder.WriteString(line + "\n")
+		switch picker.Mode {
+		case pickerRosterAdd:
+			keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+				tgbotapi.NewInlineKeyboardButtonData(
+					fmt.Sprintf("➕ %s", truncateLabel(p.FullName, 25)),
+					fmt.Sprintf("roster_add_pick|t=%d|team=%d|player=%d", picker.TournamentID, picker.TeamID, p.ID)),
+			})
+		default:
+			keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+				tgbotapi.NewInlineKeyboardButtonData(
+					fmt.Sprintf("Открыть %s", truncateLabel(p.FullName, 25)),
+					fmt.Sprintf("player_open|id=%d|page=1", p.ID)),
+			})
+		}
+	}
+	if picker.Mode == pickerRosterAdd {
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("⬅ К списку", fmt.Sprintf("roster_add_player|t=%d|team=%d|page=1", picker.TournamentID, picker.TeamID)),
+		})
+	} else {
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("⬅ Все игроки", "players_page|page=1"),
+		})
+	}
+	err = b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
+	b.setPicker(sessionKeyFor(ctx, chatID), picker)
 	return err
 }
 
@@ -1009,13 +1589,7 @@ func (b *Bot) sendGamesTournaments(ctx context.Context, chatID int64) error {
 				fmt.Sprintf("games_open_tournament|id=%d", t.ID)),
 		})
 	}
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	if len(keyboard) > 0 {
-		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	}
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
 func (b *Bot) sendGamesTeams(ctx context.Context, chatID int64, tournamentID int64) error {
@@ -1026,24 +1600,20 @@ func (b *Bot) sendGamesTeams(ctx context.Context, chatID int64, tournamentID int
 	var builder strings.Builder
 	builder.WriteString("*Матчи — выберите команду*\n")
 	if len(teams) == 0 {
-		builder.WriteString("Нет команд с заявкой в этом турнире.")
+		builder.WriteString("В этом турнире нет зарегистрированных команд.")
 	}
 	keyboard := make([][]tgbotapi.InlineKeyboardButton, 0, len(teams)+1)
 	for _, team := range teams {
 		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData(
-				team.TeamName,
+				tournamentTeamLabel(team),
 				fmt.Sprintf("games_open_team|t=%d|team=%d", tournamentID, team.TeamID)),
 		})
 	}
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "games"),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
 func (b *Bot) sendGamesMatches(ctx context.Context, chatID int64, tournamentID, teamID int64) error {
@@ -1071,14 +1641,17 @@ func (b *Bot) sendGamesMatches(ctx context.Context, chatID int64, tournamentID,
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("➕ Создать матч", fmt.Sprintf("match_start_create|t=%d|team=%d", tournamentID, teamID)),
 	})
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("📋 Загрузить расписание", fmt.Sprintf("match_start_import|t=%d|team=%d", tournamentID, teamID)),
+		tgbotapi.NewInlineKeyboardButtonData("📅 Импорт .ics", fmt.Sprintf("match_start_ics|t=%d|team=%d", tournamentID, teamID)),
+	})
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🔄 Сгенерировать круговой турнир", fmt.Sprintf("match_start_league|t=%d|team=%d", tournamentID, teamID)),
+	})
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "nav_back"),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
 func (b *Bot) showMatch(ctx context.Context, chatID int64, matchID int64) error {
@@ -1099,26 +1672,40 @@ func (b *Bot) renderMatch(ctx context.Context, chatID int64, match *models.Match
 	if err != nil {
 		return err
 	}
+	tournament, err := b.svc.Tournaments.Get(ctx, match.TournamentID)
+	if err != nil {
+		return err
+	}
+	scored := tournament.Format.Scored()
 	var builder strings.Builder
 	builder.WriteString("*Матч*\n")
 	builder.WriteString(fmt.Sprintf("%s vs %s\n", match.StartTime.In(b.loc).Format("02.01.2006 15:04"), escape(match.OpponentName)))
 	if match.Location != nil && *match.Location != "" {
 		builder.WriteString(fmt.Sprintf("Место: %s\n", escape(*match.Location)))
 	}
+	if match.Home != nil {
+		builder.WriteString(fmt.Sprintf("Поле: %s\n", homeLabel(*match.Home)))
+	}
+	if match.Stage != nil {
+		builder.WriteString(fmt.Sprintf("Стадия: %s\n", stageLabel(*match.Stage)))
+	}
 	builder.WriteString(fmt.Sprintf("Статус: %s\n", match.Status))
-	if match.ScoreHT != nil {
+	if !scored {
+		builder.WriteString("_Фестиваль: счёт не ведётся._\n")
+	}
+	if scored && match.ScoreHT != nil {
 		builder.WriteString(fmt.Sprintf("HT: %s\n", *match.ScoreHT))
 	}
-	if match.ScoreFT != nil {
+	if scored && match.ScoreFT != nil {
 		builder.WriteString(fmt.Sprintf("FT: %s\n", *match.ScoreFT))
 	}
-	if match.ScoreET != nil {
+	if scored && match.ScoreET != nil {
 		builder.WriteString(fmt.Sprintf("ET: %s\n", *match.ScoreET))
 	}
-	if match.ScorePEN != nil {
+	if scored && match.ScorePEN != nil {
 		builder.WriteString(fmt.Sprintf("PEN: %s\n", *match.ScorePEN))
 	}
-	if match.ScoreFinalUs != nil || match.ScoreFinalThem != nil {
+	if scored && (match.ScoreFinalUs != nil || match.ScoreFinalThem != nil) {
 		builder.WriteString(fmt.Sprintf("Итог: %d:%d\n", safeInt(match.ScoreFinalUs), safeInt(match.ScoreFinalThem)))
 	}
 	builder.WriteString("\n*Состав*\n")
@@ -1159,27 +1746,25 @@ func (b *Bot) renderMatch(ctx context.Context, chatID int64, match *models.Match
 		matchStatusButton(matchID, match.Status, models.MatchStatusPlayed),
 		matchStatusButton(matchID, match.Status, models.MatchStatusCanceled),
 	}
-	keyboard := tgbotapi.NewInlineKeyboardMarkup(
-		[]tgbotapi.InlineKeyboardButton{
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{
 			tgbotapi.NewInlineKeyboardButtonData("✏ Редактировать", fmt.Sprintf("match_edit|id=%d", matchID)),
 		},
 		statusRow,
-		[]tgbotapi.InlineKeyboardButton{
+		{
 			tgbotapi.NewInlineKeyboardButtonData("👥 Состав", fmt.Sprintf("match_lineup_menu|match=%d", matchID)),
 			tgbotapi.NewInlineKeyboardButtonData("⚽ События", fmt.Sprintf("match_events_menu|match=%d", matchID)),
 		},
-		[]tgbotapi.InlineKeyboardButton{
+	}
+	if scored {
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 			tgbotapi.NewInlineKeyboardButtonData("🔁 Сбросить счёт", fmt.Sprintf("match_scores_reset|id=%d", matchID)),
-		},
-		[]tgbotapi.InlineKeyboardButton{
-			tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "nav_back"),
-		},
-	)
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = keyboard
-	_, err = b.api.Send(msg)
-	return err
+		})
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "nav_back"),
+	})
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
 func (b *Bot) sendLineupMenu(ctx context.Context, chatID int64, matchID int64) error {
@@ -1187,11 +1772,24 @@ func (b *Bot) sendLineupMenu(ctx context.Context, chatID int64, matchID int64) e
 	if err != nil {
 		return err
 	}
+	regulations, err := b.matchRegulations(ctx, matchID)
+	if err != nil {
+		return err
+	}
 	var builder strings.Builder
 	builder.WriteString("*Состав матча*\n")
 	if len(lineup) == 0 {
 		builder.WriteString("Пока пусто.\n")
 	} else {
+		starters, bench := lineupCounts(lineup)
+		benchLimit := ""
+		if regulations.MaxBench != nil {
+			benchLimit = fmt.Sprintf("/%d", *regulations.MaxBench)
+		}
+		builder.WriteString(fmt.Sprintf("В старте: %d/%d, запасных: %d%s\n", starters, regulations.MaxStarters, bench, benchLimit))
+		if starters < regulations.MinStarters {
+			builder.WriteString(fmt.Sprintf("_Для матча нужно не меньше %d игроков в старте._\n", regulations.MinStarters))
+		}
 		for _, l := range lineup {
 			role := string(l.Role)
 			if l.NumberOverride != nil {
@@ -1217,11 +1815,7 @@ func (b *Bot) sendLineupMenu(ctx context.Context, chatID int64, matchID int64) e
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ К матчу", fmt.Sprintf("open_match|id=%d", matchID)),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
 func (b *Bot) sendLineupAddList(ctx context.Context, chatID int64, matchID int64, page int) error {
@@ -1229,7 +1823,7 @@ func (b *Bot) sendLineupAddList(ctx context.Context, chatID int64, matchID int64
 	if err != nil {
 		return err
 	}
-	roster, err := b.svc.Rosters.ListRoster(ctx, match.TournamentID, match.TeamID)
+	roster, err := b.svc.Rosters.ListRosterOn(ctx, match.TournamentID, match.TeamID, match.StartTime)
 	if err != nil {
 		return err
 	}
@@ -1299,22 +1893,55 @@ func (b *Bot) sendLineupAddList(ctx context.Context, chatID int64, matchID int64
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ К составу", fmt.Sprintf("match_lineup_menu|match=%d", matchID)),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
+// addPlayerToLineup puts the player in the starting lineup or, once it is
+// full, on the bench.
 func (b *Bot) addPlayerToLineup(ctx context.Context, chatID int64, matchID, playerID int64) error {
-	if err := b.svc.Lineup.Upsert(ctx, matchID, playerID, models.LineupRoleStart, nil, nil); err != nil {
+	lineup, err := b.svc.Lineup.Get(ctx, matchID)
+	if err != nil {
+		return err
+	}
+	regulations, err := b.matchRegulations(ctx, matchID)
+	if err != nil {
+		return err
+	}
+	role := models.LineupRoleStart
+	if starters, _ := lineupCounts(lineup); starters >= regulations.MaxStarters {
+		role = models.LineupRoleSub
+	}
+	if err := b.svc.Lineup.Upsert(ctx, matchID, playerID, role, nil, nil); err != nil {
 		b.sendSimple(chatID, fmt.Sprintf("Не удалось добавить игрока: %v", err))
 		return nil
 	}
-	b.sendSimple(chatID, "Игрок добавлен в состав.")
+	if role == models.LineupRoleSub {
+		b.sendSimple(chatID, "Стартовый состав заполнен, игрок добавлен в запас.")
+	} else {
+		b.sendSimple(chatID, "Игрок добавлен в состав.")
+	}
 	return b.sendLineupMenu(ctx, chatID, matchID)
 }
 
+func (b *Bot) matchRegulations(ctx context.Context, matchID int64) (*models.Regulations, error) {
+	match, err := b.svc.Matches.Get(ctx, matchID)
+	if err != nil {
+		return nil, err
+	}
+	return b.svc.Tournaments.Regulations(ctx, match.TournamentID)
+}
+
+func lineupCounts(lineup []models.MatchLineup) (starters, bench int) {
+	for _, entry := range lineup {
+		if entry.Role == models.LineupRoleStart {
+			starters++
+		} else {
+			bench++
+		}
+	}
+	return starters, bench
+}
+
 func (b *Bot) removePlayerFromLineup(ctx context.Context, chatID int64, matchID, playerID int64) error {
 	if err := b.svc.Lineup.Remove(ctx, matchID, playerID); err != nil {
 		b.sendSimple(chatID, fmt.Sprintf("Не удалось удалить игрока: %v", err))
@@ -1352,22 +1979,6 @@ func (b *Bot) toggleLineupRole(ctx context.Context, chatID int64, matchID, playe
 	return b.sendLineupMenu(ctx, chatID, matchID)
 }
 
-func (b *Bot) startLineupNumberWizard(ctx context.Context, chatID, adminID int64, matchID, playerID int64) error {
-	state := &wizardState{
-		Flow: flowLineupNumber,
-		Step: 0,
-		Data: map[string]string{
-			"match_id":  strconv.FormatInt(matchID, 10),
-			"player_id": strconv.FormatInt(playerID, 10),
-		},
-	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, "Введите номер игрока на матч (или '-' чтобы очистить).")
-	return nil
-}
-
 func (b *Bot) sendEventsMenu(ctx context.Context, chatID int64, matchID int64) error {
 	events, err := b.svc.Events.List(ctx, matchID)
 	if err != nil {
@@ -1402,11 +2013,7 @@ func (b *Bot) sendEventsMenu(ctx context.Context, chatID int64, matchID int64) e
 			tgbotapi.NewInlineKeyboardButtonData("⬅ К матчу", fmt.Sprintf("open_match|id=%d", matchID)),
 		},
 	)
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = keyboard
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), keyboard)
 }
 
 func (b *Bot) sendEventsGoalPlayerList(ctx context.Context, chatID int64, matchID int64) error {
@@ -1431,11 +2038,7 @@ func (b *Bot) sendEventsGoalPlayerList(ctx context.Context, chatID int64, matchI
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", fmt.Sprintf("match_events_menu|match=%d", matchID)),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
 func (b *Bot) sendEventsCardPlayerList(ctx context.Context, chatID int64, matchID int64) error {
@@ -1460,11 +2063,7 @@ func (b *Bot) sendEventsCardPlayerList(ctx context.Context, chatID int64, matchI
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", fmt.Sprintf("match_events_menu|match=%d", matchID)),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
 func (b *Bot) sendEventsCardTypeMenu(ctx context.Context, chatID int64, matchID, playerID int64) error {
@@ -1474,10 +2073,7 @@ func (b *Bot) sendEventsCardTypeMenu(ctx context.Context, chatID int64, matchID,
 			tgbotapi.NewInlineKeyboardButtonData("🟥 Красная", fmt.Sprintf("match_events_card_type|match=%d|player=%d|type=red", matchID, playerID)),
 		},
 	)
-	msg := tgbotapi.NewMessage(chatID, "Выберите тип карточки:")
-	msg.ReplyMarkup = keyboard
-	_, err := b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, "Выберите тип карточки:", keyboard)
 }
 
 func (b *Bot) sendEventSubOutList(ctx context.Context, chatID int64, matchID int64) error {
@@ -1502,11 +2098,7 @@ func (b *Bot) sendEventSubOutList(ctx context.Context, chatID int64, matchID int
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", fmt.Sprintf("match_events_menu|match=%d", matchID)),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
 func (b *Bot) sendEventSubInList(ctx context.Context, chatID int64, matchID, outPlayerID int64) error {
@@ -1514,7 +2106,7 @@ func (b *Bot) sendEventSubInList(ctx context.Context, chatID int64, matchID, out
 	if err != nil {
 		return err
 	}
-	roster, err := b.svc.Rosters.ListRoster(ctx, match.TournamentID, match.TeamID)
+	roster, err := b.svc.Rosters.ListRosterOn(ctx, match.TournamentID, match.TeamID, match.StartTime)
 	if err != nil {
 		return err
 	}
@@ -1531,1462 +2123,166 @@ func (b *Bot) sendEventSubInList(ctx context.Context, chatID int64, matchID, out
 	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
 		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", fmt.Sprintf("match_events_menu|match=%d", matchID)),
 	})
-	msg := tgbotapi.NewMessage(chatID, builder.String())
-	msg.ParseMode = "Markdown"
-	msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(keyboard...)
-	_, err = b.api.Send(msg)
-	return err
-}
-
-// ----------------------------------------------------------------------------
-// Wizards (minimal)
-
-type wizardState struct {
-	Flow string            `json:"flow"`
-	Step int               `json:"step"`
-	Data map[string]string `json:"data"`
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
 }
 
-func (b *Bot) startTournamentWizard(ctx context.Context, chatID, adminID int64) error {
-	state := &wizardState{
-		Flow: flowCreateTournament,
-		Step: 0,
-		Data: make(map[string]string),
+func (b *Bot) setMatchStatus(ctx context.Context, chatID int64, matchID int64, statusText string) error {
+	status := models.MatchStatus(strings.ToLower(statusText))
+	switch status {
+	case models.MatchStatusScheduled, models.MatchStatusPlayed, models.MatchStatusCanceled:
+	default:
+		b.sendSimple(chatID, "Неизвестный статус.")
+		return nil
 	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
+	if err := b.svc.Matches.Update(ctx, matchID, models.MatchPatch{Status: &status}); err != nil {
+		b.sendSimple(chatID, fmt.Sprintf("Не удалось обновить статус: %v", err))
+		return nil
 	}
-	b.sendSimple(chatID, "Создание турнира: введите название.")
-	return nil
+	b.sendSimple(chatID, fmt.Sprintf("Статус матча: %s", statusLabel(status)))
+	return b.showMatch(ctx, chatID, matchID)
 }
 
-func (b *Bot) advanceWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	switch state.Flow {
-	case flowCreateTournament:
-		return b.advanceTournamentWizard(ctx, msg, state)
-	case flowEditTournament:
-		return b.advanceTournamentEditWizard(ctx, msg, state)
-	case flowCreateTeam:
-		return b.advanceTeamWizard(ctx, msg, state)
-	case flowEditTeam:
-		return b.advanceTeamEditWizard(ctx, msg, state)
-	case flowCreatePlayer:
-		return b.advancePlayerWizard(ctx, msg, state)
-	case flowEditPlayer:
-		return b.advancePlayerEditWizard(ctx, msg, state)
-	case flowRosterAddPlayer:
-		return b.advanceRosterAddWizard(ctx, msg, state)
-	case flowRosterChangeNumber:
-		return b.advanceRosterChangeWizard(ctx, msg, state)
-	case flowMatchCreate:
-		return b.advanceMatchCreateWizard(ctx, msg, state)
-	case flowMatchEdit:
-		return b.advanceMatchEditWizard(ctx, msg, state)
-	case flowLineupNumber:
-		return b.advanceLineupNumberWizard(ctx, msg, state)
-	case flowEventGoal:
-		return b.advanceEventGoalWizard(ctx, msg, state)
-	case flowEventCard:
-		return b.advanceEventCardWizard(ctx, msg, state)
-	case flowEventSub:
-		return b.advanceEventSubWizard(ctx, msg, state)
-	default:
+func (b *Bot) resetMatchScores(ctx context.Context, chatID int64, matchID int64) error {
+	patch := models.MatchPatch{
+		ScoreHT:        models.NewOptionalString(nil),
+		ScoreFT:        models.NewOptionalString(nil),
+		ScoreET:        models.NewOptionalString(nil),
+		ScorePEN:       models.NewOptionalString(nil),
+		ScoreFinalUs:   models.NewOptionalInt(nil),
+		ScoreFinalThem: models.NewOptionalInt(nil),
+	}
+	if err := b.svc.Matches.Update(ctx, matchID, patch); err != nil {
+		b.sendSimple(chatID, fmt.Sprintf("Не удалось сбросить счёт: %v", err))
 		return nil
 	}
+	b.sendSimple(chatID, "Счёт матча сброшен.")
+	return b.showMatch(ctx, chatID, matchID)
 }
 
-func (b *Bot) advanceTournamentWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	switch state.Step {
-	case 0:
-		state.Data["name"] = text
-		state.Step++
-		b.sendSimple(chatID, "Введите тип турнира (или '-' для пропуска).")
-	case 1:
-		if text != "-" && text != "" {
-			state.Data["type"] = text
-		}
-		state.Step++
-		b.sendSimple(chatID, "Укажите статус (planned/active/finished).")
-	case 2:
-		if text == "" {
-			text = "planned"
-		}
-		switch models.TournamentStatus(text) {
-		case models.TournamentStatusPlanned, models.TournamentStatusActive, models.TournamentStatusFinished:
-			state.Data["status"] = text
-		default:
-			b.sendSimple(chatID, "Неверный статус. Попробуйте ещё раз.")
-			return nil
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите дату начала в формате YYYY-MM-DD или '-' для пропуска.")
-	case 3:
-		if text != "-" && text != "" {
-			if _, err := time.Parse("2006-01-02", text); err != nil {
-				b.sendSimple(chatID, "Неверный формат даты. Укажите YYYY-MM-DD.")
-				return nil
-			}
-			state.Data["start_date"] = text
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите дату окончания в формате YYYY-MM-DD или '-' для пропуска.")
-	case 4:
-		if text != "-" && text != "" {
-			if _, err := time.Parse("2006-01-02", text); err != nil {
-				b.sendSimple(chatID, "Неверный формат даты. Укажите YYYY-MM-DD.")
-				return nil
-			}
-			state.Data["end_date"] = text
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите примечание (или '-' для пропуска).")
-	case 5:
-		if text != "-" && text != "" {
-			state.Data["note"] = text
-		}
-		if err := b.finishTournamentWizard(ctx, state); err != nil {
-			return err
-		}
-		b.sendSimple(chatID, "Турнир создан.")
-		return b.svc.Sessions.Clear(ctx, adminID)
-	}
-
-	return b.saveSession(ctx, adminID, &state.Flow, state)
+type matchSummary struct {
+	Match          models.Match
+	TeamName       string
+	TournamentName string
+	// Format is the format of the tournament; festival scores are not shown.
+	Format models.TournamentFormat
 }
 
-func (b *Bot) finishTournamentWizard(ctx context.Context, state *wizardState) error {
-	name := state.Data["name"]
-	if name == "" {
-		return errors.New("empty name")
-	}
-	var typ *string
-	if val := state.Data["type"]; val != "" {
-		typ = &val
-	}
-	status := models.TournamentStatus(state.Data["status"])
-	if status == "" {
-		status = models.TournamentStatusPlanned
-	}
-	var start *time.Time
-	if val := state.Data["start_date"]; val != "" {
-		parsed, _ := time.ParseInLocation("2006-01-02", val, b.loc)
-		start = &parsed
+func (b *Bot) collectUpcomingMatches(ctx context.Context, tournamentID int64, teams []models.TournamentTeam) []matchSummary {
+	teamNames := make(map[int64]string, len(teams))
+	for _, tm := range teams {
+		teamNames[tm.TeamID] = tm.TeamName
 	}
-	var end *time.Time
-	if val := state.Data["end_date"]; val != "" {
-		parsed, _ := time.ParseInLocation("2006-01-02", val, b.loc)
-		end = &parsed
+	matches, err := b.svc.Matches.ListAll(ctx, models.MatchFilter{TournamentID: tournamentID})
+	if err != nil {
+		return nil
 	}
-	var note *string
-	if val := state.Data["note"]; val != "" {
-		note = &val
+	var summaries []matchSummary
+	for _, m := range matches {
+		if name, ok := teamNames[m.TeamID]; ok {
+			summaries = append(summaries, matchSummary{Match: m, TeamName: name})
+		}
 	}
-	_, err := b.svc.Tournaments.Create(ctx, service.CreateTournamentInput{
-		Name:      name,
-		Type:      typ,
-		Status:    status,
-		StartDate: start,
-		EndDate:   end,
-		Note:      note,
-	})
-	return err
+	return b.nextMatches(summaries)
 }
 
-func (b *Bot) startTournamentEditWizard(ctx context.Context, chatID, adminID int64, tournamentID int64) error {
-	tournament, err := b.svc.Tournaments.Get(ctx, tournamentID)
-	if err != nil {
-		return err
-	}
-	state := &wizardState{
-		Flow: flowEditTournament,
-		Step: 0,
-		Data: map[string]string{
-			"id":          strconv.FormatInt(tournamentID, 10),
-			"orig_status": string(tournament.Status),
-		},
-	}
-	if tournament.Type != nil {
-		state.Data["orig_type"] = *tournament.Type
-	}
-	if tournament.StartDate != nil {
-		state.Data["orig_start"] = tournament.StartDate.Format("2006-01-02")
-	}
-	if tournament.EndDate != nil {
-		state.Data["orig_end"] = tournament.EndDate.Format("2006-01-02")
-	}
-	if tournament.Note != nil {
-		state.Data["orig_note"] = *tournament.Note
+// nextMatches picks the first three scheduled matches that have not started
+// more than an hour ago.
+func (b *Bot) nextMatches(summaries []matchSummary) []matchSummary {
+	cutoff := b.timeNow().Add(-1 * time.Hour)
+	var upcoming []matchSummary
+	for _, s := range summaries {
+		if s.Match.Status == models.MatchStatusScheduled && s.Match.StartTime.After(cutoff) {
+			upcoming = append(upcoming, s)
+		}
 	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
+	sort.Slice(upcoming, func(i, j int) bool {
+		return upcoming[i].Match.StartTime.Before(upcoming[j].Match.StartTime)
+	})
+	if len(upcoming) > 3 {
+		upcoming = upcoming[:3]
 	}
-	b.sendSimple(chatID, fmt.Sprintf("Текущее название: %s\nВведите новое название (или '-' чтобы оставить).", escape(tournament.Name)))
-	return nil
+	return upcoming
 }
 
-func (b *Bot) advanceTournamentEditWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	switch state.Step {
-	case 0:
-		if text != "" && text != "-" {
-			state.Data["name_new"] = text
-		}
-		state.Step++
-		current := "(пусто)"
-		if val := state.Data["orig_type"]; val != "" {
-			current = escape(val)
-		}
-		b.sendSimple(chatID, fmt.Sprintf("Текущий тип: %s\nВведите новый тип (или '-' чтобы оставить, 'удалить' чтобы очистить).", current))
-	case 1:
-		if text != "" && text != "-" {
-			if strings.EqualFold(text, "удалить") {
-				state.Data["type_action"] = "delete"
-			} else {
-				state.Data["type_new"] = text
-			}
-		}
-		state.Step++
-		status := state.Data["orig_status"]
-		if status == "" {
-			status = string(models.TournamentStatusPlanned)
-		}
-		b.sendSimple(chatID, fmt.Sprintf("Текущий статус: %s\nВведите новый статус (planned/active/finished) или '-' чтобы оставить.", status))
-	case 2:
-		if text != "" && text != "-" {
-			normalized := strings.ToLower(text)
-			switch models.TournamentStatus(normalized) {
-			case models.TournamentStatusPlanned, models.TournamentStatusActive, models.TournamentStatusFinished:
-				state.Data["status_new"] = normalized
-			default:
-				b.sendSimple(chatID, "Недопустимый статус. Используйте planned/active/finished или '-'.")
-				return nil
-			}
-		}
-		state.Step++
-		current := state.Data["orig_start"]
-		if current == "" {
-			current = "(не задана)"
-		}
-		b.sendSimple(chatID, fmt.Sprintf("Текущая дата начала: %s\nВведите новую дату (YYYY-MM-DD), '-' чтобы оставить, 'удалить' чтобы очистить.", current))
-	case 3:
-		if text != "" && text != "-" {
-			if strings.EqualFold(text, "удалить") {
-				state.Data["start_action"] = "delete"
-			} else {
-				if _, err := time.Parse("2006-01-02", text); err != nil {
-					b.sendSimple(chatID, "Неверный формат даты. Используйте YYYY-MM-DD, '-' или 'удалить'.")
-					return nil
-				}
-				state.Data["start_new"] = text
-			}
-		}
-		state.Step++
-		current := state.Data["orig_end"]
-		if current == "" {
-			current = "(не задана)"
-		}
-		b.sendSimple(chatID, fmt.Sprintf("Текущая дата окончания: %s\nВведите новую дату (YYYY-MM-DD), '-' чтобы оставить, 'удалить' чтобы очистить.", current))
-	case 4:
-		if text != "" && text != "-" {
-			if strings.EqualFold(text, "удалить") {
-				state.Data["end_action"] = "delete"
-			} else {
-				if _, err := time.Parse("2006-01-02", text); err != nil {
-					b.sendSimple(chatID, "Неверный формат даты. Используйте YYYY-MM-DD, '-' или 'удалить'.")
-					return nil
-				}
-				state.Data["end_new"] = text
-			}
+func (b *Bot) collectTeamUpcomingMatches(ctx context.Context, tournaments []models.Tournament, teamID int64) []matchSummary {
+	cutoff := b.timeNow().Add(-1 * time.Hour)
+	var summaries []matchSummary
+	for _, t := range tournaments {
+		matches, err := b.svc.Matches.List(ctx, t.ID, teamID)
+		if err != nil {
+			continue
 		}
-		state.Step++
-		current := state.Data["orig_note"]
-		if current == "" {
-			current = "(пусто)"
-		} else {
-			current = escape(current)
-		}
-		b.sendSimple(chatID, fmt.Sprintf("Текущее примечание: %s\nВведите новое примечание, '-' чтобы оставить, 'удалить' чтобы очистить.", current))
-	case 5:
-		if text != "" {
-			if strings.EqualFold(text, "удалить") {
-				state.Data["note_action"] = "delete"
-			} else if text != "-" {
-				state.Data["note_new"] = text
+		for _, m := range matches {
+			if m.Status == models.MatchStatusScheduled && m.StartTime.After(cutoff) {
+				summaries = append(summaries, matchSummary{
+					Match:          m,
+					TournamentName: t.Name,
+				})
 			}
 		}
-		if err := b.finishTournamentEditWizard(ctx, state); err != nil {
-			b.sendSimple(chatID, fmt.Sprintf("Ошибка обновления турнира: %v", err))
-			return nil
-		}
-		b.sendSimple(chatID, "Турнир обновлён.")
-		_ = b.sendTournamentList(ctx, chatID, 1)
-		return b.svc.Sessions.Clear(ctx, adminID)
 	}
-	return b.saveSession(ctx, adminID, &state.Flow, state)
+	if len(summaries) == 0 {
+		return nil
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Match.StartTime.Before(summaries[j].Match.StartTime)
+	})
+	if len(summaries) > 3 {
+		summaries = summaries[:3]
+	}
+	return summaries
 }
 
-func (b *Bot) finishTournamentEditWizard(ctx context.Context, state *wizardState) error {
-	tournamentID := parseInt64(state.Data["id"])
-	patch := models.TournamentPatch{}
+// ----------------------------------------------------------------------------
 
-	if v := state.Data["name_new"]; v != "" {
-		val := v
-		patch.Name = &val
-	}
-	if action := state.Data["type_action"]; action == "delete" {
-		patch.Type = models.NewOptionalString(nil)
-	} else if v := state.Data["type_new"]; v != "" {
-		val := v
-		patch.Type = models.NewOptionalString(&val)
+type callbackPayload struct {
+	Action string
+	Params map[string]string
+}
+
+func parseCallback(data string) (*callbackPayload, error) {
+	parts := strings.Split(data, "|")
+	if len(parts) == 0 {
+		return nil, errors.New("empty callback")
 	}
-	if v := state.Data["status_new"]; v != "" {
-		status := models.TournamentStatus(v)
-		patch.Status = &status
+	payload := &callbackPayload{
+		Action: parts[0],
+		Params: map[string]string{},
 	}
-	if action := state.Data["start_action"]; action == "delete" {
-		patch.StartDate = models.NewOptionalTime(nil)
-	} else if v := state.Data["start_new"]; v != "" {
-		parsed, err := time.ParseInLocation("2006-01-02", v, b.loc)
-		if err != nil {
-			return err
+	for _, part := range parts[1:] {
+		if part == "" {
+			continue
 		}
-		patch.StartDate = models.NewOptionalTime(&parsed)
-	}
-	if action := state.Data["end_action"]; action == "delete" {
-		patch.EndDate = models.NewOptionalTime(nil)
-	} else if v := state.Data["end_new"]; v != "" {
-		parsed, err := time.ParseInLocation("2006-01-02", v, b.loc)
-		if err != nil {
-			return err
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			continue
 		}
-		patch.EndDate = models.NewOptionalTime(&parsed)
-	}
-	if action := state.Data["note_action"]; action == "delete" {
-		patch.Note = models.NewOptionalString(nil)
-	} else if v := state.Data["note_new"]; v != "" {
-		val := v
-		patch.Note = models.NewOptionalString(&val)
+		payload.Params[kv[0]] = kv[1]
 	}
+	return payload, nil
+}
 
-	return b.svc.Tournaments.Update(ctx, tournamentID, patch)
+func escape(s string) string {
+	replacer := strings.NewReplacer(
+		"_", "\\_",
+		"*", "\\*",
+		"`", "\\`",
+		"[", "\\[",
+	)
+	return replacer.Replace(s)
 }
 
-func (b *Bot) startTeamWizard(ctx context.Context, chatID, adminID int64) error {
-	state := &wizardState{
-		Flow: flowCreateTeam,
-		Step: 0,
-		Data: make(map[string]string),
+func parseInt64(value string) int64 {
+	if value == "" {
+		return 0
 	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, "Создание команды: введите название.")
-	return nil
-}
-
-func (b *Bot) advanceTeamWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	switch state.Step {
-	case 0:
-		if text == "" {
-			b.sendSimple(chatID, "Название не может быть пустым. Повторите ввод.")
-			return nil
-		}
-		state.Data["name"] = text
-		state.Step++
-		b.sendSimple(chatID, "Введите короткий код (например, U12).")
-	case 1:
-		if text == "" {
-			b.sendSimple(chatID, "Короткий код не может быть пустым.")
-			return nil
-		}
-		state.Data["short_code"] = text
-		state.Step++
-		b.sendSimple(chatID, "Команда активна? (да/нет, по умолчанию да).")
-	case 2:
-		if text == "" {
-			state.Data["active"] = "true"
-		} else {
-			val, ok := parseYesNo(text)
-			if !ok {
-				b.sendSimple(chatID, "Введите 'да' или 'нет'.")
-				return nil
-			}
-			state.Data["active"] = strconv.FormatBool(val)
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите примечание (или '-' для пропуска).")
-	case 3:
-		if text != "-" && text != "" {
-			state.Data["note"] = text
-		}
-		if err := b.finishTeamWizard(ctx, state); err != nil {
-			b.sendSimple(chatID, fmt.Sprintf("Не удалось создать команду: %v", err))
-		} else {
-			b.sendSimple(chatID, "Команда создана.")
-			_ = b.sendTeams(ctx, chatID)
-		}
-		return b.svc.Sessions.Clear(ctx, adminID)
-	}
-	return b.saveSession(ctx, adminID, &state.Flow, state)
-}
-
-func (b *Bot) finishTeamWizard(ctx context.Context, state *wizardState) error {
-	active := true
-	if val := state.Data["active"]; val != "" {
-		parsed, err := strconv.ParseBool(val)
-		if err == nil {
-			active = parsed
-		}
-	}
-	var note *string
-	if val := state.Data["note"]; val != "" {
-		note = &val
-	}
-	_, err := b.svc.Teams.Create(ctx, service.CreateTeamInput{
-		Name:      state.Data["name"],
-		ShortCode: state.Data["short_code"],
-		Active:    active,
-		Note:      note,
-	})
-	return err
-}
-
-func (b *Bot) startTeamEditWizard(ctx context.Context, chatID, adminID int64, teamID int64) error {
-	team, err := b.svc.Teams.Get(ctx, teamID)
-	if err != nil {
-		return err
-	}
-	state := &wizardState{
-		Flow: flowEditTeam,
-		Step: 0,
-		Data: map[string]string{
-			"id": strconv.FormatInt(team.ID, 10),
-		},
-	}
-	if team.Note != nil {
-		state.Data["orig_note"] = *team.Note
-	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, fmt.Sprintf("Текущее название: %s\nВведите новое название (или '-' чтобы оставить).", escape(team.Name)))
-	return nil
-}
-
-func (b *Bot) advanceTeamEditWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	switch state.Step {
-	case 0:
-		if text != "" && text != "-" {
-			state.Data["name_new"] = text
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите новый короткий код (или '-' чтобы оставить).")
-	case 1:
-		if text != "" && text != "-" {
-			state.Data["code_new"] = text
-		}
-		state.Step++
-		b.sendSimple(chatID, "Команда активна? (да/нет, '-' чтобы оставить текущее значение).")
-	case 2:
-		if text != "" && text != "-" {
-			val, ok := parseYesNo(text)
-			if !ok {
-				b.sendSimple(chatID, "Введите 'да', 'нет' или '-' чтобы оставить без изменений.")
-				return nil
-			}
-			state.Data["active_new"] = strconv.FormatBool(val)
-		}
-		state.Step++
-		current := state.Data["orig_note"]
-		if current == "" {
-			current = "(пусто)"
-		} else {
-			current = escape(current)
-		}
-		b.sendSimple(chatID, fmt.Sprintf("Текущее примечание: %s\nВведите новое примечание, '-' чтобы оставить, 'удалить' чтобы очистить.", current))
-	case 3:
-		if text != "" {
-			if strings.EqualFold(text, "удалить") {
-				state.Data["note_action"] = "delete"
-			} else if text != "-" {
-				state.Data["note_new"] = text
-			}
-		}
-		if err := b.finishTeamEditWizard(ctx, state); err != nil {
-			b.sendSimple(chatID, fmt.Sprintf("Не удалось обновить команду: %v", err))
-			return nil
-		}
-		b.sendSimple(chatID, "Команда обновлена.")
-		teamID := parseInt64(state.Data["id"])
-		_ = b.showTeam(ctx, chatID, teamID)
-		return b.svc.Sessions.Clear(ctx, adminID)
-	}
-	return b.saveSession(ctx, adminID, &state.Flow, state)
-}
-
-func (b *Bot) finishTeamEditWizard(ctx context.Context, state *wizardState) error {
-	teamID := parseInt64(state.Data["id"])
-	patch := models.TeamPatch{}
-	if v := state.Data["name_new"]; v != "" {
-		val := v
-		patch.Name = &val
-	}
-	if v := state.Data["code_new"]; v != "" {
-		val := v
-		patch.ShortCode = &val
-	}
-	if v := state.Data["active_new"]; v != "" {
-		if parsed, err := strconv.ParseBool(v); err == nil {
-			value := parsed
-			patch.Active = &value
-		} else {
-			return err
-		}
-	}
-	if state.Data["note_action"] == "delete" {
-		patch.Note = models.NewOptionalString(nil)
-	} else if v := state.Data["note_new"]; v != "" {
-		val := v
-		patch.Note = models.NewOptionalString(&val)
-	}
-	return b.svc.Teams.Update(ctx, teamID, patch)
-}
-
-func (b *Bot) startPlayerWizard(ctx context.Context, chatID, adminID int64) error {
-	state := &wizardState{
-		Flow: flowCreatePlayer,
-		Step: 0,
-		Data: make(map[string]string),
-	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, "Создание игрока: укажите ФИО.")
-	return nil
-}
-
-func (b *Bot) advancePlayerWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	switch state.Step {
-	case 0:
-		if text == "" {
-			b.sendSimple(chatID, "ФИО не может быть пустым.")
-			return nil
-		}
-		state.Data["full_name"] = text
-		state.Step++
-		b.sendSimple(chatID, "Введите дату рождения (YYYY-MM-DD) или '-' для пропуска.")
-	case 1:
-		if text != "-" && text != "" {
-			if _, err := time.Parse("2006-01-02", text); err != nil {
-				b.sendSimple(chatID, "Неверный формат даты. Используйте YYYY-MM-DD.")
-				return nil
-			}
-			state.Data["birth_date"] = text
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите игровую позицию (или '-' для пропуска).")
-	case 2:
-		if text != "-" && text != "" {
-			state.Data["position"] = text
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите примечание (или '-' для пропуска).")
-	case 3:
-		if text != "-" && text != "" {
-			state.Data["note"] = text
-		}
-		if err := b.finishPlayerWizard(ctx, state); err != nil {
-			b.sendSimple(chatID, fmt.Sprintf("Не удалось создать игрока: %v", err))
-		} else {
-			b.sendSimple(chatID, "Игрок создан.")
-			_ = b.sendPlayersPage(ctx, chatID, 1)
-		}
-		return b.svc.Sessions.Clear(ctx, adminID)
-	}
-	return b.saveSession(ctx, adminID, &state.Flow, state)
-}
-
-func (b *Bot) finishPlayerWizard(ctx context.Context, state *wizardState) error {
-	var birth *time.Time
-	if val := state.Data["birth_date"]; val != "" {
-		parsed, err := time.ParseInLocation("2006-01-02", val, b.loc)
-		if err == nil {
-			birth = &parsed
-		}
-	}
-	var position *string
-	if val := state.Data["position"]; val != "" {
-		position = &val
-	}
-	var note *string
-	if val := state.Data["note"]; val != "" {
-		note = &val
-	}
-	_, err := b.svc.Players.Create(ctx, service.CreatePlayerInput{
-		FullName: state.Data["full_name"],
-		Birth:    birth,
-		Position: position,
-		Active:   true,
-		Note:     note,
-	})
-	return err
-}
-
-func (b *Bot) startPlayerEditWizard(ctx context.Context, chatID, adminID int64, playerID int64, page int) error {
-	player, err := b.svc.Players.Get(ctx, playerID)
-	if err != nil {
-		return err
-	}
-	state := &wizardState{
-		Flow: flowEditPlayer,
-		Step: 0,
-		Data: map[string]string{
-			"id":          strconv.FormatInt(playerID, 10),
-			"return_page": strconv.FormatInt(int64(page), 10),
-		},
-	}
-	if player.BirthDate != nil {
-		state.Data["orig_birth"] = player.BirthDate.Format("2006-01-02")
-	}
-	if player.Position != nil {
-		state.Data["orig_position"] = *player.Position
-	}
-	if player.Note != nil {
-		state.Data["orig_note"] = *player.Note
-	}
-	state.Data["orig_active"] = strconv.FormatBool(player.Active)
-
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, fmt.Sprintf("Текущее ФИО: %s\nВведите новое ФИО (или '-' чтобы оставить).", escape(player.FullName)))
-	return nil
-}
-
-func (b *Bot) advancePlayerEditWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	switch state.Step {
-	case 0:
-		if text != "" && text != "-" {
-			state.Data["name_new"] = text
-		}
-		state.Step++
-		current := state.Data["orig_birth"]
-		if current == "" {
-			current = "(не задана)"
-		}
-		b.sendSimple(chatID, fmt.Sprintf("Текущая дата рождения: %s\nВведите новую дату (YYYY-MM-DD), '-' чтобы оставить, 'удалить' чтобы очистить.", current))
-	case 1:
-		if text != "" && text != "-" {
-			if strings.EqualFold(text, "удалить") {
-				state.Data["birth_action"] = "delete"
-			} else {
-				if _, err := time.Parse("2006-01-02", text); err != nil {
-					b.sendSimple(chatID, "Неверный формат даты. Используйте YYYY-MM-DD, '-' или 'удалить'.")
-					return nil
-				}
-				state.Data["birth_new"] = text
-			}
-		}
-		state.Step++
-		current := state.Data["orig_position"]
-		if current == "" {
-			current = "(пусто)"
-		} else {
-			current = escape(current)
-		}
-		b.sendSimple(chatID, fmt.Sprintf("Текущая позиция: %s\nВведите новую позицию, '-' чтобы оставить, 'удалить' чтобы очистить.", current))
-	case 2:
-		if text != "" && text != "-" {
-			if strings.EqualFold(text, "удалить") {
-				state.Data["position_action"] = "delete"
-			} else {
-				state.Data["position_new"] = text
-			}
-		}
-		state.Step++
-		currentStatus := "активен"
-		if state.Data["orig_active"] == "false" {
-			currentStatus = "неактивен"
-		}
-		b.sendSimple(chatID, fmt.Sprintf("Игрок сейчас %s.\nВведите 'да'/'нет' чтобы изменить активность или '-' чтобы оставить.", currentStatus))
-	case 3:
-		if text != "" && text != "-" {
-			val, ok := parseYesNo(text)
-			if !ok {
-				b.sendSimple(chatID, "Введите 'да', 'нет' или '-' чтобы оставить без изменений.")
-				return nil
-			}
-			state.Data["active_new"] = strconv.FormatBool(val)
-		}
-		state.Step++
-		current := state.Data["orig_note"]
-		if current == "" {
-			current = "(пусто)"
-		} else {
-			current = escape(current)
-		}
-		b.sendSimple(chatID, fmt.Sprintf("Текущее примечание: %s\nВведите новое примечание, '-' чтобы оставить, 'удалить' чтобы очистить.", current))
-	case 4:
-		if text != "" {
-			if strings.EqualFold(text, "удалить") {
-				state.Data["note_action"] = "delete"
-			} else if text != "-" {
-				state.Data["note_new"] = text
-			}
-		}
-		if err := b.finishPlayerEditWizard(ctx, state); err != nil {
-			b.sendSimple(chatID, fmt.Sprintf("Не удалось обновить игрока: %v", err))
-			return nil
-		}
-		b.sendSimple(chatID, "Игрок обновлён.")
-		playerID := parseInt64(state.Data["id"])
-		page := int(parseInt64(state.Data["return_page"]))
-		if page < 1 {
-			page = 1
-		}
-		_ = b.showPlayer(ctx, chatID, playerID, page)
-		return b.svc.Sessions.Clear(ctx, adminID)
-	}
-	return b.saveSession(ctx, adminID, &state.Flow, state)
-}
-
-func (b *Bot) finishPlayerEditWizard(ctx context.Context, state *wizardState) error {
-	playerID := parseInt64(state.Data["id"])
-	patch := models.PlayerPatch{}
-	if v := state.Data["name_new"]; v != "" {
-		val := v
-		patch.FullName = &val
-	}
-	if action := state.Data["birth_action"]; action == "delete" {
-		patch.BirthDate = models.NewOptionalTime(nil)
-	} else if v := state.Data["birth_new"]; v != "" {
-		parsed, err := time.ParseInLocation("2006-01-02", v, b.loc)
-		if err != nil {
-			return err
-		}
-		patch.BirthDate = models.NewOptionalTime(&parsed)
-	}
-	if action := state.Data["position_action"]; action == "delete" {
-		patch.Position = models.NewOptionalString(nil)
-	} else if v := state.Data["position_new"]; v != "" {
-		val := v
-		patch.Position = models.NewOptionalString(&val)
-	}
-	if v := state.Data["active_new"]; v != "" {
-		if parsed, err := strconv.ParseBool(v); err == nil {
-			value := parsed
-			patch.Active = &value
-		} else {
-			return err
-		}
-	}
-	if action := state.Data["note_action"]; action == "delete" {
-		patch.Note = models.NewOptionalString(nil)
-	} else if v := state.Data["note_new"]; v != "" {
-		val := v
-		patch.Note = models.NewOptionalString(&val)
-	}
-	return b.svc.Players.Update(ctx, playerID, patch)
-}
-
-func (b *Bot) startRosterAddWizard(ctx context.Context, chatID, adminID int64, tournamentID, teamID, playerID int64) error {
-	state := &wizardState{
-		Flow: flowRosterAddPlayer,
-		Step: 0,
-		Data: map[string]string{
-			"tournament_id": strconv.FormatInt(tournamentID, 10),
-			"team_id":       strconv.FormatInt(teamID, 10),
-			"player_id":     strconv.FormatInt(playerID, 10),
-		},
-	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, "Введите номер игрока в турнире (или '-' для пропуска).")
-	return nil
-}
-
-func (b *Bot) advanceRosterAddWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	if state.Step != 0 {
-		return nil
-	}
-	var (
-		number *int
-		err    error
-	)
-	if text != "-" && text != "" {
-		number, err = parseOptionalInt(text)
-		if err != nil {
-			b.sendSimple(chatID, "Номер должен быть целым числом или '-' для пропуска.")
-			return nil
-		}
-	}
-	tournamentID := parseInt64(state.Data["tournament_id"])
-	teamID := parseInt64(state.Data["team_id"])
-	playerID := parseInt64(state.Data["player_id"])
-
-	if err := b.svc.Rosters.AddPlayer(ctx, tournamentID, teamID, playerID, number); err != nil {
-		b.sendSimple(chatID, fmt.Sprintf("Не удалось добавить игрока: %v", err))
-	} else {
-		b.sendSimple(chatID, "Игрок добавлен в заявку.")
-		_ = b.showRoster(ctx, chatID, tournamentID, teamID)
-	}
-	return b.svc.Sessions.Clear(ctx, adminID)
-}
-
-func (b *Bot) startRosterChangeWizard(ctx context.Context, chatID, adminID int64, tournamentID, teamID, playerID int64) error {
-	state := &wizardState{
-		Flow: flowRosterChangeNumber,
-		Step: 0,
-		Data: map[string]string{
-			"tournament_id": strconv.FormatInt(tournamentID, 10),
-			"team_id":       strconv.FormatInt(teamID, 10),
-			"player_id":     strconv.FormatInt(playerID, 10),
-		},
-	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, "Введите новый номер игрока (или '-' для удаления номера).")
-	return nil
-}
-
-func (b *Bot) advanceRosterChangeWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-	if state.Step != 0 {
-		return nil
-	}
-	var (
-		number *int
-		err    error
-	)
-	if text != "-" && text != "" {
-		number, err = parseOptionalInt(text)
-		if err != nil {
-			b.sendSimple(chatID, "Номер должен быть целым числом или '-' для удаления.")
-			return nil
-		}
-	}
-	tournamentID := parseInt64(state.Data["tournament_id"])
-	teamID := parseInt64(state.Data["team_id"])
-	playerID := parseInt64(state.Data["player_id"])
-
-	if err := b.svc.Rosters.UpdateNumber(ctx, tournamentID, teamID, playerID, number); err != nil {
-		b.sendSimple(chatID, fmt.Sprintf("Не удалось изменить номер: %v", err))
-	} else {
-		b.sendSimple(chatID, "Номер обновлён.")
-		_ = b.showRoster(ctx, chatID, tournamentID, teamID)
-	}
-	return b.svc.Sessions.Clear(ctx, adminID)
-}
-
-func (b *Bot) startMatchCreateWizard(ctx context.Context, chatID, adminID int64, tournamentID, teamID int64) error {
-	state := &wizardState{
-		Flow: flowMatchCreate,
-		Step: 0,
-		Data: map[string]string{
-			"tournament_id": strconv.FormatInt(tournamentID, 10),
-			"team_id":       strconv.FormatInt(teamID, 10),
-		},
-	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, "Создание матча: укажите соперника.")
-	return nil
-}
-
-func (b *Bot) advanceMatchCreateWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	switch state.Step {
-	case 0:
-		if text == "" {
-			b.sendSimple(chatID, "Соперник не может быть пустым.")
-			return nil
-		}
-		state.Data["opponent"] = text
-		state.Step++
-		b.sendSimple(chatID, "Введите дату матча (YYYY-MM-DD).")
-	case 1:
-		if _, err := time.Parse("2006-01-02", text); err != nil {
-			b.sendSimple(chatID, "Неверный формат. Используйте YYYY-MM-DD.")
-			return nil
-		}
-		state.Data["date"] = text
-		state.Step++
-		b.sendSimple(chatID, "Введите время матча (HH:MM).")
-	case 2:
-		if _, err := time.Parse("15:04", text); err != nil {
-			b.sendSimple(chatID, "Неверный формат времени. Используйте HH:MM (24 часа).")
-			return nil
-		}
-		state.Data["time"] = text
-		state.Step++
-		b.sendSimple(chatID, "Введите место проведения (или '-' для пропуска).")
-	case 3:
-		if text != "-" && text != "" {
-			state.Data["location"] = text
-		}
-		if err := b.finishMatchCreateWizard(ctx, state); err != nil {
-			b.sendSimple(chatID, fmt.Sprintf("Не удалось создать матч: %v", err))
-		} else {
-			b.sendSimple(chatID, "Матч создан.")
-			tournamentID := parseInt64(state.Data["tournament_id"])
-			teamID := parseInt64(state.Data["team_id"])
-			_ = b.sendGamesMatches(ctx, chatID, tournamentID, teamID)
-		}
-		return b.svc.Sessions.Clear(ctx, adminID)
-	}
-	return b.saveSession(ctx, adminID, &state.Flow, state)
-}
-
-func (b *Bot) finishMatchCreateWizard(ctx context.Context, state *wizardState) error {
-	tournamentID := parseInt64(state.Data["tournament_id"])
-	teamID := parseInt64(state.Data["team_id"])
-	date := state.Data["date"]
-	timePart := state.Data["time"]
-	start, err := time.ParseInLocation("2006-01-02 15:04", fmt.Sprintf("%s %s", date, timePart), b.loc)
-	if err != nil {
-		return err
-	}
-	var location *string
-	if val := state.Data["location"]; val != "" {
-		location = &val
-	}
-	_, err = b.svc.Matches.Create(ctx, service.CreateMatchInput{
-		TournamentID: tournamentID,
-		TeamID:       teamID,
-		Opponent:     state.Data["opponent"],
-		StartTime:    start,
-		Location:     location,
-		Status:       models.MatchStatusScheduled,
-	})
-	return err
-}
-
-func (b *Bot) advanceLineupNumberWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	if state.Step != 0 {
-		return nil
-	}
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	matchID := parseInt64(state.Data["match_id"])
-	playerID := parseInt64(state.Data["player_id"])
-
-	var patch models.LineupPatch
-	switch text {
-	case "-":
-		patch.NumberOverride = models.NewOptionalInt(nil)
-	default:
-		if text == "" {
-			b.sendSimple(chatID, "Введите номер или '-' для удаления.")
-			return nil
-		}
-		num, err := strconv.Atoi(text)
-		if err != nil {
-			b.sendSimple(chatID, "Номер должен быть целым числом.")
-			return nil
-		}
-		patch.NumberOverride = models.NewOptionalInt(&num)
-	}
-
-	if err := b.svc.Lineup.Update(ctx, matchID, playerID, patch); err != nil {
-		b.sendSimple(chatID, fmt.Sprintf("Не удалось обновить номер: %v", err))
-		return nil
-	}
-	b.sendSimple(chatID, "Номер в составе обновлён.")
-	_ = b.sendLineupMenu(ctx, chatID, matchID)
-	return b.svc.Sessions.Clear(ctx, adminID)
-}
-
-func (b *Bot) startEventGoalWizard(ctx context.Context, chatID, adminID int64, matchID, playerID int64) error {
-	state := &wizardState{
-		Flow: flowEventGoal,
-		Step: 0,
-		Data: map[string]string{
-			"match_id":  strconv.FormatInt(matchID, 10),
-			"player_id": strconv.FormatInt(playerID, 10),
-		},
-	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, "Введите время гола (например, 45+2).")
-	return nil
-}
-
-func (b *Bot) advanceEventGoalWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	if state.Step != 0 {
-		return nil
-	}
-	text := strings.TrimSpace(msg.Text)
-	if text == "" {
-		b.sendSimple(msg.Chat.ID, "Время события не может быть пустым.")
-		return nil
-	}
-	matchID := parseInt64(state.Data["match_id"])
-	playerID := parseInt64(state.Data["player_id"])
-	if err := b.svc.Events.AddGoal(ctx, matchID, playerID, text); err != nil {
-		b.sendSimple(msg.Chat.ID, fmt.Sprintf("Не удалось добавить гол: %v", err))
-		return nil
-	}
-	b.sendSimple(msg.Chat.ID, "Гол добавлен.")
-	_ = b.showMatch(ctx, msg.Chat.ID, matchID)
-	return b.svc.Sessions.Clear(ctx, msg.From.ID)
-}
-
-func (b *Bot) startEventCardWizard(ctx context.Context, chatID, adminID int64, matchID, playerID int64, cardType string) error {
-	cardType = strings.ToLower(cardType)
-	if cardType != "yellow" && cardType != "red" {
-		b.sendSimple(chatID, "Неизвестный тип карточки.")
-		return nil
-	}
-	state := &wizardState{
-		Flow: flowEventCard,
-		Step: 0,
-		Data: map[string]string{
-			"match_id":  strconv.FormatInt(matchID, 10),
-			"player_id": strconv.FormatInt(playerID, 10),
-			"card_type": cardType,
-		},
-	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, "Введите время карточки (например, 12 или 90+3).")
-	return nil
-}
-
-func (b *Bot) advanceEventCardWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	if state.Step != 0 {
-		return nil
-	}
-	text := strings.TrimSpace(msg.Text)
-	if text == "" {
-		b.sendSimple(msg.Chat.ID, "Время события не может быть пустым.")
-		return nil
-	}
-	matchID := parseInt64(state.Data["match_id"])
-	playerID := parseInt64(state.Data["player_id"])
-	cardType := models.CardType(state.Data["card_type"])
-	if cardType != models.CardTypeYellow && cardType != models.CardTypeRed {
-		b.sendSimple(msg.Chat.ID, "Неизвестный тип карточки.")
-		return nil
-	}
-	if err := b.svc.Events.AddCard(ctx, matchID, playerID, cardType, text); err != nil {
-		b.sendSimple(msg.Chat.ID, fmt.Sprintf("Не удалось добавить карточку: %v", err))
-		return nil
-	}
-	b.sendSimple(msg.Chat.ID, "Карточка добавлена.")
-	_ = b.showMatch(ctx, msg.Chat.ID, matchID)
-	return b.svc.Sessions.Clear(ctx, msg.From.ID)
-}
-
-func (b *Bot) startEventSubWizard(ctx context.Context, chatID, adminID int64, matchID, outPlayerID, inPlayerID int64) error {
-	if outPlayerID == inPlayerID {
-		b.sendSimple(chatID, "Игроки замены должны отличаться.")
-		return nil
-	}
-	state := &wizardState{
-		Flow: flowEventSub,
-		Step: 0,
-		Data: map[string]string{
-			"match_id": strconv.FormatInt(matchID, 10),
-			"out_id":   strconv.FormatInt(outPlayerID, 10),
-			"in_id":    strconv.FormatInt(inPlayerID, 10),
-		},
-	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, "Введите время замены (например, 60).")
-	return nil
-}
-
-func (b *Bot) advanceEventSubWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	if state.Step != 0 {
-		return nil
-	}
-	text := strings.TrimSpace(msg.Text)
-	if text == "" {
-		b.sendSimple(msg.Chat.ID, "Время события не может быть пустым.")
-		return nil
-	}
-	matchID := parseInt64(state.Data["match_id"])
-	outID := parseInt64(state.Data["out_id"])
-	inID := parseInt64(state.Data["in_id"])
-	if err := b.svc.Events.AddSub(ctx, matchID, outID, inID, text); err != nil {
-		b.sendSimple(msg.Chat.ID, fmt.Sprintf("Не удалось добавить замену: %v", err))
-		return nil
-	}
-	b.sendSimple(msg.Chat.ID, "Замена добавлена.")
-	_ = b.showMatch(ctx, msg.Chat.ID, matchID)
-	return b.svc.Sessions.Clear(ctx, msg.From.ID)
-}
-
-func (b *Bot) startMatchEditWizard(ctx context.Context, chatID, adminID int64, matchID int64) error {
-	state := &wizardState{
-		Flow: flowMatchEdit,
-		Step: 0,
-		Data: map[string]string{
-			"match_id": strconv.FormatInt(matchID, 10),
-		},
-	}
-	if err := b.saveSession(ctx, adminID, &state.Flow, state); err != nil {
-		return err
-	}
-	b.sendSimple(chatID, "Введите статус (scheduled/played/canceled) или '-' чтобы оставить без изменений.")
-	return nil
-}
-
-func (b *Bot) advanceMatchEditWizard(ctx context.Context, msg *tgbotapi.Message, state *wizardState) error {
-	text := strings.TrimSpace(msg.Text)
-	adminID := msg.From.ID
-	chatID := msg.Chat.ID
-
-	switch state.Step {
-	case 0:
-		if text != "" && text != "-" {
-			normalized := strings.ToLower(text)
-			if normalized != string(models.MatchStatusScheduled) &&
-				normalized != string(models.MatchStatusPlayed) &&
-				normalized != string(models.MatchStatusCanceled) {
-				b.sendSimple(chatID, "Допустимые статусы: scheduled, played, canceled или '-'.")
-				return nil
-			}
-			state.Data["status"] = normalized
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите дату матча (YYYY-MM-DD) или '-' чтобы оставить без изменений.")
-	case 1:
-		if text != "" && text != "-" {
-			if _, err := time.Parse("2006-01-02", text); err != nil {
-				b.sendSimple(chatID, "Неверный формат даты. Используйте YYYY-MM-DD или '-'.")
-				return nil
-			}
-			state.Data["date"] = text
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите время матча (HH:MM) или '-' чтобы оставить без изменений.")
-	case 2:
-		if text != "" && text != "-" {
-			if _, err := time.Parse("15:04", text); err != nil {
-				b.sendSimple(chatID, "Неверный формат времени. Используйте HH:MM или '-'.")
-				return nil
-			}
-			state.Data["time"] = text
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите место проведения или '-' чтобы очистить (оставьте пустым для без изменений).")
-	case 3:
-		if text != "" {
-			state.Data["location"] = text
-		}
-		state.Step++
-		b.sendSimple(chatID, "Введите счёты через пробел: HT FT ET PEN FINAL_US FINAL_THEM. Используйте '-' для каждого значения или '-' целиком чтобы пропустить.")
-	case 4:
-		if text != "" {
-			state.Data["scores"] = text
-		}
-		if err := b.finishMatchEditWizard(ctx, state); err != nil {
-			b.sendSimple(chatID, fmt.Sprintf("Не удалось обновить матч: %v. Повторите ввод счётов.", err))
-			return nil
-		}
-		b.sendSimple(chatID, "Матч обновлён.")
-		matchID := parseInt64(state.Data["match_id"])
-		_ = b.showMatch(ctx, chatID, matchID)
-		return b.svc.Sessions.Clear(ctx, adminID)
-	}
-	return b.saveSession(ctx, adminID, &state.Flow, state)
-}
-
-func (b *Bot) finishMatchEditWizard(ctx context.Context, state *wizardState) error {
-	matchID := parseInt64(state.Data["match_id"])
-	match, err := b.svc.Matches.Get(ctx, matchID)
-	if err != nil {
-		return err
-	}
-	patch := models.MatchPatch{}
-
-	if status := state.Data["status"]; status != "" && status != "-" {
-		ms := models.MatchStatus(status)
-		if ms != models.MatchStatusScheduled && ms != models.MatchStatusPlayed && ms != models.MatchStatusCanceled {
-			return fmt.Errorf("неверный статус: %s", status)
-		}
-		patch.Status = &ms
-	}
-
-	dateVal := state.Data["date"]
-	timeVal := state.Data["time"]
-	if dateVal == "" {
-		dateVal = "-"
-	}
-	if timeVal == "" {
-		timeVal = "-"
-	}
-	if dateVal != "-" || timeVal != "-" {
-		start := match.StartTime.In(b.loc)
-		year, month, day := start.Date()
-		hour, minute, _ := start.Clock()
-
-		if dateVal != "-" {
-			parsed, err := time.ParseInLocation("2006-01-02", dateVal, b.loc)
-			if err != nil {
-				return fmt.Errorf("неверный формат даты: %w", err)
-			}
-			year, month, day = parsed.Date()
-		}
-		if timeVal != "-" {
-			parsed, err := time.Parse("15:04", timeVal)
-			if err != nil {
-				return fmt.Errorf("неверный формат времени: %w", err)
-			}
-			hour, minute, _ = parsed.Clock()
-		}
-		newStart := time.Date(year, month, day, hour, minute, 0, 0, b.loc).UTC()
-		patch.StartTime = models.NewOptionalTime(&newStart)
-	}
-
-	if loc := state.Data["location"]; loc != "" {
-		if loc == "-" {
-			patch.Location = models.NewOptionalString(nil)
-		} else {
-			value := loc
-			patch.Location = models.NewOptionalString(&value)
-		}
-	}
-
-	if patch.Status != nil && *patch.Status == models.MatchStatusCanceled {
-		patch.ScoreHT = models.NewOptionalString(nil)
-		patch.ScoreFT = models.NewOptionalString(nil)
-		patch.ScoreET = models.NewOptionalString(nil)
-		patch.ScorePEN = models.NewOptionalString(nil)
-		patch.ScoreFinalUs = models.NewOptionalInt(nil)
-		patch.ScoreFinalThem = models.NewOptionalInt(nil)
-	} else if scores := state.Data["scores"]; scores != "" && scores != "-" {
-		tokens := strings.Fields(scores)
-		if len(tokens) != 6 {
-			return fmt.Errorf("ожидалось 6 значений для счётов, получено %d", len(tokens))
-		}
-		if patch.ScoreHT, err = optionalScoreString(tokens[0]); err != nil {
-			return err
-		}
-		if patch.ScoreFT, err = optionalScoreString(tokens[1]); err != nil {
-			return err
-		}
-		if patch.ScoreET, err = optionalScoreString(tokens[2]); err != nil {
-			return err
-		}
-		if patch.ScorePEN, err = optionalScoreString(tokens[3]); err != nil {
-			return err
-		}
-		if patch.ScoreFinalUs, err = optionalScoreInt(tokens[4]); err != nil {
-			return err
-		}
-		if patch.ScoreFinalThem, err = optionalScoreInt(tokens[5]); err != nil {
-			return err
-		}
-	}
-
-	return b.svc.Matches.Update(ctx, matchID, patch)
-}
-
-func (b *Bot) setMatchStatus(ctx context.Context, chatID int64, matchID int64, statusText string) error {
-	status := models.MatchStatus(strings.ToLower(statusText))
-	switch status {
-	case models.MatchStatusScheduled, models.MatchStatusPlayed, models.MatchStatusCanceled:
-	default:
-		b.sendSimple(chatID, "Неизвестный статус.")
-		return nil
-	}
-	if err := b.svc.Matches.Update(ctx, matchID, models.MatchPatch{Status: &status}); err != nil {
-		b.sendSimple(chatID, fmt.Sprintf("Не удалось обновить статус: %v", err))
-		return nil
-	}
-	b.sendSimple(chatID, fmt.Sprintf("Статус матча: %s", statusLabel(status)))
-	return b.showMatch(ctx, chatID, matchID)
-}
-
-func (b *Bot) resetMatchScores(ctx context.Context, chatID int64, matchID int64) error {
-	patch := models.MatchPatch{
-		ScoreHT:        models.NewOptionalString(nil),
-		ScoreFT:        models.NewOptionalString(nil),
-		ScoreET:        models.NewOptionalString(nil),
-		ScorePEN:       models.NewOptionalString(nil),
-		ScoreFinalUs:   models.NewOptionalInt(nil),
-		ScoreFinalThem: models.NewOptionalInt(nil),
-	}
-	if err := b.svc.Matches.Update(ctx, matchID, patch); err != nil {
-		b.sendSimple(chatID, fmt.Sprintf("Не удалось сбросить счёт: %v", err))
-		return nil
-	}
-	b.sendSimple(chatID, "Счёт матча сброшен.")
-	return b.showMatch(ctx, chatID, matchID)
-}
-
-type matchSummary struct {
-	Match          models.Match
-	TeamName       string
-	TournamentName string
-}
-
-func (b *Bot) collectUpcomingMatches(ctx context.Context, tournamentID int64, teams []models.TournamentTeam) []matchSummary {
-	cutoff := b.timeNow().Add(-1 * time.Hour)
-	teamNames := make(map[int64]string, len(teams))
-	for _, tm := range teams {
-		teamNames[tm.TeamID] = tm.TeamName
-	}
-	var summaries []matchSummary
-	for _, tm := range teams {
-		matches, err := b.svc.Matches.List(ctx, tournamentID, tm.TeamID)
-		if err != nil {
-			continue
-		}
-		for _, m := range matches {
-			if m.Status == models.MatchStatusScheduled && m.StartTime.After(cutoff) {
-				summaries = append(summaries, matchSummary{
-					Match:    m,
-					TeamName: teamNames[tm.TeamID],
-				})
-			}
-		}
-	}
-	if len(summaries) == 0 {
-		return nil
-	}
-	sort.Slice(summaries, func(i, j int) bool {
-		return summaries[i].Match.StartTime.Before(summaries[j].Match.StartTime)
-	})
-	if len(summaries) > 3 {
-		summaries = summaries[:3]
-	}
-	return summaries
-}
-
-func (b *Bot) collectTeamUpcomingMatches(ctx context.Context, tournaments []models.Tournament, teamID int64) []matchSummary {
-	cutoff := b.timeNow().Add(-1 * time.Hour)
-	var summaries []matchSummary
-	for _, t := range tournaments {
-		matches, err := b.svc.Matches.List(ctx, t.ID, teamID)
-		if err != nil {
-			continue
-		}
-		for _, m := range matches {
-			if m.Status == models.MatchStatusScheduled && m.StartTime.After(cutoff) {
-				summaries = append(summaries, matchSummary{
-					Match:          m,
-					TournamentName: t.Name,
-				})
-			}
-		}
-	}
-	if len(summaries) == 0 {
-		return nil
-	}
-	sort.Slice(summaries, func(i, j int) bool {
-		return summaries[i].Match.StartTime.Before(summaries[j].Match.StartTime)
-	})
-	if len(summaries) > 3 {
-		summaries = summaries[:3]
-	}
-	return summaries
-}
-
-// ----------------------------------------------------------------------------
-
-type callbackPayload struct {
-	Action string
-	Params map[string]string
-}
-
-func parseCallback(data string) (*callbackPayload, error) {
-	parts := strings.Split(data, "|")
-	if len(parts) == 0 {
-		return nil, errors.New("empty callback")
-	}
-	payload := &callbackPayload{
-		Action: parts[0],
-		Params: map[string]string{},
-	}
-	for _, part := range parts[1:] {
-		if part == "" {
-			continue
-		}
-		kv := strings.SplitN(part, "=", 2)
-		if len(kv) != 2 {
-			continue
-		}
-		payload.Params[kv[0]] = kv[1]
-	}
-	return payload, nil
-}
-
-func escape(s string) string {
-	replacer := strings.NewReplacer(
-		"_", "\\_",
-		"*", "\\*",
-		"`", "\\`",
-		"[", "\\[",
-	)
-	return replacer.Replace(s)
-}
-
-func parseInt64(value string) int64 {
-	if value == "" {
-		return 0
-	}
-	id, err := strconv.ParseInt(value, 10, 64)
-	if err != nil {
-		return 0
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
 	}
 	return id
 }
 
-func parseOptionalInt(value string) (*int, error) {
-	if value == "" {
-		return nil, nil
-	}
-	number, err := strconv.Atoi(value)
-	if err != nil {
-		return nil, err
-	}
-	return &number, nil
-}
-
 func parseYesNo(text string) (bool, bool) {
 	switch strings.ToLower(strings.TrimSpace(text)) {
 	case "да", "yes", "y", "true", "1":
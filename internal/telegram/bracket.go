@@ -0,0 +1,210 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+	"github.com/dynamost/telegram-bot/internal/service"
+)
+
+var matchStageChoices = []wizardChoice{
+	{Label: stageLabel(models.MatchStageGroup), Value: string(models.MatchStageGroup)},
+	{Label: stageLabel(models.MatchStageRoundOf16), Value: string(models.MatchStageRoundOf16)},
+	{Label: stageLabel(models.MatchStageQuarterFinal), Value: string(models.MatchStageQuarterFinal)},
+	{Label: stageLabel(models.MatchStageSemiFinal), Value: string(models.MatchStageSemiFinal)},
+	{Label: stageLabel(models.MatchStageFinal), Value: string(models.MatchStageFinal)},
+}
+
+func stageLabel(stage models.MatchStage) string {
+	switch stage {
+	case models.MatchStageGroup:
+		return "Группа"
+	case models.MatchStageRoundOf16:
+		return "1/8 финала"
+	case models.MatchStageQuarterFinal:
+		return "1/4 финала"
+	case models.MatchStageSemiFinal:
+		return "1/2 финала"
+	case models.MatchStageFinal:
+		return "Финал"
+	default:
+		return string(stage)
+	}
+}
+
+// writeBracket renders the bracket stage by stage. Undecided slots are shown
+// as "?", byes as "—".
+func (b *Bot) writeBracket(builder *strings.Builder, bracket *models.Bracket) {
+	for n, stage := range bracket.Stages {
+		builder.WriteString(fmt.Sprintf("_%s_\n", stageLabel(stage.Stage)))
+		for _, tie := range stage.Ties {
+			away := tie.Away
+			switch {
+			case away == "" && n == 0:
+				away = "—"
+			case away == "":
+				away = "?"
+			}
+			home := tie.Home
+			if home == "" {
+				home = "?"
+			}
+			line := fmt.Sprintf("%d. %s — %s", tie.Position+1, escape(home), escape(away))
+			if tie.Match != nil {
+				line += " 🏟 " + tie.Match.StartTime.In(b.loc).Format("02.01")
+			}
+			if tie.Winner != "" {
+				line += " → *" + escape(tie.Winner) + "*"
+			}
+			builder.WriteString(line + "\n")
+		}
+	}
+}
+
+func (b *Bot) sendBracket(ctx context.Context, chatID int64, tournamentID int64) error {
+	bracket, err := b.svc.Brackets.Get(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	var builder strings.Builder
+	builder.WriteString("*Сетка плей-офф*\n")
+	if len(bracket.Stages) == 0 {
+		builder.WriteString("Сетка ещё не составлена.\n")
+	} else {
+		b.writeBracket(&builder, bracket)
+		builder.WriteString("\nПобедители матчей клуба со стадией плей-офф проходят дальше сами. Результаты остальных пар отметьте кнопками.\n")
+	}
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	for _, stage := range bracket.Stages {
+		for _, tie := range stage.Ties {
+			if tie.Home == "" || tie.Away == "" || (tie.Winner != "" && !tie.Manual) {
+				continue
+			}
+			keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+				tgbotapi.NewInlineKeyboardButtonData(
+					fmt.Sprintf("✏ %s: %s — %s", stageLabel(stage.Stage), truncateLabel(tie.Home, 15), truncateLabel(tie.Away, 15)),
+					fmt.Sprintf("bracket_tie|t=%d|s=%s|p=%d", tournamentID, stage.Stage, tie.Position)),
+			})
+		}
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("🆕 Составить сетку", fmt.Sprintf("bracket_start_create|t=%d", tournamentID)),
+	})
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "nav_back"),
+	})
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
+}
+
+// sendBracketTie asks who won a tie without a club match.
+func (b *Bot) sendBracketTie(ctx context.Context, chatID int64, tournamentID int64, stage models.MatchStage, position int) error {
+	bracket, err := b.svc.Brackets.Get(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	tie := bracket.Tie(stage, position)
+	if tie == nil {
+		return models.ErrNotFound
+	}
+	text := fmt.Sprintf("*%s, пара %d*\n%s — %s\nКто прошёл дальше?", stageLabel(stage), position+1, escape(tie.Home), escape(tie.Away))
+	param := fmt.Sprintf("t=%d|s=%s|p=%d", tournamentID, stage, position)
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{
+			tgbotapi.NewInlineKeyboardButtonData(truncateLabel(tie.Home, 25), "bracket_win|"+param+"|w=home"),
+			tgbotapi.NewInlineKeyboardButtonData(truncateLabel(tie.Away, 25), "bracket_win|"+param+"|w=away"),
+		},
+	}
+	if tie.Manual {
+		keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("🗑 Сбросить результат", "bracket_win|"+param+"|w="),
+		})
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", fmt.Sprintf("bracket_open|id=%d", tournamentID)),
+	})
+	return b.render(ctx, chatID, text, tgbotapi.NewInlineKeyboardMarkup(keyboard...))
+}
+
+func (b *Bot) setBracketWinner(ctx context.Context, chatID int64, tournamentID int64, stage models.MatchStage, position int, side string) error {
+	winner := ""
+	if side != "" {
+		bracket, err := b.svc.Brackets.Get(ctx, tournamentID)
+		if err != nil {
+			return err
+		}
+		tie := bracket.Tie(stage, position)
+		if tie == nil {
+			return models.ErrNotFound
+		}
+		winner = tie.Home
+		if side == "away" {
+			winner = tie.Away
+		}
+	}
+	if err := b.svc.Brackets.SetWinner(ctx, tournamentID, stage, position, winner); err != nil {
+		b.sendSimple(chatID, fmt.Sprintf("Не удалось сохранить результат: %v", err))
+	}
+	return b.sendBracket(ctx, chatID, tournamentID)
+}
+
+// parseEntrantsAnswer takes the bracket entrants in draw order, one per line.
+func parseEntrantsAnswer(text string) (string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		name := oneLine(line)
+		if name == "" {
+			continue
+		}
+		if seen[strings.ToLower(name)] {
+			return "", userMessagef("Команда «%s» указана дважды.", name)
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	if len(names) < 2 || len(names) > 16 {
+		return "", userMessage("Нужно от 2 до 16 команд, по одной в строке.")
+	}
+	return strings.Join(names, "\n"), nil
+}
+
+func (b *Bot) bracketSummary(_ context.Context, st *wizardState) (string, error) {
+	ties, err := service.DrawBracket(st.id("tournament_id"), strings.Split(st.Data["entrants"], "\n"))
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Старт с этапа «%s»\n", stageLabel(ties[0].Stage)))
+	for _, tie := range ties {
+		if tie.AwayName == nil {
+			builder.WriteString(fmt.Sprintf("%d. %s — без соперника\n", tie.Position+1, escape(*tie.HomeName)))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%d. %s — %s\n", tie.Position+1, escape(*tie.HomeName), escape(*tie.AwayName)))
+	}
+	if st.Data["replace"] != "" {
+		builder.WriteString("\nТекущая сетка и отмеченные результаты будут заменены.\n")
+	}
+	return builder.String(), nil
+}
+
+func (b *Bot) finishBracketWizard(ctx context.Context, st *wizardState) error {
+	return b.svc.Brackets.Create(ctx, st.id("tournament_id"), strings.Split(st.Data["entrants"], "\n"))
+}
+
+func (b *Bot) startBracketWizard(ctx context.Context, key models.SessionKey, tournamentID int64) error {
+	data := map[string]string{"tournament_id": strconv.FormatInt(tournamentID, 10)}
+	bracket, err := b.svc.Brackets.Get(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	if len(bracket.Stages) > 0 {
+		data["replace"] = "true"
+	}
+	return b.startWizard(ctx, key, flowBracket, data)
+}
@@ -0,0 +1,40 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+	"github.com/dynamost/telegram-bot/internal/service"
+)
+
+// sendCalendar sends the matches of a scope as an .ics file. With calendar
+// feeds enabled the caption carries the link to subscribe to instead, so that
+// later changes reach the phone by themselves.
+func (b *Bot) sendCalendar(ctx context.Context, chatID int64, scope models.CalendarScope, id int64) error {
+	data, name, err := b.svc.Calendars.Build(ctx, scope, id)
+	if err != nil {
+		return err
+	}
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	doc.Caption = "Откройте файл, чтобы добавить матчи в календарь."
+	if link := b.feedLink(scope, id); link != "" {
+		doc.Caption = fmt.Sprintf("Файл — разовый снимок расписания. Чтобы календарь обновлялся сам, подпишитесь по ссылке:\n%s", link)
+	}
+	if _, err := b.out.Send(doc); err != nil {
+		return err
+	}
+	b.detachScreen(chatID)
+	return nil
+}
+
+func (b *Bot) feedLink(scope models.CalendarScope, id int64) string {
+	if b.feedURL == "" {
+		return ""
+	}
+	query := url.Values{"token": {b.svc.Calendars.FeedToken(scope, id)}}
+	return b.feedURL + service.CalendarFeedPath(scope, id) + "?" + query.Encode()
+}
@@ -0,0 +1,56 @@
+package telegram
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+// tokenizeKeyboard registers the actions of the keyboard's buttons and
+// replaces their callback_data with the signed tokens. Buttons are still
+// built with the readable "action|key=value" form, which is no longer bound
+// by Telegram's 64-byte limit.
+func (b *Bot) tokenizeKeyboard(ctx context.Context, markup *tgbotapi.InlineKeyboardMarkup) error {
+	var (
+		actions []models.NavigationEntry
+		targets []*tgbotapi.InlineKeyboardButton
+	)
+	for i := range markup.InlineKeyboard {
+		for j := range markup.InlineKeyboard[i] {
+			button := &markup.InlineKeyboard[i][j]
+			if button.CallbackData == nil {
+				continue
+			}
+			payload, err := parseCallback(*button.CallbackData)
+			if err != nil {
+				return err
+			}
+			actions = append(actions, models.NavigationEntry{Action: payload.Action, Params: payload.Params})
+			targets = append(targets, button)
+		}
+	}
+	tokens, err := b.svc.Callbacks.Register(ctx, actions)
+	if err != nil {
+		return err
+	}
+	for i, button := range targets {
+		token := tokens[i]
+		button.CallbackData = &token
+	}
+	return nil
+}
+
+// resolveCallback turns a button token back into the action it stands for.
+func (b *Bot) resolveCallback(ctx context.Context, data string) (*callbackPayload, error) {
+	entry, err := b.svc.Callbacks.Resolve(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	params := entry.Params
+	if params == nil {
+		params = map[string]string{}
+	}
+	return &callbackPayload{Action: entry.Action, Params: params}, nil
+}
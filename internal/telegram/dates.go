@@ -0,0 +1,194 @@
+package telegram
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// humanDate is a date typed in free form, with the time of day when one was
+// given.
+type humanDate struct {
+	Date  time.Time
+	Clock string
+}
+
+var (
+	clockPattern   = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	dottedPattern  = regexp.MustCompile(`^(\d{1,2})[./](\d{1,2})(?:[./](\d{2}|\d{4}))?$`)
+	isoDatePattern = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+)
+
+var relativeDays = map[string]int{
+	"позавчера":   -2,
+	"вчера":       -1,
+	"сегодня":     0,
+	"завтра":      1,
+	"послезавтра": 2,
+}
+
+var weekdayWords = map[string]time.Weekday{
+	"пн": time.Monday, "понедельник": time.Monday,
+	"вт": time.Tuesday, "вторник": time.Tuesday,
+	"ср": time.Wednesday, "среда": time.Wednesday, "среду": time.Wednesday,
+	"чт": time.Thursday, "четверг": time.Thursday,
+	"пт": time.Friday, "пятница": time.Friday, "пятницу": time.Friday,
+	"сб": time.Saturday, "суббота": time.Saturday, "субботу": time.Saturday,
+	"вс": time.Sunday, "воскресенье": time.Sunday,
+}
+
+// monthStems match both "ноябрь" and "ноября".
+var monthStems = []string{"янв", "фев", "мар", "апр", "ма", "июн", "июл", "авг", "сен", "окт", "ноя", "дек"}
+
+var genitiveMonths = [...]string{
+	"января", "февраля", "марта", "апреля", "мая", "июня",
+	"июля", "августа", "сентября", "октября", "ноября", "декабря",
+}
+
+var weekdayTitles = [...]string{"воскресенье", "понедельник", "вторник", "среда", "четверг", "пятница", "суббота"}
+
+// parseHumanDate understands "сегодня", "завтра 18:30", "сб 10:00",
+// "15.11 19:00", "15.11.2026", "15 ноября" and YYYY-MM-DD, relative to now.
+// A date without a year, or a weekday, is taken in the future when ahead is
+// set, so that "15.01" typed in December means next January.
+func parseHumanDate(text string, now time.Time, ahead bool) (humanDate, error) {
+	var result humanDate
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	var words []string
+	for _, word := range strings.Fields(strings.ToLower(strings.ReplaceAll(text, ",", " "))) {
+		if match := clockPattern.FindStringSubmatch(word); match != nil {
+			hour, _ := strconv.Atoi(match[1])
+			minute, _ := strconv.Atoi(match[2])
+			if hour > 23 || minute > 59 {
+				return result, userMessagef("Неверное время %q.", word)
+			}
+			result.Clock = fmt.Sprintf("%02d:%02d", hour, minute)
+			continue
+		}
+		if word == "в" {
+			continue
+		}
+		words = append(words, word)
+	}
+	if len(words) == 0 {
+		return result, userMessage("Не удалось распознать дату. Например: 15.11, 15.11.2026, завтра, сб.")
+	}
+
+	date, ok := time.Time{}, false
+	switch first := words[0]; {
+	case len(words) == 1 && isoDatePattern.MatchString(first):
+		parsed, err := time.ParseInLocation("2006-01-02", first, now.Location())
+		if err != nil {
+			return result, userMessage("Такой даты нет.")
+		}
+		date, ok = parsed, true
+	case len(words) == 1 && dottedPattern.MatchString(first):
+		match := dottedPattern.FindStringSubmatch(first)
+		day, _ := strconv.Atoi(match[1])
+		month, _ := strconv.Atoi(match[2])
+		var err error
+		date, err = resolveDate(today, day, month, match[3], ahead)
+		if err != nil {
+			return result, err
+		}
+		ok = true
+	case len(words) == 1:
+		if offset, found := relativeDays[first]; found {
+			date, ok = today.AddDate(0, 0, offset), true
+		} else if weekday, found := weekdayWords[first]; found {
+			days := (int(weekday) - int(today.Weekday()) + 7) % 7
+			if !ahead && days > 0 {
+				days -= 7
+			}
+			date, ok = today.AddDate(0, 0, days), true
+		}
+	case len(words) <= 3:
+		day, err := strconv.Atoi(first)
+		month := monthByName(words[1])
+		if err != nil || month == 0 {
+			break
+		}
+		year := ""
+		if len(words) == 3 {
+			year = strings.TrimSuffix(words[2], "г")
+		}
+		date, err = resolveDate(today, day, month, year, ahead)
+		if err != nil {
+			return result, err
+		}
+		ok = true
+	}
+	if !ok {
+		return result, userMessage("Не удалось распознать дату. Например: 15.11, 15.11.2026, завтра, сб.")
+	}
+	result.Date = date
+	return result, nil
+}
+
+// resolveDate builds a date from its parts; year may be empty, two or four
+// digits.
+func resolveDate(today time.Time, day, month int, year string, ahead bool) (time.Time, error) {
+	y := today.Year()
+	if year != "" {
+		parsed, err := strconv.Atoi(year)
+		if err != nil {
+			return time.Time{}, userMessage("Неверный год.")
+		}
+		if parsed < 100 {
+			parsed += 2000
+		}
+		y = parsed
+	}
+	if month < 1 || month > 12 {
+		return time.Time{}, userMessage("Такой даты нет.")
+	}
+	date := time.Date(y, time.Month(month), day, 0, 0, 0, 0, today.Location())
+	if date.Day() != day {
+		return time.Time{}, userMessage("Такой даты нет.")
+	}
+	if year == "" && ahead && date.Before(today) {
+		date = date.AddDate(1, 0, 0)
+	}
+	return date, nil
+}
+
+func monthByName(word string) int {
+	for i, stem := range monthStems {
+		// "ма" would also match "март".
+		if strings.HasPrefix(word, stem) && (stem != "ма" || strings.HasPrefix(word, "мая") || strings.HasPrefix(word, "май")) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// formatHumanDate echoes an interpreted date back, e.g.
+// "суббота, 15 ноября 2026, 19:00".
+func formatHumanDate(date time.Time, clock string) string {
+	text := fmt.Sprintf("%s, %d %s %d", weekdayTitles[date.Weekday()], date.Day(), genitiveMonths[date.Month()-1], date.Year())
+	if clock != "" {
+		text += ", " + clock
+	}
+	return text
+}
+
+// parseDateAnswer parses the answer of a date step that looks ahead: match
+// and tournament dates.
+func (b *Bot) parseDateAnswer(text string) (string, error) {
+	parsed, err := parseHumanDate(text, b.timeNow().In(b.loc), true)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Date.Format("2006-01-02"), nil
+}
+
+// parseBirthDateAnswer parses a date in the past, such as a birth date.
+func (b *Bot) parseBirthDateAnswer(text string) (string, error) {
+	parsed, err := parseHumanDate(text, b.timeNow().In(b.loc), false)
+	if err != nil {
+		return "", err
+	}
+	return parsed.Date.Format("2006-01-02"), nil
+}
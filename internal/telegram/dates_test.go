@@ -0,0 +1,73 @@
+package telegram
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseHumanDate(t *testing.T) {
+	// Wednesday.
+	now := time.Date(2026, time.December, 16, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		text  string
+		ahead bool
+		date  string
+		clock string
+	}{
+		{text: "сегодня", ahead: true, date: "2026-12-16"},
+		{text: "завтра 18:30", ahead: true, date: "2026-12-17", clock: "18:30"},
+		{text: "в 9:05, послезавтра", ahead: true, date: "2026-12-18", clock: "09:05"},
+		{text: "позавчера", date: "2026-12-14"},
+		{text: "Сб 10:00", ahead: true, date: "2026-12-19", clock: "10:00"},
+		{text: "суббота", date: "2026-12-12"},
+		{text: "ср", ahead: true, date: "2026-12-16"},
+		{text: "ср", date: "2026-12-16"},
+		{text: "пн", ahead: true, date: "2026-12-21"},
+		{text: "15.01", ahead: true, date: "2027-01-15"},
+		{text: "15.01", date: "2026-01-15"},
+		{text: "16.12", ahead: true, date: "2026-12-16"},
+		{text: "15.11.2026 19:00", ahead: true, date: "2026-11-15", clock: "19:00"},
+		{text: "15/11/27", date: "2027-11-15"},
+		{text: "15 ноября", ahead: true, date: "2027-11-15"},
+		{text: "20 декабря", ahead: true, date: "2026-12-20"},
+		{text: "3 марта", ahead: true, date: "2027-03-03"},
+		{text: "1 мая 2027г", date: "2027-05-01"},
+		{text: "29 февраля 2028", date: "2028-02-29"},
+		{text: "2026-02-28", date: "2026-02-28"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got, err := parseHumanDate(tt.text, now, tt.ahead)
+			if err != nil {
+				t.Fatalf("parseHumanDate(%q): %v", tt.text, err)
+			}
+			if date := got.Date.Format("2006-01-02"); date != tt.date || got.Clock != tt.clock {
+				t.Errorf("parseHumanDate(%q) = %s %q, want %s %q", tt.text, date, got.Clock, tt.date, tt.clock)
+			}
+		})
+	}
+}
+
+func TestParseHumanDateErrors(t *testing.T) {
+	now := time.Date(2026, time.December, 16, 10, 0, 0, 0, time.UTC)
+	for _, text := range []string{
+		"",
+		"18:30",
+		"когда-нибудь",
+		"завтра 25:00",
+		"30.02",
+		"15.13",
+		"29 февраля 2027",
+		"2026-02-30",
+		"15 числа",
+	} {
+		t.Run(text, func(t *testing.T) {
+			_, err := parseHumanDate(text, now, true)
+			var message userMessage
+			if !errors.As(err, &message) {
+				t.Errorf("parseHumanDate(%q) error = %v, want a user message", text, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package telegram
+
+import (
+	"context"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+const workerQueueSize = 64
+
+// dispatcher fans updates out to a fixed set of workers. Every update of the
+// same user (or chat, when there is no sender) lands on the same worker, so
+// per-user ordering, the navigation stack and the persisted session stay
+// consistent while different admins are served in parallel.
+type dispatcher struct {
+	queues []chan tgbotapi.Update
+	wg     sync.WaitGroup
+}
+
+func newDispatcher(workers int, handle func(tgbotapi.Update)) *dispatcher {
+	d := &dispatcher{queues: make([]chan tgbotapi.Update, workers)}
+	for i := range d.queues {
+		queue := make(chan tgbotapi.Update, workerQueueSize)
+		d.queues[i] = queue
+		d.wg.Add(1)
+		go func() {
+			defer d.wg.Done()
+			for update := range queue {
+				handle(update)
+			}
+		}()
+	}
+	return d
+}
+
+// dispatch blocks while the target worker queue is full unless ctx is done.
+func (d *dispatcher) dispatch(ctx context.Context, update tgbotapi.Update) {
+	key := uint64(updateOrderKey(update))
+	queue := d.queues[key%uint64(len(d.queues))]
+	select {
+	case queue <- update:
+	case <-ctx.Done():
+	}
+}
+
+// stop closes the queues and waits until every queued update is handled.
+func (d *dispatcher) stop() {
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.wg.Wait()
+}
+
+func updateOrderKey(update tgbotapi.Update) int64 {
+	if id := updateUserID(update); id != 0 {
+		return id
+	}
+	if chat := update.FromChat(); chat != nil {
+		return chat.ID
+	}
+	return int64(update.UpdateID)
+}
+
+func updateUserID(update tgbotapi.Update) int64 {
+	if user := update.SentFrom(); user != nil {
+		return user.ID
+	}
+	return 0
+}
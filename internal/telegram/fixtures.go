@@ -0,0 +1,148 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+	"github.com/dynamost/telegram-bot/internal/service"
+)
+
+// maxFixtureLines caps one pasted schedule so that the preview fits into a
+// single message.
+const maxFixtureLines = 60
+
+// fixture is one line of a pasted schedule:
+// "12.10 11:00 Спартак, стадион Труд".
+type fixture struct {
+	Start    time.Time
+	Opponent string
+	Location string
+}
+
+// parseFixtureLine reads the date and time, then the opponent; everything
+// after the first comma is the venue.
+func parseFixtureLine(line string, now time.Time) (fixture, error) {
+	head, location, _ := strings.Cut(line, ",")
+	words := strings.Fields(head)
+	clockAt := -1
+	for i, word := range words {
+		if clockPattern.MatchString(word) {
+			clockAt = i
+			break
+		}
+	}
+	if clockAt < 1 {
+		return fixture{}, userMessage("нужны дата и время, например «12.10 11:00»")
+	}
+	if clockAt == len(words)-1 {
+		return fixture{}, userMessage("не указан соперник")
+	}
+	parsed, err := parseHumanDate(strings.Join(words[:clockAt+1], " "), now, true)
+	if err != nil {
+		return fixture{}, err
+	}
+	start, err := time.ParseInLocation("2006-01-02 15:04", parsed.Date.Format("2006-01-02")+" "+parsed.Clock, now.Location())
+	if err != nil {
+		return fixture{}, userMessagef("неверное время %q", parsed.Clock)
+	}
+	return fixture{
+		Start:    start,
+		Opponent: strings.Join(words[clockAt+1:], " "),
+		Location: strings.TrimSpace(location),
+	}, nil
+}
+
+// parseFixturesAnswer checks every pasted line. When any of them is wrong the
+// answer is rejected with the list of errors by line number; otherwise the
+// schedule is stored one normalised line per match.
+func (b *Bot) parseFixturesAnswer(text string) (string, error) {
+	now := b.timeNow().In(b.loc)
+	var (
+		stored   []string
+		problems []string
+		count    int
+	)
+	for n, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		count++
+		item, err := parseFixtureLine(line, now)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%d. %s — %v", n+1, line, err))
+			continue
+		}
+		stored = append(stored, strings.Join([]string{item.Start.Format("2006-01-02 15:04"), item.Opponent, item.Location}, "\t"))
+	}
+	switch {
+	case count == 0:
+		return "", userMessage("Вставьте хотя бы одну строку расписания.")
+	case count > maxFixtureLines:
+		return "", userMessagef("Слишком много строк: %d, максимум %d за раз.", count, maxFixtureLines)
+	case len(problems) > 0:
+		return "", userMessagef("Ошибки в строках:\n%s\n\nИсправьте их и отправьте список целиком.", strings.Join(problems, "\n"))
+	}
+	return strings.Join(stored, "\n"), nil
+}
+
+// storedFixtures reads back the schedule saved by parseFixturesAnswer.
+func (st *wizardState) storedFixtures(loc *time.Location) ([]fixture, error) {
+	var fixtures []fixture
+	for _, line := range strings.Split(st.Data["fixtures"], "\n") {
+		parts := strings.Split(line, "\t")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("fixture %q: %w", line, models.ErrValidation)
+		}
+		start, err := time.ParseInLocation("2006-01-02 15:04", parts[0], loc)
+		if err != nil {
+			return nil, err
+		}
+		fixtures = append(fixtures, fixture{Start: start, Opponent: parts[1], Location: parts[2]})
+	}
+	return fixtures, nil
+}
+
+func (b *Bot) fixturesSummary(_ context.Context, st *wizardState) (string, error) {
+	fixtures, err := st.storedFixtures(b.loc)
+	if err != nil {
+		return "", err
+	}
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Матчей: %d\n", len(fixtures)))
+	for i, item := range fixtures {
+		builder.WriteString(fmt.Sprintf("%d. %s %s — %s", i+1, weekdayNames[(int(item.Start.Weekday())+6)%7], item.Start.Format("02.01.2006 15:04"), escape(item.Opponent)))
+		if item.Location != "" {
+			builder.WriteString(fmt.Sprintf(" (%s)", escape(item.Location)))
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String(), nil
+}
+
+func (b *Bot) finishFixturesWizard(ctx context.Context, st *wizardState) error {
+	fixtures, err := st.storedFixtures(b.loc)
+	if err != nil {
+		return err
+	}
+	inputs := make([]service.CreateMatchInput, 0, len(fixtures))
+	for _, item := range fixtures {
+		input := service.CreateMatchInput{
+			TournamentID: st.id("tournament_id"),
+			TeamID:       st.id("team_id"),
+			Opponent:     item.Opponent,
+			StartTime:    item.Start,
+			Status:       models.MatchStatusScheduled,
+		}
+		if item.Location != "" {
+			location := item.Location
+			input.Location = &location
+		}
+		inputs = append(inputs, input)
+	}
+	_, err = b.svc.Matches.CreateMany(ctx, inputs)
+	return err
+}
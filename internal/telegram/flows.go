@@ -0,0 +1,901 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+	"github.com/dynamost/telegram-bot/internal/service"
+)
+
+var matchStatusChoices = []wizardChoice{
+	{Label: statusLabel(models.MatchStatusScheduled), Value: string(models.MatchStatusScheduled)},
+	{Label: statusLabel(models.MatchStatusPlayed), Value: string(models.MatchStatusPlayed)},
+	{Label: statusLabel(models.MatchStatusCanceled), Value: string(models.MatchStatusCanceled)},
+}
+
+// prompt returns a fixed question.
+func prompt(text string) func(*wizardState) string {
+	return func(*wizardState) string { return text }
+}
+
+// currentPrompt shows the value being edited, stored under "orig_"+key when
+// the wizard was started, before the question.
+func currentPrompt(label, key, question string) func(*wizardState) string {
+	return func(st *wizardState) string {
+		current := "(пусто)"
+		if val := st.Data["orig_"+key]; val != "" {
+			current = escape(val)
+		}
+		return fmt.Sprintf("%s: %s\n%s", label, current, question)
+	}
+}
+
+// numberPrompt suggests the free numbers found when the wizard was started.
+func numberPrompt(question string) func(*wizardState) string {
+	return func(st *wizardState) string {
+		if free := st.Data["free_numbers"]; free != "" {
+			return fmt.Sprintf("%s\nСвободные номера: %s.", question, free)
+		}
+		return question
+	}
+}
+
+// joinNumbers lists numbers for a prompt.
+func joinNumbers(numbers []int) string {
+	parts := make([]string, 0, len(numbers))
+	for _, number := range numbers {
+		parts = append(parts, strconv.Itoa(number))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func registerGroupPrompt(st *wizardState) string {
+	const question = "Введите группу или дивизион, например «A» или «2014 г.р.»."
+	if st.Data["registered"] == "" {
+		return question
+	}
+	return currentPrompt("Текущая группа", "group", question)(st)
+}
+
+// wizardFlows declares every wizard of the bot.
+func (b *Bot) wizardFlows() map[string]*wizardFlow {
+	return map[string]*wizardFlow{
+		flowCreateTournament: {
+			Title: "Новый турнир",
+			Steps: []wizardStep{
+				{Key: "name", Label: "Название", Prompt: prompt("Введите название.")},
+				{Key: "format", Label: "Формат", Prompt: prompt("Выберите формат турнира. Если пропустить — лига."), Choices: tournamentFormatChoices, Optional: true},
+				{Key: "points", Label: "Очки", Prompt: prompt("Очки за победу, ничью и поражение через пробел. Если пропустить — 3 1 0."), Parse: parsePointsAnswer, When: formatHasStandings, Optional: true},
+				{Key: "legs", Label: "Круги", Prompt: prompt("Сколько кругов в лиге? Если пропустить — два."), Choices: legChoices, When: formatIsLeague, Optional: true},
+				{Key: "groups", Label: "Группы", Prompt: prompt("Количество групп и сколько команд выходят из каждой, через пробел. Если пропустить — 2 2."), Parse: parseGroupsAnswer, When: formatHasGroups, Optional: true},
+				{Key: "start_date", Label: "Старт", Prompt: prompt("Выберите дату начала или введите её (например, 15.11 или сб)."), Parse: b.parseDateAnswer, Picker: pickerDate, Optional: true},
+				{Key: "end_date", Label: "Финиш", Prompt: prompt("Выберите дату окончания или введите её (например, 15.11 или сб)."), Parse: b.parseDateAnswer, Picker: pickerDate, Validate: validateEndDate, Optional: true},
+				{Key: "note", Label: "Примечание", Prompt: prompt("Введите примечание."), Optional: true},
+			},
+			Finish: b.finishTournamentWizard,
+			Done: func(ctx context.Context, chatID int64, _ *wizardState) error {
+				return b.sendTournamentList(ctx, chatID, 1)
+			},
+			Success: "Турнир создан.",
+			Failure: "Не удалось создать турнир",
+		},
+		flowEditTournament: {
+			Title: "Редактирование турнира",
+			Edit:  true,
+			Steps: []wizardStep{
+				{Key: "name", Label: "Название", Prompt: currentPrompt("Текущее название", "name", "Введите новое название."), Optional: true},
+				{Key: "format", Label: "Формат", Prompt: currentPrompt("Текущий формат", "format", "Выберите новый формат. Настройки сбросятся к обычным для него."), Choices: tournamentFormatChoices, Optional: true},
+				{Key: "points", Label: "Очки", Prompt: currentPrompt("Очки сейчас", "points", "Очки за победу, ничью и поражение через пробел."), Parse: parsePointsAnswer, When: formatHasStandings, Optional: true},
+				{Key: "legs", Label: "Круги", Prompt: currentPrompt("Кругов сейчас", "legs", "Сколько кругов в лиге?"), Choices: legChoices, When: formatIsLeague, Optional: true},
+				{Key: "groups", Label: "Группы", Prompt: currentPrompt("Группы сейчас", "groups", "Количество групп и сколько команд выходят из каждой, через пробел."), Parse: parseGroupsAnswer, When: formatHasGroups, Optional: true},
+				{Key: "start_date", Label: "Старт", Prompt: currentPrompt("Текущая дата начала", "start_date", "Выберите новую дату или введите её (например, 15.11 или сб)."), Parse: b.parseDateAnswer, Picker: pickerDate, Optional: true, Clearable: true},
+				{Key: "end_date", Label: "Финиш", Prompt: currentPrompt("Текущая дата окончания", "end_date", "Выберите новую дату или введите её (например, 15.11 или сб)."), Parse: b.parseDateAnswer, Picker: pickerDate, Validate: validateEndDate, Optional: true, Clearable: true},
+				{Key: "note", Label: "Примечание", Prompt: currentPrompt("Текущее примечание", "note", "Введите новое примечание."), Optional: true, Clearable: true},
+			},
+			Finish: b.finishTournamentEditWizard,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				return b.showTournament(ctx, chatID, st.id("id"))
+			},
+			Success: "Турнир обновлён.",
+			Failure: "Ошибка обновления турнира",
+		},
+		flowRegulations: {
+			Title: "Регламент",
+			Edit:  true,
+			Steps: []wizardStep{
+				{Key: "periods", Label: "Таймы", Prompt: currentPrompt("Таймы сейчас", "periods", "Количество таймов и их длительность, например «2x25»."), Parse: parsePeriodsAnswer, Optional: true},
+				{Key: "squad", Label: "Состав", Prompt: currentPrompt("В старте и минимум сейчас", "squad", "Игроков в старте и минимум игроков для матча через пробел."), Parse: parseSquadAnswer, Optional: true},
+				{Key: "bench", Label: "Запасных", Prompt: currentPrompt("Запасных сейчас", "bench", "Сколько запасных можно заявить на матч? «удалить» снимает ограничение."), Parse: parseLimitAnswer, Optional: true, Clearable: true},
+				{Key: "subs", Label: "Замен", Prompt: currentPrompt("Замен сейчас", "subs", "Сколько замен можно сделать за матч? «удалить» снимает ограничение."), Parse: parseLimitAnswer, Optional: true, Clearable: true},
+				{Key: "rolling", Label: "Обратные замены", Prompt: currentPrompt("Обратные замены", "rolling", "Может ли заменённый игрок вернуться на поле?"), Choices: yesNoChoices, Parse: parseWizardYesNo, Optional: true},
+				{Key: "roster_opens", Label: "Заявка с", Prompt: currentPrompt("Заявка открывается", "roster_opens", "Выберите новую дату или введите её."), Parse: b.parseDateAnswer, Picker: pickerDate, Optional: true, Clearable: true},
+				{Key: "roster_closes", Label: "Заявка до", Prompt: currentPrompt("Заявка закрывается", "roster_closes", "Выберите новую дату или введите её."), Parse: b.parseDateAnswer, Picker: pickerDate, Validate: validateRosterCloses, Optional: true, Clearable: true},
+				{Key: "squad_size", Label: "Игроков в заявке", Prompt: currentPrompt("Игроков в заявке", "squad_size", "Минимум и максимум через пробел; «-» вместо числа — без ограничения, «удалить» снимает оба."), Parse: parseSquadSizeAnswer, Optional: true, Clearable: true},
+				{Key: "ages", Label: "Годы рождения", Prompt: currentPrompt("Годы рождения", "ages", "«2014-2015», «2014» (2014 г.р. и младше) или категория «U12»; «удалить» снимает ограничение."), Parse: b.parseAgeAnswer, Optional: true, Clearable: true},
+				{Key: "overage", Label: "Старше возраста", Prompt: currentPrompt("Старше возраста", "overage", "Сколько игроков старше возраста может заявить команда?"), Parse: parseLimitAnswer, When: ageLimited, Optional: true},
+				{Key: "one_team", Label: "Одна команда", Prompt: currentPrompt("Только за одну команду", "one_team", "Может ли игрок быть заявлен только за одну команду турнира?"), Choices: yesNoChoices, Parse: parseWizardYesNo, Optional: true},
+			},
+			Finish: b.finishRegulationsWizard,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				return b.showTournament(ctx, chatID, st.id("id"))
+			},
+			Success: "Регламент сохранён.",
+			Failure: "Не удалось сохранить регламент",
+		},
+		flowCreateTeam: {
+			Title: "Новая команда",
+			Steps: []wizardStep{
+				{Key: "name", Label: "Название", Prompt: prompt("Введите название.")},
+				{Key: "short_code", Label: "Код", Prompt: prompt("Введите короткий код (например, U12).")},
+				{Key: "active", Label: "Активна", Prompt: prompt("Команда активна? По умолчанию да."), Choices: yesNoChoices, Parse: parseWizardYesNo, Optional: true},
+				{Key: "note", Label: "Примечание", Prompt: prompt("Введите примечание."), Optional: true},
+			},
+			Finish:  b.finishTeamWizard,
+			Done:    func(ctx context.Context, chatID int64, _ *wizardState) error { return b.sendTeams(ctx, chatID) },
+			Success: "Команда создана.",
+			Failure: "Не удалось создать команду",
+		},
+		flowEditTeam: {
+			Title: "Редактирование команды",
+			Edit:  true,
+			Steps: []wizardStep{
+				{Key: "name", Label: "Название", Prompt: currentPrompt("Текущее название", "name", "Введите новое название."), Optional: true},
+				{Key: "short_code", Label: "Код", Prompt: currentPrompt("Текущий код", "short_code", "Введите новый короткий код."), Optional: true},
+				{Key: "active", Label: "Активна", Prompt: currentPrompt("Активна", "active", "Команда активна?"), Choices: yesNoChoices, Parse: parseWizardYesNo, Optional: true},
+				{Key: "note", Label: "Примечание", Prompt: currentPrompt("Текущее примечание", "note", "Введите новое примечание."), Optional: true, Clearable: true},
+			},
+			Finish: b.finishTeamEditWizard,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				return b.showTeam(ctx, chatID, st.id("id"))
+			},
+			Success: "Команда обновлена.",
+			Failure: "Не удалось обновить команду",
+		},
+		flowCreatePlayer: {
+			Title: "Новый игрок",
+			Steps: []wizardStep{
+				{Key: "full_name", Label: "ФИО", Prompt: prompt("Укажите ФИО.")},
+				{Key: "birth_date", Label: "Дата рождения", Prompt: prompt("Выберите дату рождения или введите её (например, 15.11.2014)."), Parse: b.parseBirthDateAnswer, Picker: pickerDate, Optional: true},
+				{Key: "position", Label: "Позиция", Prompt: prompt("Введите игровую позицию."), Optional: true},
+				{Key: "note", Label: "Примечание", Prompt: prompt("Введите примечание."), Optional: true},
+			},
+			Finish: b.finishPlayerWizard,
+			Done: func(ctx context.Context, chatID int64, _ *wizardState) error {
+				return b.sendPlayersPage(ctx, chatID, 1)
+			},
+			Success: "Игрок создан.",
+			Failure: "Не удалось создать игрока",
+		},
+		flowEditPlayer: {
+			Title: "Редактирование игрока",
+			Edit:  true,
+			Steps: []wizardStep{
+				{Key: "full_name", Label: "ФИО", Prompt: currentPrompt("Текущее ФИО", "full_name", "Введите новое ФИО."), Optional: true},
+				{Key: "birth_date", Label: "Дата рождения", Prompt: currentPrompt("Текущая дата рождения", "birth_date", "Выберите новую дату или введите её (например, 15.11.2014)."), Parse: b.parseBirthDateAnswer, Picker: pickerDate, Optional: true, Clearable: true},
+				{Key: "position", Label: "Позиция", Prompt: currentPrompt("Текущая позиция", "position", "Введите новую позицию."), Optional: true, Clearable: true},
+				{Key: "active", Label: "Активен", Prompt: currentPrompt("Активен", "active", "Игрок активен?"), Choices: yesNoChoices, Parse: parseWizardYesNo, Optional: true},
+				{Key: "note", Label: "Примечание", Prompt: currentPrompt("Текущее примечание", "note", "Введите новое примечание."), Optional: true, Clearable: true},
+			},
+			Finish: b.finishPlayerEditWizard,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				page := int(st.id("return_page"))
+				if page < 1 {
+					page = 1
+				}
+				return b.showPlayer(ctx, chatID, st.id("id"), page)
+			},
+			Success: "Игрок обновлён.",
+			Failure: "Не удалось обновить игрока",
+		},
+		flowRegisterTeam: {
+			Title: "Добавить команду в турнир",
+			Steps: []wizardStep{
+				{Key: "group", Label: "Группа", Prompt: registerGroupPrompt, Optional: true, Clearable: true},
+				{Key: "registered_on", Label: "Дата регистрации", Prompt: prompt("Выберите дату регистрации или введите её. Если пропустить — сегодня."), Parse: b.parseBirthDateAnswer, Picker: pickerDate, Optional: true},
+			},
+			Finish: b.finishRegisterTeamWizard,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				if st.Data["registered"] != "" {
+					return b.showRosterOfWizard(ctx, chatID, st)
+				}
+				return b.sendRosterTeams(ctx, chatID, st.id("tournament_id"))
+			},
+			Success: "Команда зарегистрирована в турнире.",
+			Failure: "Не удалось зарегистрировать команду",
+		},
+		flowRosterAddPlayer: {
+			Title: "Добавление в заявку",
+			Steps: []wizardStep{
+				{Key: "number", Label: "Номер", Prompt: numberPrompt("Введите номер игрока в турнире."), Parse: parseWizardNumber, Optional: true},
+			},
+			Finish: func(ctx context.Context, st *wizardState) error {
+				return b.svc.Rosters.AddPlayer(ctx, st.id("tournament_id"), st.id("team_id"), st.id("player_id"), st.intPtr("number"))
+			},
+			Done:    b.showRosterOfWizard,
+			Success: "Игрок добавлен в заявку.",
+			Failure: "Не удалось добавить игрока",
+		},
+		flowRosterChangeNumber: {
+			Title: "Номер в заявке",
+			Steps: []wizardStep{
+				{Key: "number", Label: "Номер", Prompt: numberPrompt("Введите новый номер игрока."), Parse: parseWizardNumber, Clearable: true},
+			},
+			Finish: func(ctx context.Context, st *wizardState) error {
+				return b.svc.Rosters.UpdateNumber(ctx, st.id("tournament_id"), st.id("team_id"), st.id("player_id"), st.intPtr("number"))
+			},
+			Done:    b.showRosterOfWizard,
+			Success: "Номер обновлён.",
+			Failure: "Не удалось изменить номер",
+		},
+		flowMatchCreate: {
+			Title: "Новый матч",
+			Steps: []wizardStep{
+				{Key: "opponent", Label: "Соперник", Prompt: prompt("Укажите соперника.")},
+				{Key: "date", Label: "Дата", Prompt: prompt("Выберите дату матча или введите её, можно сразу со временем: «сб 10:00», «завтра 18:30», «15.11 19:00»."), Parse: b.parseDateAnswer, Picker: pickerDate, Clock: "time"},
+				{Key: "time", Label: "Время", Prompt: prompt("Выберите время начала или введите его (HH:MM)."), Parse: parseWizardClock, Picker: pickerTime},
+				{Key: "location", Label: "Место", Prompt: prompt("Введите место проведения."), Optional: true},
+				{Key: "stage", Label: "Стадия", Prompt: prompt("Выберите стадию турнира. Матчи плей-офф продвигают победителя по сетке."), Choices: matchStageChoices, When: matchHasStage, Optional: true},
+			},
+			Finish: b.finishMatchCreateWizard,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				return b.sendGamesMatches(ctx, chatID, st.id("tournament_id"), st.id("team_id"))
+			},
+			Success: "Матч создан.",
+			Failure: "Не удалось создать матч",
+		},
+		flowMatchImport: {
+			Title: "Загрузка расписания",
+			Steps: []wizardStep{
+				{Key: "fixtures", Label: "Расписание", Prompt: prompt("Вставьте расписание, по матчу в строке: дата, время, соперник и через запятую место.\nНапример:\n12.10 11:00 Спартак, стадион Труд\nсб 10:00 Динамо"), Parse: b.parseFixturesAnswer},
+			},
+			Finish:  b.finishFixturesWizard,
+			Summary: b.fixturesSummary,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				return b.sendGamesMatches(ctx, chatID, st.id("tournament_id"), st.id("team_id"))
+			},
+			Success: "Расписание загружено.",
+			Failure: "Не удалось загрузить расписание",
+		},
+		flowMatchLeague: {
+			Title: "Круговой турнир",
+			Steps: []wizardStep{
+				{Key: "opponents", Label: "Соперники", Prompt: prompt("Отправьте остальные команды лиги, по одной в строке."), Parse: parseOpponentsAnswer},
+				{Key: "legs", Label: "Круги", Prompt: prompt("Сколько кругов играется?"), Choices: legChoices},
+				{Key: "start_date", Label: "Старт", Prompt: prompt("Выберите дату, с которой начинается первый тур, или введите её."), Parse: b.parseDateAnswer, Picker: pickerDate},
+				{Key: "weekday", Label: "Игровой день", Prompt: prompt("Выберите день недели для туров. Если пропустить — день недели даты старта."), Choices: matchdayChoices, Optional: true},
+				{Key: "time", Label: "Начало", Prompt: prompt("Выберите время начала матчей или введите его (HH:MM)."), Parse: parseWizardClock, Picker: pickerTime},
+				{Key: "blackouts", Label: "Без игр", Prompt: prompt("Перечислите даты, когда туров нет, через запятую: «31.12, 07.01» или периодом «28.12 - 08.01»."), Parse: b.parseBlackoutsAnswer, Optional: true},
+				{Key: "venue", Label: "Домашнее поле", Prompt: prompt("Где команда играет дома? Место будет указано в домашних матчах."), Optional: true},
+			},
+			Finish:  b.finishLeagueWizard,
+			Summary: b.leagueSummary,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				return b.sendGamesMatches(ctx, chatID, st.id("tournament_id"), st.id("team_id"))
+			},
+			Success: "Календарь создан.",
+			Failure: "Не удалось создать календарь",
+		},
+		flowBracket: {
+			Title: "Сетка плей-офф",
+			Steps: []wizardStep{
+				{Key: "entrants", Label: "Команды", Prompt: prompt("Отправьте участников плей-офф по одному в строке в порядке посева: первый играет с последним, второй — с предпоследним по другую сторону сетки и т. д. Если команд не 2, 4, 8 или 16, первые по посеву проходят следующий круг без игры."), Parse: parseEntrantsAnswer},
+			},
+			Finish:  b.finishBracketWizard,
+			Summary: b.bracketSummary,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				return b.sendBracket(ctx, chatID, st.id("tournament_id"))
+			},
+			Success: "Сетка составлена.",
+			Failure: "Не удалось составить сетку",
+		},
+		flowMatchImportICS: {
+			Title: "Импорт календаря",
+			Steps: []wizardStep{
+				{Key: "events", Label: "Календарь", Prompt: prompt("Отправьте файл календаря .ics. Матчи, загруженные из него раньше, будут обновлены, а не продублированы."), Parse: b.parseCalendarAnswer, Document: true},
+			},
+			Finish:  b.finishCalendarImport,
+			Summary: b.calendarSummary,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				return b.sendGamesMatches(ctx, chatID, st.id("tournament_id"), st.id("team_id"))
+			},
+			Success: "Календарь импортирован.",
+			Failure: "Не удалось импортировать календарь",
+		},
+		flowMatchEdit: {
+			Title: "Редактирование матча",
+			Edit:  true,
+			Steps: []wizardStep{
+				{Key: "status", Label: "Статус", Prompt: prompt("Выберите статус."), Choices: matchStatusChoices, Optional: true},
+				{Key: "date", Label: "Дата", Prompt: currentPrompt("Текущая дата", "date", "Выберите новую дату или введите её, можно сразу со временем: «сб 10:00»."), Parse: b.parseDateAnswer, Picker: pickerDate, Clock: "time", Optional: true},
+				{Key: "time", Label: "Время", Prompt: currentPrompt("Текущее время", "time", "Выберите новое время или введите его (HH:MM)."), Parse: parseWizardClock, Picker: pickerTime, Optional: true},
+				{Key: "location", Label: "Место", Prompt: prompt("Введите место проведения."), Optional: true, Clearable: true},
+				{Key: "stage", Label: "Стадия", Prompt: currentPrompt("Текущая стадия", "stage", "Выберите стадию турнира."), Choices: matchStageChoices, When: matchHasStage, Optional: true, Clearable: true},
+				{Key: "scores", Label: "Счёт", Prompt: prompt("Введите счёты через пробел: HT FT ET PEN FINAL\\_US FINAL\\_THEM. '-' на месте значения очищает его."), Parse: parseWizardScores, When: matchScored, Optional: true},
+			},
+			Finish: b.finishMatchEditWizard,
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				return b.showMatch(ctx, chatID, st.id("match_id"))
+			},
+			Success: "Матч обновлён.",
+			Failure: "Не удалось обновить матч",
+		},
+		flowLineupNumber: {
+			Title: "Номер на матч",
+			Steps: []wizardStep{
+				{Key: "number", Label: "Номер", Prompt: numberPrompt("Введите номер игрока на матч."), Parse: parseWizardNumber, Clearable: true},
+			},
+			Finish: func(ctx context.Context, st *wizardState) error {
+				return b.svc.Lineup.Update(ctx, st.id("match_id"), st.id("player_id"), models.LineupPatch{
+					NumberOverride: models.NewOptionalInt(st.intPtr("number")),
+				})
+			},
+			Done: func(ctx context.Context, chatID int64, st *wizardState) error {
+				return b.sendLineupMenu(ctx, chatID, st.id("match_id"))
+			},
+			Success: "Номер в составе обновлён.",
+			Failure: "Не удалось обновить номер",
+		},
+		flowEventGoal: {
+			Title: "Гол",
+			Steps: []wizardStep{
+				{Key: "minute", Label: "Минута", Prompt: prompt("Введите время гола (например, 45+2).")},
+			},
+			Finish: func(ctx context.Context, st *wizardState) error {
+				return b.svc.Events.AddGoal(ctx, st.id("match_id"), st.id("player_id"), st.Data["minute"])
+			},
+			Done:    b.showMatchOfWizard,
+			Success: "Гол добавлен.",
+			Failure: "Не удалось добавить гол",
+		},
+		flowEventCard: {
+			Title: "Карточка",
+			Steps: []wizardStep{
+				{Key: "minute", Label: "Минута", Prompt: prompt("Введите время карточки (например, 12 или 90+3).")},
+			},
+			Finish: func(ctx context.Context, st *wizardState) error {
+				cardType := models.CardType(st.Data["card_type"])
+				if cardType != models.CardTypeYellow && cardType != models.CardTypeRed {
+					return errors.New("неизвестный тип карточки")
+				}
+				return b.svc.Events.AddCard(ctx, st.id("match_id"), st.id("player_id"), cardType, st.Data["minute"])
+			},
+			Done:    b.showMatchOfWizard,
+			Success: "Карточка добавлена.",
+			Failure: "Не удалось добавить карточку",
+		},
+		flowEventSub: {
+			Title: "Замена",
+			Steps: []wizardStep{
+				{Key: "minute", Label: "Минута", Prompt: prompt("Введите время замены (например, 60).")},
+			},
+			Finish: func(ctx context.Context, st *wizardState) error {
+				return b.svc.Events.AddSub(ctx, st.id("match_id"), st.id("out_id"), st.id("in_id"), st.Data["minute"])
+			},
+			Done:    b.showMatchOfWizard,
+			Success: "Замена добавлена.",
+			Failure: "Не удалось добавить замену",
+		},
+	}
+}
+
+func validateEndDate(st *wizardState, value string) error {
+	start := st.Data["start_date"]
+	if start == "" {
+		start = st.Data["orig_start_date"]
+	}
+	if start != "" && value < start {
+		return userMessage("Дата окончания раньше даты начала.")
+	}
+	return nil
+}
+
+func validateRosterCloses(st *wizardState, value string) error {
+	opens, ok := st.Data["roster_opens"]
+	if !ok {
+		opens = st.Data["orig_roster_opens"]
+	}
+	if opens != "" && value < opens {
+		return userMessage("Заявка не может закрыться раньше, чем откроется.")
+	}
+	return nil
+}
+
+func (b *Bot) finishRegisterTeamWizard(ctx context.Context, st *wizardState) error {
+	input := service.RegisterTeamInput{
+		TournamentID: st.id("tournament_id"),
+		TeamID:       st.id("team_id"),
+	}
+	// Editing keeps the group and the date unless they are answered again.
+	if _, answered := st.Data["group"]; answered || st.Data["registered"] == "" {
+		input.GroupName = st.stringPtr("group")
+	} else {
+		input.GroupName = st.stringPtr("orig_group")
+	}
+	registeredOn, err := st.datePtr("registered_on", b.loc)
+	if err != nil {
+		return err
+	}
+	if registeredOn == nil && st.Data["registered"] != "" {
+		if registeredOn, err = st.datePtr("registered", b.loc); err != nil {
+			return err
+		}
+	}
+	input.RegisteredOn = registeredOn
+	return b.svc.Rosters.RegisterTeam(ctx, input)
+}
+
+func (b *Bot) showRosterOfWizard(ctx context.Context, chatID int64, st *wizardState) error {
+	return b.showRoster(ctx, chatID, st.id("tournament_id"), st.id("team_id"))
+}
+
+func (b *Bot) showMatchOfWizard(ctx context.Context, chatID int64, st *wizardState) error {
+	return b.showMatch(ctx, chatID, st.id("match_id"))
+}
+
+// Starters -------------------------------------------------------------------
+
+func (b *Bot) startTournamentWizard(ctx context.Context, key models.SessionKey) error {
+	return b.startWizard(ctx, key, flowCreateTournament, nil)
+}
+
+func (b *Bot) startTournamentEditWizard(ctx context.Context, key models.SessionKey, tournamentID int64) error {
+	tournament, err := b.svc.Tournaments.Get(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	config := tournament.FormatConfig
+	data := map[string]string{
+		"id":             strconv.FormatInt(tournamentID, 10),
+		"orig_name":      tournament.Name,
+		"orig_format":    formatLabel(tournament.Format),
+		"current_format": string(tournament.Format),
+		"orig_points":    fmt.Sprintf("%d %d %d", config.PointsWin, config.PointsDraw, config.PointsLoss),
+		"orig_legs":      strconv.Itoa(config.Legs),
+		"orig_groups":    fmt.Sprintf("%d %d", config.Groups, config.AdvancePerGroup),
+	}
+	if tournament.StartDate != nil {
+		data["orig_start_date"] = tournament.StartDate.Format("2006-01-02")
+	}
+	if tournament.EndDate != nil {
+		data["orig_end_date"] = tournament.EndDate.Format("2006-01-02")
+	}
+	if tournament.Note != nil {
+		data["orig_note"] = *tournament.Note
+	}
+	return b.startWizard(ctx, key, flowEditTournament, data)
+}
+
+func (b *Bot) startRegulationsWizard(ctx context.Context, key models.SessionKey, tournamentID int64) error {
+	regulations, err := b.svc.Tournaments.Regulations(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{"id": strconv.FormatInt(tournamentID, 10)}
+	regulationsData(regulations, data)
+	return b.startWizard(ctx, key, flowRegulations, data)
+}
+
+func (b *Bot) startTeamWizard(ctx context.Context, key models.SessionKey) error {
+	return b.startWizard(ctx, key, flowCreateTeam, nil)
+}
+
+func (b *Bot) startTeamEditWizard(ctx context.Context, key models.SessionKey, teamID int64) error {
+	team, err := b.svc.Teams.Get(ctx, teamID)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		"id":              strconv.FormatInt(team.ID, 10),
+		"orig_name":       team.Name,
+		"orig_short_code": team.ShortCode,
+		"orig_active":     yesNoLabel(team.Active),
+	}
+	if team.Note != nil {
+		data["orig_note"] = *team.Note
+	}
+	return b.startWizard(ctx, key, flowEditTeam, data)
+}
+
+func (b *Bot) startPlayerWizard(ctx context.Context, key models.SessionKey) error {
+	return b.startWizard(ctx, key, flowCreatePlayer, nil)
+}
+
+func (b *Bot) startPlayerEditWizard(ctx context.Context, key models.SessionKey, playerID int64, page int) error {
+	player, err := b.svc.Players.Get(ctx, playerID)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		"id":             strconv.FormatInt(playerID, 10),
+		"return_page":    strconv.Itoa(page),
+		"orig_full_name": player.FullName,
+		"orig_active":    yesNoLabel(player.Active),
+	}
+	if player.BirthDate != nil {
+		data["orig_birth_date"] = player.BirthDate.Format("2006-01-02")
+	}
+	if player.Position != nil {
+		data["orig_position"] = *player.Position
+	}
+	if player.Note != nil {
+		data["orig_note"] = *player.Note
+	}
+	return b.startWizard(ctx, key, flowEditPlayer, data)
+}
+
+// startRegisterTeamWizard registers a team or, when it is already in the
+// tournament, edits its group and registration date.
+func (b *Bot) startRegisterTeamWizard(ctx context.Context, key models.SessionKey, tournamentID, teamID int64) error {
+	data := map[string]string{
+		"tournament_id": strconv.FormatInt(tournamentID, 10),
+		"team_id":       strconv.FormatInt(teamID, 10),
+	}
+	team, err := b.svc.Rosters.GetTournamentTeam(ctx, tournamentID, teamID)
+	switch {
+	case err == nil:
+		data["registered"] = team.RegisteredOn.Format("2006-01-02")
+		if team.GroupName != nil {
+			data["orig_group"] = *team.GroupName
+		}
+	case !errors.Is(err, models.ErrNotFound):
+		return err
+	}
+	return b.startWizard(ctx, key, flowRegisterTeam, data)
+}
+
+func (b *Bot) startRosterAddWizard(ctx context.Context, key models.SessionKey, tournamentID, teamID, playerID int64) error {
+	free, err := b.svc.Rosters.FreeNumbers(ctx, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
+	return b.startWizard(ctx, key, flowRosterAddPlayer, map[string]string{
+		"tournament_id": strconv.FormatInt(tournamentID, 10),
+		"team_id":       strconv.FormatInt(teamID, 10),
+		"player_id":     strconv.FormatInt(playerID, 10),
+		"free_numbers":  joinNumbers(free),
+	})
+}
+
+func (b *Bot) startRosterChangeWizard(ctx context.Context, key models.SessionKey, tournamentID, teamID, playerID int64) error {
+	free, err := b.svc.Rosters.FreeNumbers(ctx, tournamentID, teamID)
+	if err != nil {
+		return err
+	}
+	return b.startWizard(ctx, key, flowRosterChangeNumber, map[string]string{
+		"tournament_id": strconv.FormatInt(tournamentID, 10),
+		"team_id":       strconv.FormatInt(teamID, 10),
+		"player_id":     strconv.FormatInt(playerID, 10),
+		"free_numbers":  joinNumbers(free),
+	})
+}
+
+func (b *Bot) startMatchCreateWizard(ctx context.Context, key models.SessionKey, tournamentID, teamID int64) error {
+	tournament, err := b.svc.Tournaments.Get(ctx, tournamentID)
+	if err != nil {
+		return err
+	}
+	return b.startWizard(ctx, key, flowMatchCreate, map[string]string{
+		"tournament_id":     strconv.FormatInt(tournamentID, 10),
+		"team_id":           strconv.FormatInt(teamID, 10),
+		"tournament_format": string(tournament.Format),
+	})
+}
+
+func (b *Bot) startMatchImportWizard(ctx context.Context, key models.SessionKey, tournamentID, teamID int64) error {
+	return b.startWizard(ctx, key, flowMatchImport, map[string]string{
+		"tournament_id": strconv.FormatInt(tournamentID, 10),
+		"team_id":       strconv.FormatInt(teamID, 10),
+	})
+}
+
+func (b *Bot) startLeagueWizard(ctx context.Context, key models.SessionKey, tournamentID, teamID int64) error {
+	team, err := b.svc.Teams.Get(ctx, teamID)
+	if err != nil {
+		return err
+	}
+	return b.startWizard(ctx, key, flowMatchLeague, map[string]string{
+		"tournament_id": strconv.FormatInt(tournamentID, 10),
+		"team_id":       strconv.FormatInt(teamID, 10),
+		"team_name":     team.Name,
+	})
+}
+
+func (b *Bot) startCalendarImportWizard(ctx context.Context, key models.SessionKey, tournamentID, teamID int64) error {
+	return b.startWizard(ctx, key, flowMatchImportICS, map[string]string{
+		"tournament_id": strconv.FormatInt(tournamentID, 10),
+		"team_id":       strconv.FormatInt(teamID, 10),
+	})
+}
+
+func (b *Bot) startMatchEditWizard(ctx context.Context, key models.SessionKey, matchID int64) error {
+	match, err := b.svc.Matches.Get(ctx, matchID)
+	if err != nil {
+		return err
+	}
+	tournament, err := b.svc.Tournaments.Get(ctx, match.TournamentID)
+	if err != nil {
+		return err
+	}
+	start := match.StartTime.In(b.loc)
+	data := map[string]string{
+		"match_id":          strconv.FormatInt(matchID, 10),
+		"orig_date":         start.Format("2006-01-02"),
+		"orig_time":         start.Format("15:04"),
+		"tournament_format": string(tournament.Format),
+	}
+	if match.Stage != nil {
+		data["orig_stage"] = stageLabel(*match.Stage)
+	}
+	return b.startWizard(ctx, key, flowMatchEdit, data)
+}
+
+func (b *Bot) startLineupNumberWizard(ctx context.Context, key models.SessionKey, matchID, playerID int64) error {
+	free, err := b.svc.Lineup.FreeNumbers(ctx, matchID)
+	if err != nil {
+		return err
+	}
+	return b.startWizard(ctx, key, flowLineupNumber, map[string]string{
+		"match_id":     strconv.FormatInt(matchID, 10),
+		"player_id":    strconv.FormatInt(playerID, 10),
+		"free_numbers": joinNumbers(free),
+	})
+}
+
+func (b *Bot) startEventGoalWizard(ctx context.Context, key models.SessionKey, matchID, playerID int64) error {
+	return b.startWizard(ctx, key, flowEventGoal, map[string]string{
+		"match_id":  strconv.FormatInt(matchID, 10),
+		"player_id": strconv.FormatInt(playerID, 10),
+	})
+}
+
+func (b *Bot) startEventCardWizard(ctx context.Context, key models.SessionKey, matchID, playerID int64, cardType string) error {
+	cardType = strings.ToLower(cardType)
+	if cardType != string(models.CardTypeYellow) && cardType != string(models.CardTypeRed) {
+		b.sendSimple(key.ChatID, "Неизвестный тип карточки.")
+		return nil
+	}
+	return b.startWizard(ctx, key, flowEventCard, map[string]string{
+		"match_id":  strconv.FormatInt(matchID, 10),
+		"player_id": strconv.FormatInt(playerID, 10),
+		"card_type": cardType,
+	})
+}
+
+func (b *Bot) startEventSubWizard(ctx context.Context, key models.SessionKey, matchID, outPlayerID, inPlayerID int64) error {
+	if outPlayerID == inPlayerID {
+		b.sendSimple(key.ChatID, "Игроки замены должны отличаться.")
+		return nil
+	}
+	return b.startWizard(ctx, key, flowEventSub, map[string]string{
+		"match_id": strconv.FormatInt(matchID, 10),
+		"out_id":   strconv.FormatInt(outPlayerID, 10),
+		"in_id":    strconv.FormatInt(inPlayerID, 10),
+	})
+}
+
+// Finishers ------------------------------------------------------------------
+
+func (b *Bot) finishTournamentWizard(ctx context.Context, st *wizardState) error {
+	start, err := st.datePtr("start_date", b.loc)
+	if err != nil {
+		return err
+	}
+	end, err := st.datePtr("end_date", b.loc)
+	if err != nil {
+		return err
+	}
+	format := wizardFormat(st)
+	config, _, err := wizardFormatConfig(st, models.DefaultFormatConfig(format))
+	if err != nil {
+		return err
+	}
+	input := service.CreateTournamentInput{
+		Name:         st.Data["name"],
+		Format:       format,
+		FormatConfig: &config,
+		Status:       models.TournamentStatusPlanned,
+		StartDate:    start,
+		EndDate:      end,
+		Note:         st.stringPtr("note"),
+	}
+	_, err = b.svc.Tournaments.Create(ctx, input)
+	return err
+}
+
+func (b *Bot) finishTournamentEditWizard(ctx context.Context, st *wizardState) error {
+	patch := models.TournamentPatch{
+		Name: st.stringPtr("name"),
+		Note: st.optionalString("note"),
+	}
+	current, err := b.svc.Tournaments.Get(ctx, st.id("id"))
+	if err != nil {
+		return err
+	}
+	base := current.FormatConfig
+	if v := st.Data["format"]; v != "" && models.TournamentFormat(v) != current.Format {
+		format := models.TournamentFormat(v)
+		patch.Format = &format
+		base = models.DefaultFormatConfig(format)
+	}
+	config, answered, err := wizardFormatConfig(st, base)
+	if err != nil {
+		return err
+	}
+	if answered {
+		patch.FormatConfig = &config
+	}
+	if patch.StartDate, err = st.optionalDate("start_date", b.loc); err != nil {
+		return err
+	}
+	if patch.EndDate, err = st.optionalDate("end_date", b.loc); err != nil {
+		return err
+	}
+	return b.svc.Tournaments.Update(ctx, st.id("id"), patch)
+}
+
+func (b *Bot) finishRegulationsWizard(ctx context.Context, st *wizardState) error {
+	current, err := b.svc.Tournaments.Regulations(ctx, st.id("id"))
+	if err != nil {
+		return err
+	}
+	regulations, answered, err := wizardRegulations(st, *current, b.loc)
+	if err != nil || !answered {
+		return err
+	}
+	return b.svc.Tournaments.SetRegulations(ctx, regulations)
+}
+
+func (b *Bot) finishTeamWizard(ctx context.Context, st *wizardState) error {
+	active := true
+	if val := st.boolPtr("active"); val != nil {
+		active = *val
+	}
+	_, err := b.svc.Teams.Create(ctx, service.CreateTeamInput{
+		Name:      st.Data["name"],
+		ShortCode: st.Data["short_code"],
+		Active:    active,
+		Note:      st.stringPtr("note"),
+	})
+	return err
+}
+
+func (b *Bot) finishTeamEditWizard(ctx context.Context, st *wizardState) error {
+	return b.svc.Teams.Update(ctx, st.id("id"), models.TeamPatch{
+		Name:      st.stringPtr("name"),
+		ShortCode: st.stringPtr("short_code"),
+		Active:    st.boolPtr("active"),
+		Note:      st.optionalString("note"),
+	})
+}
+
+func (b *Bot) finishPlayerWizard(ctx context.Context, st *wizardState) error {
+	birth, err := st.datePtr("birth_date", b.loc)
+	if err != nil {
+		return err
+	}
+	_, err = b.svc.Players.Create(ctx, service.CreatePlayerInput{
+		FullName: st.Data["full_name"],
+		Birth:    birth,
+		Position: st.stringPtr("position"),
+		Active:   true,
+		Note:     st.stringPtr("note"),
+	})
+	return err
+}
+
+func (b *Bot) finishPlayerEditWizard(ctx context.Context, st *wizardState) error {
+	patch := models.PlayerPatch{
+		FullName: st.stringPtr("full_name"),
+		Position: st.optionalString("position"),
+		Active:   st.boolPtr("active"),
+		Note:     st.optionalString("note"),
+	}
+	var err error
+	if patch.BirthDate, err = st.optionalDate("birth_date", b.loc); err != nil {
+		return err
+	}
+	return b.svc.Players.Update(ctx, st.id("id"), patch)
+}
+
+func (b *Bot) finishMatchCreateWizard(ctx context.Context, st *wizardState) error {
+	start, err := time.ParseInLocation("2006-01-02 15:04", st.Data["date"]+" "+st.Data["time"], b.loc)
+	if err != nil {
+		return err
+	}
+	_, err = b.svc.Matches.Create(ctx, service.CreateMatchInput{
+		TournamentID: st.id("tournament_id"),
+		TeamID:       st.id("team_id"),
+		Opponent:     st.Data["opponent"],
+		StartTime:    start,
+		Location:     st.stringPtr("location"),
+		Status:       models.MatchStatusScheduled,
+		Stage:        st.stagePtr("stage"),
+	})
+	return err
+}
+
+func (b *Bot) finishMatchEditWizard(ctx context.Context, st *wizardState) error {
+	matchID := st.id("match_id")
+	match, err := b.svc.Matches.Get(ctx, matchID)
+	if err != nil {
+		return err
+	}
+	patch := models.MatchPatch{
+		Location: st.optionalString("location"),
+		Stage:    st.optionalString("stage"),
+	}
+	if status := st.Data["status"]; status != "" {
+		ms := models.MatchStatus(status)
+		patch.Status = &ms
+	}
+
+	dateVal, hasDate := st.Data["date"]
+	timeVal, hasTime := st.Data["time"]
+	if hasDate || hasTime {
+		start := match.StartTime.In(b.loc)
+		year, month, day := start.Date()
+		hour, minute, _ := start.Clock()
+		if hasDate {
+			parsed, err := time.ParseInLocation("2006-01-02", dateVal, b.loc)
+			if err != nil {
+				return fmt.Errorf("неверный формат даты: %w", err)
+			}
+			year, month, day = parsed.Date()
+		}
+		if hasTime {
+			parsed, err := time.Parse("15:04", timeVal)
+			if err != nil {
+				return fmt.Errorf("неверный формат времени: %w", err)
+			}
+			hour, minute, _ = parsed.Clock()
+		}
+		newStart := time.Date(year, month, day, hour, minute, 0, 0, b.loc).UTC()
+		patch.StartTime = models.NewOptionalTime(&newStart)
+	}
+
+	if patch.Status != nil && *patch.Status == models.MatchStatusCanceled {
+		patch.ScoreHT = models.NewOptionalString(nil)
+		patch.ScoreFT = models.NewOptionalString(nil)
+		patch.ScoreET = models.NewOptionalString(nil)
+		patch.ScorePEN = models.NewOptionalString(nil)
+		patch.ScoreFinalUs = models.NewOptionalInt(nil)
+		patch.ScoreFinalThem = models.NewOptionalInt(nil)
+	} else if scores := st.Data["scores"]; scores != "" {
+		tokens := strings.Fields(scores)
+		if len(tokens) != 6 {
+			return fmt.Errorf("ожидалось 6 значений для счётов, получено %d", len(tokens))
+		}
+		if patch.ScoreHT, err = optionalScoreString(tokens[0]); err != nil {
+			return err
+		}
+		if patch.ScoreFT, err = optionalScoreString(tokens[1]); err != nil {
+			return err
+		}
+		if patch.ScoreET, err = optionalScoreString(tokens[2]); err != nil {
+			return err
+		}
+		if patch.ScorePEN, err = optionalScoreString(tokens[3]); err != nil {
+			return err
+		}
+		if patch.ScoreFinalUs, err = optionalScoreInt(tokens[4]); err != nil {
+			return err
+		}
+		if patch.ScoreFinalThem, err = optionalScoreInt(tokens[5]); err != nil {
+			return err
+		}
+	}
+
+	return b.svc.Matches.Update(ctx, matchID, patch)
+}
+
+func yesNoLabel(val bool) string {
+	if val {
+		return "да"
+	}
+	return "нет"
+}
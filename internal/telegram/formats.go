@@ -0,0 +1,159 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+var tournamentFormatChoices = func() []wizardChoice {
+	choices := make([]wizardChoice, 0, len(models.TournamentFormats))
+	for _, format := range models.TournamentFormats {
+		choices = append(choices, wizardChoice{Label: formatLabel(format), Value: string(format)})
+	}
+	return choices
+}()
+
+func formatLabel(format models.TournamentFormat) string {
+	switch format {
+	case models.TournamentFormatLeague:
+		return "Лига"
+	case models.TournamentFormatCup:
+		return "Кубок"
+	case models.TournamentFormatGroupPlayoff:
+		return "Группы + плей-офф"
+	case models.TournamentFormatFriendly:
+		return "Товарищеские"
+	case models.TournamentFormatFestival:
+		return "Фестиваль"
+	default:
+		return string(format)
+	}
+}
+
+// describeFormat lists the settings of a tournament format for its card.
+func describeFormat(t *models.Tournament) string {
+	config := t.FormatConfig
+	var parts []string
+	if t.Format.HasStandings() {
+		parts = append(parts, fmt.Sprintf("очки %d/%d/%d", config.PointsWin, config.PointsDraw, config.PointsLoss))
+	}
+	if t.Format == models.TournamentFormatLeague {
+		parts = append(parts, fmt.Sprintf("кругов: %d", config.Legs))
+	}
+	if t.Format == models.TournamentFormatGroupPlayoff {
+		parts = append(parts, fmt.Sprintf("групп: %d, выходят по %d", config.Groups, config.AdvancePerGroup))
+	}
+	if !t.Format.Scored() {
+		parts = append(parts, "счёт не ведётся")
+	}
+	if len(parts) == 0 {
+		return formatLabel(t.Format)
+	}
+	return fmt.Sprintf("%s (%s)", formatLabel(t.Format), strings.Join(parts, ", "))
+}
+
+// wizardFormat is the format chosen in the wizard or, when editing without
+// changing it, the current one.
+func wizardFormat(st *wizardState) models.TournamentFormat {
+	if value := st.Data["format"]; value != "" {
+		return models.TournamentFormat(value)
+	}
+	if value := st.Data["current_format"]; value != "" {
+		return models.TournamentFormat(value)
+	}
+	return models.TournamentFormatLeague
+}
+
+func formatHasStandings(st *wizardState) bool {
+	return wizardFormat(st).HasStandings()
+}
+
+func formatIsLeague(st *wizardState) bool {
+	return wizardFormat(st) == models.TournamentFormatLeague
+}
+
+func formatHasGroups(st *wizardState) bool {
+	return wizardFormat(st) == models.TournamentFormatGroupPlayoff
+}
+
+// matchHasStage limits the stage step of match wizards to tournaments with a
+// bracket.
+func matchHasStage(st *wizardState) bool {
+	return models.TournamentFormat(st.Data["tournament_format"]).HasBracket()
+}
+
+func matchScored(st *wizardState) bool {
+	return models.TournamentFormat(st.Data["tournament_format"]).Scored()
+}
+
+// parsePointsAnswer reads the points for a win, a draw and a loss: "3 1 0".
+func parsePointsAnswer(text string) (string, error) {
+	values, err := parseInts(text, 3)
+	if err != nil {
+		return "", userMessage("Введите три числа через пробел: очки за победу, ничью и поражение, например «3 1 0».")
+	}
+	if values[0] <= values[1] || values[1] < values[2] || values[2] < 0 {
+		return "", userMessage("За победу должно даваться больше, чем за ничью, а за ничью — не меньше, чем за поражение.")
+	}
+	return strings.Join(strings.Fields(text), " "), nil
+}
+
+// parseGroupsAnswer reads the number of groups and how many teams leave each
+// of them: "2 2".
+func parseGroupsAnswer(text string) (string, error) {
+	values, err := parseInts(text, 2)
+	if err != nil || values[0] < 1 || values[1] < 1 {
+		return "", userMessage("Введите два числа через пробел: количество групп и сколько команд выходят из каждой, например «2 2».")
+	}
+	return strings.Join(strings.Fields(text), " "), nil
+}
+
+func parseInts(text string, count int) ([]int, error) {
+	fields := strings.Fields(text)
+	if len(fields) != count {
+		return nil, fmt.Errorf("want %d numbers", count)
+	}
+	values := make([]int, 0, count)
+	for _, field := range fields {
+		value, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// wizardFormatConfig applies the answered settings to base. It reports
+// whether any of them was answered.
+func wizardFormatConfig(st *wizardState, base models.FormatConfig) (models.FormatConfig, bool, error) {
+	config, answered := base, false
+	if value := st.Data["points"]; value != "" {
+		points, err := parseInts(value, 3)
+		if err != nil {
+			return config, false, err
+		}
+		config.PointsWin, config.PointsDraw, config.PointsLoss = points[0], points[1], points[2]
+		answered = true
+	}
+	if value := st.Data["legs"]; value != "" {
+		legs, err := strconv.Atoi(value)
+		if err != nil {
+			return config, false, err
+		}
+		config.Legs = legs
+		answered = true
+	}
+	if value := st.Data["groups"]; value != "" {
+		groups, err := parseInts(value, 2)
+		if err != nil {
+			return config, false, err
+		}
+		config.Groups, config.AdvancePerGroup = groups[0], groups[1]
+		answered = true
+	}
+	return config, answered, nil
+}
@@ -0,0 +1,211 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+	"github.com/dynamost/telegram-bot/internal/schedule"
+	"github.com/dynamost/telegram-bot/internal/service"
+)
+
+// maxLeagueOpponents keeps a generated calendar within one preview message.
+const maxLeagueOpponents = 19
+
+var legChoices = []wizardChoice{
+	{Label: "Один круг", Value: "1"},
+	{Label: "Два круга", Value: "2"},
+}
+
+// matchdayChoices are Monday to Sunday; values are time.Weekday numbers.
+var matchdayChoices = func() []wizardChoice {
+	choices := make([]wizardChoice, 0, len(weekdayNames))
+	for i, name := range weekdayNames {
+		choices = append(choices, wizardChoice{Label: name, Value: strconv.Itoa((i + 1) % 7)})
+	}
+	return choices
+}()
+
+// parseOpponentsAnswer takes the other league teams, one per line.
+func parseOpponentsAnswer(text string) (string, error) {
+	var names []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		name := oneLine(line)
+		if name == "" || seen[strings.ToLower(name)] {
+			continue
+		}
+		seen[strings.ToLower(name)] = true
+		names = append(names, name)
+	}
+	switch {
+	case len(names) == 0:
+		return "", userMessage("Укажите хотя бы одного соперника.")
+	case len(names) > maxLeagueOpponents:
+		return "", userMessagef("Слишком много соперников: %d, максимум %d.", len(names), maxLeagueOpponents)
+	}
+	return strings.Join(names, "\n"), nil
+}
+
+// parseBlackoutsAnswer reads dates without games separated by commas or
+// lines; "28.12 - 08.01" blocks a whole period.
+func (b *Bot) parseBlackoutsAnswer(text string) (string, error) {
+	now := b.timeNow().In(b.loc)
+	var dates []string
+	for _, item := range strings.FieldsFunc(text, func(r rune) bool { return r == ',' || r == ';' || r == '\n' }) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		from, to := item, item
+		for _, sep := range []string{"–", "—", " - ", ".."} {
+			if left, right, ok := strings.Cut(item, sep); ok {
+				from, to = strings.TrimSpace(left), strings.TrimSpace(right)
+				break
+			}
+		}
+		first, err := parseHumanDate(from, now, true)
+		if err != nil {
+			return "", userMessagef("%s: %v", item, err)
+		}
+		last, err := parseHumanDate(to, now, true)
+		if err != nil {
+			return "", userMessagef("%s: %v", item, err)
+		}
+		if last.Date.Before(first.Date) || last.Date.After(first.Date.AddDate(1, 0, 0)) {
+			return "", userMessagef("%s: неверный период.", item)
+		}
+		for day := first.Date; !day.After(last.Date); day = day.AddDate(0, 0, 1) {
+			dates = append(dates, day.Format("2006-01-02"))
+		}
+	}
+	if len(dates) == 0 {
+		return "", userMessage("Укажите даты через запятую, например «31.12, 07.01» или «28.12 - 08.01».")
+	}
+	return strings.Join(dates, ","), nil
+}
+
+// leagueRound is one round of a generated calendar from the team's side.
+type leagueRound struct {
+	Number   int
+	Day      time.Time
+	Opponent string
+	Home     bool
+	// Rest is set when the team has no match in the round.
+	Rest bool
+}
+
+// leagueCalendar pairs the team with the opponents by the round-robin
+// schedule and gives every round its matchday.
+func (b *Bot) leagueCalendar(st *wizardState) ([]leagueRound, error) {
+	participants := append([]string{st.Data["team_name"]}, strings.Split(st.Data["opponents"], "\n")...)
+	legs, err := strconv.Atoi(st.Data["legs"])
+	if err != nil {
+		return nil, fmt.Errorf("legs: %w", models.ErrValidation)
+	}
+	start, err := st.datePtr("start_date", b.loc)
+	if err != nil || start == nil {
+		return nil, fmt.Errorf("start_date: %w", models.ErrValidation)
+	}
+	weekday := start.Weekday()
+	if value, ok := st.Data["weekday"]; ok {
+		day, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("weekday: %w", models.ErrValidation)
+		}
+		weekday = time.Weekday(day)
+	}
+	var blackout []time.Time
+	if value := st.Data["blackouts"]; value != "" {
+		for _, item := range strings.Split(value, ",") {
+			day, err := time.ParseInLocation("2006-01-02", item, b.loc)
+			if err != nil {
+				return nil, err
+			}
+			blackout = append(blackout, day)
+		}
+	}
+
+	rounds := schedule.RoundRobin(len(participants), legs)
+	days := schedule.Matchdays(*start, weekday, len(rounds), blackout)
+	calendar := make([]leagueRound, 0, len(rounds))
+	for i, round := range rounds {
+		entry := leagueRound{Number: i + 1, Day: days[i], Rest: true}
+		for _, pairing := range round {
+			// The team is participant 0.
+			switch {
+			case pairing.Home == 0:
+				entry.Opponent, entry.Home, entry.Rest = participants[pairing.Away], true, false
+			case pairing.Away == 0:
+				entry.Opponent, entry.Home, entry.Rest = participants[pairing.Home], false, false
+			}
+		}
+		calendar = append(calendar, entry)
+	}
+	return calendar, nil
+}
+
+func (b *Bot) leagueSummary(_ context.Context, st *wizardState) (string, error) {
+	calendar, err := b.leagueCalendar(st)
+	if err != nil {
+		return "", err
+	}
+	participants := 1 + len(strings.Split(st.Data["opponents"], "\n"))
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("Участников: %d, кругов: %s, туров: %d\n", participants, st.Data["legs"], len(calendar)))
+	if venue := st.Data["venue"]; venue != "" {
+		builder.WriteString(fmt.Sprintf("Домашнее поле: %s\n", escape(venue)))
+	}
+	builder.WriteString("\n")
+	for _, round := range calendar {
+		day := fmt.Sprintf("%s %s", weekdayNames[(int(round.Day.Weekday())+6)%7], round.Day.Format("02.01.2006"))
+		if round.Rest {
+			builder.WriteString(fmt.Sprintf("%d. %s — свободный тур\n", round.Number, day))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%d. %s %s — %s (%s)\n", round.Number, day, st.Data["time"], escape(round.Opponent), homeLabel(round.Home)))
+	}
+	return builder.String(), nil
+}
+
+func (b *Bot) finishLeagueWizard(ctx context.Context, st *wizardState) error {
+	calendar, err := b.leagueCalendar(st)
+	if err != nil {
+		return err
+	}
+	kickoff, err := time.Parse("15:04", st.Data["time"])
+	if err != nil {
+		return fmt.Errorf("time: %w", models.ErrValidation)
+	}
+	var inputs []service.CreateMatchInput
+	for _, round := range calendar {
+		if round.Rest {
+			continue
+		}
+		home := round.Home
+		input := service.CreateMatchInput{
+			TournamentID: st.id("tournament_id"),
+			TeamID:       st.id("team_id"),
+			Opponent:     round.Opponent,
+			StartTime:    time.Date(round.Day.Year(), round.Day.Month(), round.Day.Day(), kickoff.Hour(), kickoff.Minute(), 0, 0, b.loc),
+			Status:       models.MatchStatusScheduled,
+			Home:         &home,
+		}
+		if home {
+			input.Location = st.stringPtr("venue")
+		}
+		inputs = append(inputs, input)
+	}
+	_, err = b.svc.Matches.CreateMany(ctx, inputs)
+	return err
+}
+
+func homeLabel(home bool) string {
+	if home {
+		return "дома"
+	}
+	return "в гостях"
+}
@@ -0,0 +1,41 @@
+package telegram
+
+import (
+	"context"
+	"time"
+)
+
+const housekeepingInterval = 15 * time.Minute
+
+// sessionRetention is how long an untouched session, with its "back"
+// history, is kept. Wizards are closed once idle for the wizard TTL.
+const sessionRetention = 30 * 24 * time.Hour
+
+// housekeeping periodically drops expired inline keyboards, idle wizards and
+// abandoned sessions until ctx is canceled.
+func (b *Bot) housekeeping(ctx context.Context) {
+	ticker := time.NewTicker(housekeepingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := b.svc.Callbacks.Purge(ctx); err != nil {
+				b.logger.Error(err, "purge", "callback", 0, 0)
+			}
+			expired, err := b.svc.Sessions.ExpireWizards(ctx, b.timeNow().Add(-b.wizardTTL))
+			if err != nil {
+				b.logger.Error(err, "purge", "wizard", 0, 0)
+			} else if expired > 0 {
+				b.logger.Info("purge", "wizard", expired, 0, "ok")
+			}
+			removed, err := b.svc.Sessions.DeleteStale(ctx, b.timeNow().Add(-sessionRetention))
+			if err != nil {
+				b.logger.Error(err, "purge", "session", 0, 0)
+			} else if removed > 0 {
+				b.logger.Info("purge", "session", removed, 0, "ok")
+			}
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/dynamost/telegram-bot/internal/ical"
+	"github.com/dynamost/telegram-bot/internal/models"
+	"github.com/dynamost/telegram-bot/internal/service"
+)
+
+// maxUploadSize caps files accepted by wizards.
+const maxUploadSize = 1 << 20
+
+// downloadDocument fetches the content of an uploaded file.
+func (b *Bot) downloadDocument(ctx context.Context, doc *tgbotapi.Document) (string, error) {
+	if doc.FileSize > maxUploadSize {
+		return "", fmt.Errorf("файл больше %d КБ", maxUploadSize/1024)
+	}
+	url, err := b.api.GetFileDirectURL(doc.FileID)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("download: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxUploadSize+1))
+	if err != nil {
+		return "", err
+	}
+	if len(data) > maxUploadSize {
+		return "", fmt.Errorf("файл больше %d КБ", maxUploadSize/1024)
+	}
+	return string(data), nil
+}
+
+// calendarEntry is an event of an uploaded calendar as stored in the wizard.
+type calendarEntry struct {
+	UID      string
+	Start    time.Time
+	Opponent string
+	Location string
+	// Cancelled marks an event cancelled in the calendar; it cancels the
+	// match imported from it earlier.
+	Cancelled bool
+}
+
+// parseCalendarAnswer reads an .ics file and keeps its events one per line.
+// Events without a time are left out and listed after a "#" so that the
+// preview can mention them; cancelled events are kept with a trailing
+// "cancelled" field.
+func (b *Bot) parseCalendarAnswer(text string) (string, error) {
+	events, err := ical.Parse(strings.NewReader(text), b.loc)
+	if err != nil {
+		return "", userMessagef("Не удалось прочитать календарь: %v.", err)
+	}
+	var stored, skipped []string
+	seen := make(map[string]bool, len(events))
+	for _, event := range events {
+		reason := ""
+		switch {
+		case event.UID == "":
+			reason = "нет UID"
+		case seen[event.UID]:
+			reason = "повторяющийся UID"
+		case strings.TrimSpace(event.Summary) == "":
+			reason = "нет названия"
+		case event.Start.IsZero() || event.AllDay:
+			reason = "не указано время начала"
+		}
+		if reason != "" {
+			skipped = append(skipped, "#"+oneLine(event.Summary)+"\t"+reason)
+			continue
+		}
+		seen[event.UID] = true
+		fields := []string{
+			oneLine(event.UID),
+			event.Start.In(b.loc).Format("2006-01-02 15:04"),
+			oneLine(event.Summary),
+			oneLine(event.Location),
+		}
+		if event.Status == ical.StatusCancelled {
+			fields = append(fields, "cancelled")
+		}
+		stored = append(stored, strings.Join(fields, "\t"))
+	}
+	if len(stored) == 0 {
+		return "", userMessage("В календаре нет матчей, которые можно загрузить.")
+	}
+	if len(stored) > maxFixtureLines {
+		return "", userMessagef("Слишком много событий: %d, максимум %d за раз.", len(stored), maxFixtureLines)
+	}
+	return strings.Join(append(stored, skipped...), "\n"), nil
+}
+
+func oneLine(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// storedCalendar reads back the events saved by parseCalendarAnswer.
+func (st *wizardState) storedCalendar(loc *time.Location) ([]calendarEntry, []string, error) {
+	var (
+		entries []calendarEntry
+		skipped []string
+	)
+	for _, line := range strings.Split(st.Data["events"], "\n") {
+		if rest, ok := strings.CutPrefix(line, "#"); ok {
+			skipped = append(skipped, rest)
+			continue
+		}
+		parts := strings.Split(line, "\t")
+		if len(parts) != 4 && len(parts) != 5 {
+			return nil, nil, fmt.Errorf("event %q: %w", line, models.ErrValidation)
+		}
+		start, err := time.ParseInLocation("2006-01-02 15:04", parts[1], loc)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, calendarEntry{
+			UID:       parts[0],
+			Start:     start,
+			Opponent:  parts[2],
+			Location:  parts[3],
+			Cancelled: len(parts) == 5 && parts[4] == "cancelled",
+		})
+	}
+	return entries, skipped, nil
+}
+
+// calendarDiff compares the uploaded events with the matches of the team
+// imported earlier.
+type calendarDiff struct {
+	Added    []calendarEntry
+	Changed  []calendarEntry
+	Canceled []calendarEntry
+	// Restored are matches canceled by an earlier import whose events are
+	// confirmed again.
+	Restored  []calendarEntry
+	Previous  map[string]models.Match
+	Unchanged int
+	Skipped   []string
+}
+
+func (b *Bot) diffCalendar(ctx context.Context, st *wizardState) (*calendarDiff, error) {
+	entries, skipped, err := st.storedCalendar(b.loc)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := b.svc.Matches.List(ctx, st.id("tournament_id"), st.id("team_id"))
+	if err != nil {
+		return nil, err
+	}
+	diff := &calendarDiff{Previous: make(map[string]models.Match), Skipped: skipped}
+	known := make(map[string]models.Match)
+	for _, match := range matches {
+		if match.ExternalUID != nil {
+			known[*match.ExternalUID] = match
+		}
+	}
+	for _, entry := range entries {
+		match, ok := known[entry.UID]
+		switch {
+		case entry.Cancelled && !ok:
+			diff.Skipped = append(diff.Skipped, entry.Opponent+"\tотменено в календаре")
+		case entry.Cancelled && match.Status == models.MatchStatusScheduled:
+			diff.Canceled = append(diff.Canceled, entry)
+			diff.Previous[entry.UID] = match
+		case entry.Cancelled && match.Status == models.MatchStatusPlayed:
+			diff.Skipped = append(diff.Skipped, entry.Opponent+"\tотменено в календаре, но матч уже сыгран")
+		case entry.Cancelled:
+			diff.Unchanged++
+		case ok && match.Status == models.MatchStatusCanceled && match.CanceledByCalendar:
+			diff.Restored = append(diff.Restored, entry)
+			diff.Previous[entry.UID] = match
+		case !ok:
+			diff.Added = append(diff.Added, entry)
+		case !match.StartTime.Equal(entry.Start) || match.OpponentName != entry.Opponent || derefString(match.Location) != entry.Location:
+			diff.Changed = append(diff.Changed, entry)
+			diff.Previous[entry.UID] = match
+		default:
+			diff.Unchanged++
+		}
+	}
+	return diff, nil
+}
+
+func (b *Bot) calendarSummary(ctx context.Context, st *wizardState) (string, error) {
+	diff, err := b.diffCalendar(ctx, st)
+	if err != nil {
+		return "", err
+	}
+	describe := func(start time.Time, opponent, location string) string {
+		text := fmt.Sprintf("%s — %s", start.In(b.loc).Format("02.01.2006 15:04"), escape(opponent))
+		if location != "" {
+			text += fmt.Sprintf(" (%s)", escape(location))
+		}
+		return text
+	}
+	var builder strings.Builder
+	if len(diff.Added) > 0 {
+		builder.WriteString(fmt.Sprintf("Новые матчи: %d\n", len(diff.Added)))
+		for _, entry := range diff.Added {
+			builder.WriteString("➕ " + describe(entry.Start, entry.Opponent, entry.Location) + "\n")
+		}
+		builder.WriteString("\n")
+	}
+	if len(diff.Changed) > 0 {
+		builder.WriteString(fmt.Sprintf("Изменятся: %d\n", len(diff.Changed)))
+		for _, entry := range diff.Changed {
+			previous := diff.Previous[entry.UID]
+			builder.WriteString("✏️ " + describe(previous.StartTime, previous.OpponentName, derefString(previous.Location)) + "\n")
+			builder.WriteString("   → " + describe(entry.Start, entry.Opponent, entry.Location) + "\n")
+		}
+		builder.WriteString("\n")
+	}
+	if len(diff.Canceled) > 0 {
+		builder.WriteString(fmt.Sprintf("Будут отменены: %d\n", len(diff.Canceled)))
+		for _, entry := range diff.Canceled {
+			previous := diff.Previous[entry.UID]
+			builder.WriteString("❌ " + describe(previous.StartTime, previous.OpponentName, derefString(previous.Location)) + "\n")
+		}
+		builder.WriteString("\n")
+	}
+	if len(diff.Restored) > 0 {
+		builder.WriteString(fmt.Sprintf("Вернутся в расписание: %d\n", len(diff.Restored)))
+		for _, entry := range diff.Restored {
+			builder.WriteString("↩️ " + describe(entry.Start, entry.Opponent, entry.Location) + "\n")
+		}
+		builder.WriteString("\n")
+	}
+	if diff.Unchanged > 0 {
+		builder.WriteString(fmt.Sprintf("Без изменений: %d\n", diff.Unchanged))
+	}
+	if len(diff.Skipped) > 0 {
+		builder.WriteString(fmt.Sprintf("Пропущено: %d\n", len(diff.Skipped)))
+		for _, line := range diff.Skipped {
+			summary, reason, _ := strings.Cut(line, "\t")
+			builder.WriteString(fmt.Sprintf("• %s — %s\n", escape(summary), reason))
+		}
+	}
+	if len(diff.Added) == 0 && len(diff.Changed) == 0 && len(diff.Canceled) == 0 && len(diff.Restored) == 0 {
+		builder.WriteString("\nКалендарь уже загружен, менять нечего.\n")
+	}
+	return builder.String(), nil
+}
+
+func (b *Bot) finishCalendarImport(ctx context.Context, st *wizardState) error {
+	diff, err := b.diffCalendar(ctx, st)
+	if err != nil {
+		return err
+	}
+	var inputs []service.CreateMatchInput
+	var entries []calendarEntry
+	for _, group := range [][]calendarEntry{diff.Added, diff.Changed, diff.Canceled, diff.Restored} {
+		entries = append(entries, group...)
+	}
+	for _, entry := range entries {
+		uid := entry.UID
+		input := service.CreateMatchInput{
+			TournamentID: st.id("tournament_id"),
+			TeamID:       st.id("team_id"),
+			Opponent:     entry.Opponent,
+			StartTime:    entry.Start,
+			Status:       models.MatchStatusScheduled,
+			ExternalUID:  &uid,
+		}
+		if entry.Cancelled {
+			input.Status = models.MatchStatusCanceled
+		}
+		if entry.Location != "" {
+			location := entry.Location
+			input.Location = &location
+		}
+		inputs = append(inputs, input)
+	}
+	if len(inputs) == 0 {
+		return nil
+	}
+	_, _, err = b.svc.Matches.Import(ctx, inputs)
+	return err
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
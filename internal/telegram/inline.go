@@ -0,0 +1,286 @@
+package telegram
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+const (
+	inlineLimit     = 20
+	inlineCacheTime = 30
+)
+
+// handleInlineQuery answers "@bot ..." queries typed in any chat. Match,
+// result and tournament cards hold the public schedule of the club and are
+// answered to everyone; player cards carry personal data and are only built
+// for admins.
+func (b *Bot) handleInlineQuery(ctx context.Context, q *tgbotapi.InlineQuery) error {
+	if q.From == nil {
+		return nil
+	}
+	isAdmin := b.isAdmin(q.From.ID)
+	query := strings.TrimSpace(q.Query)
+	keyword, rest := splitInlineKeyword(query)
+
+	var results []interface{}
+	switch keyword {
+	case "match":
+		summaries, err := b.collectClubMatches(ctx)
+		if err != nil {
+			return err
+		}
+		results = append(results, b.inlineUpcoming(summaries, rest)...)
+	case "result":
+		summaries, err := b.collectClubMatches(ctx)
+		if err != nil {
+			return err
+		}
+		results = append(results, b.inlineResults(summaries, rest)...)
+	default:
+		if query == "" {
+			summaries, err := b.collectClubMatches(ctx)
+			if err != nil {
+				return err
+			}
+			results = append(results, b.inlineUpcoming(summaries, "")...)
+			results = append(results, b.inlineResults(summaries, "")...)
+			break
+		}
+		if isAdmin {
+			players, err := b.svc.Players.Search(ctx, query, inlineLimit)
+			if err != nil {
+				return err
+			}
+			for _, p := range players {
+				results = append(results, b.inlinePlayerCard(ctx, p))
+			}
+		}
+		tournaments, err := b.svc.Tournaments.List(ctx, nil)
+		if err != nil {
+			return err
+		}
+		needle := strings.ToLower(query)
+		var found []models.Tournament
+		for _, t := range tournaments {
+			if strings.Contains(strings.ToLower(t.Name), needle) {
+				found = append(found, t)
+			}
+		}
+		if len(found) == 0 {
+			break
+		}
+		summaries, err := b.collectClubMatches(ctx)
+		if err != nil {
+			return err
+		}
+		for _, t := range found {
+			results = append(results, b.inlineTournamentCard(t, summaries))
+		}
+	}
+	if len(results) > inlineLimit {
+		results = results[:inlineLimit]
+	}
+	_, err := b.out.Request(tgbotapi.InlineConfig{
+		InlineQueryID: q.ID,
+		Results:       results,
+		CacheTime:     inlineCacheTime,
+		IsPersonal:    true,
+	})
+	return err
+}
+
+// splitInlineKeyword recognises the leading "матч"/"результат" keywords and
+// returns the remaining text as a team filter.
+func splitInlineKeyword(query string) (string, string) {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	rest := strings.Join(fields[1:], " ")
+	switch strings.ToLower(fields[0]) {
+	case "матч", "матчи", "игра", "игры", "match":
+		return "match", rest
+	case "результат", "результаты", "итог", "счёт", "счет", "result":
+		return "result", rest
+	default:
+		return "", query
+	}
+}
+
+// collectClubMatches lists every club match with its team and tournament
+// names. It runs on each keystroke, so the number of queries does not grow
+// with the number of teams.
+func (b *Bot) collectClubMatches(ctx context.Context) ([]matchSummary, error) {
+	matches, err := b.svc.Matches.ListAll(ctx, models.MatchFilter{})
+	if err != nil {
+		return nil, err
+	}
+	tournaments, err := b.svc.Tournaments.List(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	teams, err := b.svc.Teams.ListAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tournamentsByID := make(map[int64]models.Tournament, len(tournaments))
+	for _, t := range tournaments {
+		tournamentsByID[t.ID] = t
+	}
+	teamNames := make(map[int64]string, len(teams))
+	for _, team := range teams {
+		teamNames[team.ID] = team.Name
+	}
+	summaries := make([]matchSummary, 0, len(matches))
+	for _, m := range matches {
+		summaries = append(summaries, matchSummary{
+			Match:          m,
+			TeamName:       teamNames[m.TeamID],
+			TournamentName: tournamentsByID[m.TournamentID].Name,
+			Format:         tournamentsByID[m.TournamentID].Format,
+		})
+	}
+	return summaries, nil
+}
+
+func (b *Bot) inlineUpcoming(summaries []matchSummary, teamFilter string) []interface{} {
+	now := b.timeNow()
+	var upcoming []matchSummary
+	for _, s := range summaries {
+		if s.Match.Status == models.MatchStatusScheduled && s.Match.StartTime.After(now) && matchesTeamFilter(s, teamFilter) {
+			upcoming = append(upcoming, s)
+		}
+	}
+	sort.Slice(upcoming, func(i, j int) bool {
+		return upcoming[i].Match.StartTime.Before(upcoming[j].Match.StartTime)
+	})
+	results := make([]interface{}, 0, len(upcoming))
+	for _, s := range upcoming {
+		title := fmt.Sprintf("%s • %s vs %s", s.Match.StartTime.In(b.loc).Format("02.01 15:04"), s.TeamName, s.Match.OpponentName)
+		article := tgbotapi.NewInlineQueryResultArticleMarkdown(fmt.Sprintf("m%d", s.Match.ID), title, b.matchCardText(s))
+		article.Description = s.TournamentName
+		results = append(results, article)
+	}
+	return results
+}
+
+func (b *Bot) inlineResults(summaries []matchSummary, teamFilter string) []interface{} {
+	var played []matchSummary
+	for _, s := range summaries {
+		if s.Match.Status == models.MatchStatusPlayed && matchesTeamFilter(s, teamFilter) {
+			played = append(played, s)
+		}
+	}
+	sort.Slice(played, func(i, j int) bool {
+		return played[i].Match.StartTime.After(played[j].Match.StartTime)
+	})
+	results := make([]interface{}, 0, len(played))
+	for _, s := range played {
+		title := fmt.Sprintf("%s %s %s", s.TeamName, scoreLine(s), s.Match.OpponentName)
+		article := tgbotapi.NewInlineQueryResultArticleMarkdown(fmt.Sprintf("r%d", s.Match.ID), title, b.matchCardText(s))
+		article.Description = fmt.Sprintf("%s, %s", s.Match.StartTime.In(b.loc).Format("02.01.2006"), s.TournamentName)
+		results = append(results, article)
+	}
+	return results
+}
+
+func (b *Bot) matchCardText(s matchSummary) string {
+	var builder strings.Builder
+	if s.Match.Status == models.MatchStatusPlayed {
+		builder.WriteString("*Результат матча*\n")
+		builder.WriteString(fmt.Sprintf("%s %s %s\n", escape(s.TeamName), scoreLine(s), escape(s.Match.OpponentName)))
+	} else {
+		builder.WriteString("*Матч*\n")
+		builder.WriteString(fmt.Sprintf("%s vs %s\n", escape(s.TeamName), escape(s.Match.OpponentName)))
+	}
+	builder.WriteString(fmt.Sprintf("Когда: %s\n", s.Match.StartTime.In(b.loc).Format("02.01.2006 15:04")))
+	if s.Match.Location != nil && *s.Match.Location != "" {
+		builder.WriteString(fmt.Sprintf("Место: %s\n", escape(*s.Match.Location)))
+	}
+	builder.WriteString(fmt.Sprintf("Турнир: %s\n", escape(s.TournamentName)))
+	return builder.String()
+}
+
+func (b *Bot) inlinePlayerCard(ctx context.Context, p models.Player) tgbotapi.InlineQueryResultArticle {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("*%s*\n", escape(p.FullName)))
+	description := ""
+	if p.BirthDate != nil {
+		builder.WriteString(fmt.Sprintf("Дата рождения: %s\n", p.BirthDate.Format("02.01.2006")))
+		description = fmt.Sprintf("%d г.р.", p.BirthDate.Year())
+	}
+	if p.Position != nil && *p.Position != "" {
+		builder.WriteString(fmt.Sprintf("Позиция: %s\n", escape(*p.Position)))
+		if description != "" {
+			description += ", "
+		}
+		description += *p.Position
+	}
+	if assignments, err := b.svc.Players.ListAssignments(ctx, p.ID); err == nil && len(assignments) > 0 {
+		builder.WriteString(fmt.Sprintf("Заявок в турнирах: %d\n", len(assignments)))
+	}
+	article := tgbotapi.NewInlineQueryResultArticleMarkdown(fmt.Sprintf("p%d", p.ID), p.FullName, builder.String())
+	article.Description = description
+	return article
+}
+
+func (b *Bot) inlineTournamentCard(t models.Tournament, summaries []matchSummary) tgbotapi.InlineQueryResultArticle {
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("*%s*\n", escape(t.Name)))
+	builder.WriteString(fmt.Sprintf("Статус: %s\n", t.Status))
+	if t.StartDate != nil {
+		builder.WriteString(fmt.Sprintf("Старт: %s\n", t.StartDate.Format("02.01.2006")))
+	}
+	if t.EndDate != nil {
+		builder.WriteString(fmt.Sprintf("Финиш: %s\n", t.EndDate.Format("02.01.2006")))
+	}
+	var own []matchSummary
+	for _, s := range summaries {
+		if s.Match.TournamentID == t.ID {
+			own = append(own, s)
+		}
+	}
+	if upcoming := b.nextMatches(own); len(upcoming) > 0 {
+		builder.WriteString("\n*Ближайшие матчи:*\n")
+		for _, info := range upcoming {
+			builder.WriteString(fmt.Sprintf("- %s • %s vs %s\n",
+				info.Match.StartTime.In(b.loc).Format("02.01 15:04"),
+				escape(info.TeamName),
+				escape(info.Match.OpponentName)))
+		}
+	}
+	article := tgbotapi.NewInlineQueryResultArticleMarkdown(fmt.Sprintf("t%d", t.ID), t.Name, builder.String())
+	article.Description = fmt.Sprintf("Турнир, %s", t.Status)
+	return article
+}
+
+func matchesTeamFilter(s matchSummary, filter string) bool {
+	if filter == "" {
+		return true
+	}
+	needle := strings.ToLower(filter)
+	return strings.Contains(strings.ToLower(s.TeamName), needle) ||
+		strings.Contains(strings.ToLower(s.Match.OpponentName), needle)
+}
+
+// scoreLine is the final score of a match, or a dash when there is none or
+// the tournament keeps no scores.
+func scoreLine(s matchSummary) string {
+	m := s.Match
+	if !s.Format.Scored() {
+		return "—"
+	}
+	if m.ScoreFinalUs != nil || m.ScoreFinalThem != nil {
+		return fmt.Sprintf("%d:%d", safeInt(m.ScoreFinalUs), safeInt(m.ScoreFinalThem))
+	}
+	if m.ScoreFT != nil {
+		return *m.ScoreFT
+	}
+	return "—"
+}
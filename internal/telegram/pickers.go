@@ -0,0 +1,137 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// wizardPicker selects the inline keyboard a step offers instead of plain
+// choices. The picked value is fed to the step like a typed answer, so Parse
+// and Validate still apply.
+type wizardPicker int
+
+const (
+	pickerNone wizardPicker = iota
+	// pickerDate is a month calendar; the answer is YYYY-MM-DD.
+	pickerDate
+	// pickerTime picks an hour and then minutes in 5-minute steps; the
+	// answer is HH:MM.
+	pickerTime
+)
+
+var monthNames = [...]string{
+	"Январь", "Февраль", "Март", "Апрель", "Май", "Июнь",
+	"Июль", "Август", "Сентябрь", "Октябрь", "Ноябрь", "Декабрь",
+}
+
+var weekdayNames = [...]string{"Пн", "Вт", "Ср", "Чт", "Пт", "Сб", "Вс"}
+
+// pickerKeyboard returns the picker rows for the current step. View holds
+// what the picker shows between taps: the month of the calendar or the
+// chosen hour of the time picker.
+func (b *Bot) pickerKeyboard(step wizardStep, state *wizardState, stepParam string) [][]tgbotapi.InlineKeyboardButton {
+	switch step.Picker {
+	case pickerDate:
+		return b.calendarKeyboard(b.calendarMonth(step, state), stepParam)
+	case pickerTime:
+		return timeKeyboard(state.View, stepParam)
+	}
+	return nil
+}
+
+// calendarMonth is the month the calendar opens on: the one the admin
+// navigated to, the month of the value being edited, or the current month in
+// the club time zone.
+func (b *Bot) calendarMonth(step wizardStep, state *wizardState) time.Time {
+	for _, candidate := range []string{state.View, state.Data["orig_"+step.Key]} {
+		if len(candidate) < 7 {
+			continue
+		}
+		if month, err := time.ParseInLocation("2006-01", candidate[:7], b.loc); err == nil {
+			return month
+		}
+	}
+	now := b.timeNow().In(b.loc)
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, b.loc)
+}
+
+func (b *Bot) calendarKeyboard(month time.Time, stepParam string) [][]tgbotapi.InlineKeyboardButton {
+	navigate := func(label string, to time.Time) tgbotapi.InlineKeyboardButton {
+		return tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("wz_cal|%s|m=%s", stepParam, to.Format("2006-01")))
+	}
+	keyboard := [][]tgbotapi.InlineKeyboardButton{
+		{
+			navigate("«", month.AddDate(-1, 0, 0)),
+			navigate("‹", month.AddDate(0, -1, 0)),
+			tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%s %d", monthNames[month.Month()-1], month.Year()), "wz_noop"),
+			navigate("›", month.AddDate(0, 1, 0)),
+			navigate("»", month.AddDate(1, 0, 0)),
+		},
+	}
+	header := make([]tgbotapi.InlineKeyboardButton, 0, len(weekdayNames))
+	for _, name := range weekdayNames {
+		header = append(header, tgbotapi.NewInlineKeyboardButtonData(name, "wz_noop"))
+	}
+	keyboard = append(keyboard, header)
+
+	now := b.timeNow().In(b.loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, b.loc)
+	// Weeks start on Monday.
+	offset := (int(month.Weekday()) + 6) % 7
+	days := month.AddDate(0, 1, -1).Day()
+	var row []tgbotapi.InlineKeyboardButton
+	for i := 0; i < offset; i++ {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(" ", "wz_noop"))
+	}
+	for day := 1; day <= days; day++ {
+		date := time.Date(month.Year(), month.Month(), day, 0, 0, 0, 0, b.loc)
+		label := strconv.Itoa(day)
+		if date.Equal(today) {
+			label = "•" + label
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("wz_pick|%s|v=%s", stepParam, date.Format("2006-01-02"))))
+		if len(row) == len(weekdayNames) {
+			keyboard = append(keyboard, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		for len(row) < len(weekdayNames) {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(" ", "wz_noop"))
+		}
+		keyboard = append(keyboard, row)
+	}
+	return keyboard
+}
+
+// timeKeyboard shows the hours first and the minutes of the chosen hour
+// after that.
+func timeKeyboard(hour string, stepParam string) [][]tgbotapi.InlineKeyboardButton {
+	var keyboard [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	flush := func(width int) {
+		if len(row) == width {
+			keyboard = append(keyboard, row)
+			row = nil
+		}
+	}
+	if h, err := strconv.Atoi(hour); err != nil || h < 0 || h > 23 {
+		for h := 0; h < 24; h++ {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("%02d", h), fmt.Sprintf("wz_hour|%s|h=%02d", stepParam, h)))
+			flush(6)
+		}
+		return keyboard
+	}
+	for minute := 0; minute < 60; minute += 5 {
+		clock := fmt.Sprintf("%s:%02d", hour, minute)
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(clock, fmt.Sprintf("wz_pick|%s|v=%s", stepParam, clock)))
+		flush(4)
+	}
+	keyboard = append(keyboard, []tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardButtonData("◀ Другой час", fmt.Sprintf("wz_hour|%s|h=", stepParam)),
+	})
+	return keyboard
+}
@@ -0,0 +1,322 @@
+package telegram
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+// describeRegulations sums the match rules up in one line for the
+// tournament card.
+func describeRegulations(r *models.Regulations) string {
+	starters := fmt.Sprintf("в старте %d", r.MaxStarters)
+	if r.MinStarters > 1 {
+		starters += fmt.Sprintf(" (минимум %d)", r.MinStarters)
+	}
+	parts := []string{fmt.Sprintf("%d×%d мин", r.Periods, r.PeriodMinutes), starters}
+	if r.MaxBench != nil {
+		parts = append(parts, fmt.Sprintf("запасных до %d", *r.MaxBench))
+	}
+	if r.MaxSubs != nil {
+		parts = append(parts, fmt.Sprintf("замен до %d", *r.MaxSubs))
+	}
+	if r.RollingSubs {
+		parts = append(parts, "обратные замены")
+	}
+	if window := describeRosterWindow(r); window != "" {
+		parts = append(parts, "заявка "+window)
+	}
+	if size := describeSquadSize(r); size != "" {
+		parts = append(parts, "игроков в заявке "+size)
+	}
+	if ages := describeAgeLimits(r); ages != "" {
+		parts = append(parts, ages)
+	}
+	if r.OneTeamPerPlayer {
+		parts = append(parts, "игрок только за одну команду")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// describeAgeLimits names the eligible birth years and the overage places.
+func describeAgeLimits(r *models.Regulations) string {
+	var years string
+	switch {
+	case r.BornFrom != nil && r.BornTo != nil && *r.BornFrom == *r.BornTo:
+		years = fmt.Sprintf("%d г.р.", *r.BornFrom)
+	case r.BornFrom != nil && r.BornTo != nil:
+		years = fmt.Sprintf("%d–%d г.р.", *r.BornFrom, *r.BornTo)
+	case r.BornFrom != nil:
+		years = fmt.Sprintf("%d г.р. и младше", *r.BornFrom)
+	case r.BornTo != nil:
+		years = fmt.Sprintf("%d г.р. и старше", *r.BornTo)
+	default:
+		return ""
+	}
+	if r.MaxOverage > 0 {
+		years += fmt.Sprintf(", старше возраста до %d", r.MaxOverage)
+	}
+	return years
+}
+
+// ageCategory is the youth category a player born in birthYear plays in
+// during the season: U12 in 2026 for those born in 2015.
+func ageCategory(birthYear, season int) string {
+	return fmt.Sprintf("U%d", season-birthYear+1)
+}
+
+// playerAge is the age in full years on the given day.
+func playerAge(birthDate, on time.Time) int {
+	age := on.Year() - birthDate.Year()
+	if on.Month() < birthDate.Month() || (on.Month() == birthDate.Month() && on.Day() < birthDate.Day()) {
+		age--
+	}
+	return age
+}
+
+func describeRosterWindow(r *models.Regulations) string {
+	switch {
+	case r.RosterOpens != nil && r.RosterCloses != nil:
+		return fmt.Sprintf("%s–%s", r.RosterOpens.Format("02.01.2006"), r.RosterCloses.Format("02.01.2006"))
+	case r.RosterOpens != nil:
+		return "с " + r.RosterOpens.Format("02.01.2006")
+	case r.RosterCloses != nil:
+		return "до " + r.RosterCloses.Format("02.01.2006")
+	}
+	return ""
+}
+
+func describeSquadSize(r *models.Regulations) string {
+	switch {
+	case r.MinSquad != nil && r.MaxSquad != nil:
+		return fmt.Sprintf("от %d до %d", *r.MinSquad, *r.MaxSquad)
+	case r.MinSquad != nil:
+		return fmt.Sprintf("от %d", *r.MinSquad)
+	case r.MaxSquad != nil:
+		return fmt.Sprintf("до %d", *r.MaxSquad)
+	}
+	return ""
+}
+
+// rosterWindowStatus describes the registration window as of now.
+func rosterWindowStatus(r *models.Regulations, now time.Time) string {
+	switch r.RosterWindowOn(now) {
+	case models.RosterWindowNotOpened:
+		return "откроется " + r.RosterOpens.Format("02.01.2006")
+	case models.RosterWindowClosed:
+		return "закрыта с " + r.RosterCloses.AddDate(0, 0, 1).Format("02.01.2006")
+	}
+	if r.RosterCloses != nil {
+		return "открыта до " + r.RosterCloses.Format("02.01.2006") + " включительно"
+	}
+	return "открыта"
+}
+
+// parsePeriodsAnswer reads the number and length of periods: "2x25",
+// "2 25" or "4х12".
+func parsePeriodsAnswer(text string) (string, error) {
+	values, err := parseInts(strings.NewReplacer("x", " ", "X", " ", "х", " ", "Х", " ", "*", " ", "×", " ").Replace(text), 2)
+	if err != nil || values[0] < 1 || values[0] > 4 || values[1] < 1 || values[1] > 60 {
+		return "", userMessage("Укажите количество таймов и их длительность в минутах, например «2x25».")
+	}
+	return fmt.Sprintf("%d %d", values[0], values[1]), nil
+}
+
+// parseSquadAnswer reads the players in the starting lineup and the fewest a
+// match can be played with: "11 7".
+func parseSquadAnswer(text string) (string, error) {
+	values, err := parseInts(text, 2)
+	if err != nil || values[0] < 1 || values[0] > 11 || values[1] < 1 || values[1] > values[0] {
+		return "", userMessage("Введите два числа через пробел: игроков в старте и минимум игроков для матча, например «11 7».")
+	}
+	return fmt.Sprintf("%d %d", values[0], values[1]), nil
+}
+
+// parseSquadSizeAnswer reads the fewest and most players of a team roster;
+// "-" leaves a bound open: "12 25", "- 25".
+func parseSquadSizeAnswer(text string) (string, error) {
+	const hint = "Введите минимум и максимум игроков через пробел, например «12 25»; «-» вместо числа — без ограничения."
+	fields := strings.Fields(text)
+	if len(fields) != 2 {
+		return "", userMessage(hint)
+	}
+	bounds := make([]int, 2)
+	for i, field := range fields {
+		if field == "-" {
+			continue
+		}
+		value, err := strconv.Atoi(field)
+		if err != nil || value < 1 {
+			return "", userMessage(hint)
+		}
+		bounds[i] = value
+	}
+	if bounds[0] > 0 && bounds[1] > 0 && bounds[0] > bounds[1] {
+		return "", userMessage("Минимум больше максимума.")
+	}
+	return fields[0] + " " + fields[1], nil
+}
+
+// parseAgeAnswer reads the eligible birth years: "2014-2015", "2014" for 2014
+// and younger, or a category such as "U12" for this season.
+func (b *Bot) parseAgeAnswer(text string) (string, error) {
+	const hint = "Укажите годы рождения, например «2014-2015», «2014» (2014 г.р. и младше) или категорию «U12»."
+	text = strings.TrimSpace(text)
+	if rest := strings.TrimLeft(text, "UuУу"); rest != text {
+		age, err := strconv.Atoi(rest)
+		if err != nil || age < 5 || age > 23 {
+			return "", userMessage(hint)
+		}
+		return fmt.Sprintf("%d-", b.timeNow().In(b.loc).Year()-age+1), nil
+	}
+	from, to, _ := strings.Cut(strings.NewReplacer("–", "-", "—", "-").Replace(text), "-")
+	fromYear, err := strconv.Atoi(strings.TrimSpace(from))
+	if err != nil || fromYear < 1900 {
+		return "", userMessage(hint)
+	}
+	if to = strings.TrimSpace(to); to == "" {
+		return fmt.Sprintf("%d-", fromYear), nil
+	}
+	toYear, err := strconv.Atoi(to)
+	if err != nil || toYear < fromYear {
+		return "", userMessage(hint)
+	}
+	return fmt.Sprintf("%d-%d", fromYear, toYear), nil
+}
+
+// ageLimited reports whether the regulations being edited limit birth years,
+// so that overage places make sense.
+func ageLimited(st *wizardState) bool {
+	if value, answered := st.Data["ages"]; answered {
+		return value != ""
+	}
+	return st.Data["orig_ages"] != ""
+}
+
+func parseLimitAnswer(text string) (string, error) {
+	limit, err := strconv.Atoi(text)
+	if err != nil || limit < 0 {
+		return "", userMessage("Введите целое число не меньше нуля.")
+	}
+	return strconv.Itoa(limit), nil
+}
+
+// regulationsData fills the "orig_" values of the regulations wizard.
+func regulationsData(r *models.Regulations, data map[string]string) {
+	data["orig_periods"] = fmt.Sprintf("%dx%d", r.Periods, r.PeriodMinutes)
+	data["orig_squad"] = fmt.Sprintf("%d %d", r.MaxStarters, r.MinStarters)
+	if r.MaxBench != nil {
+		data["orig_bench"] = strconv.Itoa(*r.MaxBench)
+	}
+	if r.MaxSubs != nil {
+		data["orig_subs"] = strconv.Itoa(*r.MaxSubs)
+	}
+	data["orig_rolling"] = yesNoLabel(r.RollingSubs)
+	if r.RosterOpens != nil {
+		data["orig_roster_opens"] = r.RosterOpens.Format("2006-01-02")
+	}
+	if r.RosterCloses != nil {
+		data["orig_roster_closes"] = r.RosterCloses.Format("2006-01-02")
+	}
+	if r.MinSquad != nil || r.MaxSquad != nil {
+		data["orig_squad_size"] = squadBound(r.MinSquad) + " " + squadBound(r.MaxSquad)
+	}
+	data["orig_one_team"] = yesNoLabel(r.OneTeamPerPlayer)
+	if r.HasAgeLimits() {
+		data["orig_ages"] = describeAgeLimits(&models.Regulations{BornFrom: r.BornFrom, BornTo: r.BornTo})
+		data["orig_overage"] = strconv.Itoa(r.MaxOverage)
+	}
+}
+
+func squadBound(value *int) string {
+	if value == nil {
+		return "-"
+	}
+	return strconv.Itoa(*value)
+}
+
+// wizardRegulations applies the answered steps to base. A cleared limit or
+// date removes it. It reports whether any step was answered.
+func wizardRegulations(st *wizardState, base models.Regulations, loc *time.Location) (models.Regulations, bool, error) {
+	regulations, answered := base, false
+	if value := st.Data["periods"]; value != "" {
+		values, err := parseInts(value, 2)
+		if err != nil {
+			return regulations, false, err
+		}
+		regulations.Periods, regulations.PeriodMinutes = values[0], values[1]
+		answered = true
+	}
+	if value := st.Data["squad"]; value != "" {
+		values, err := parseInts(value, 2)
+		if err != nil {
+			return regulations, false, err
+		}
+		regulations.MaxStarters, regulations.MinStarters = values[0], values[1]
+		answered = true
+	}
+	if _, ok := st.Data["bench"]; ok {
+		regulations.MaxBench = st.intPtr("bench")
+		answered = true
+	}
+	if _, ok := st.Data["subs"]; ok {
+		regulations.MaxSubs = st.intPtr("subs")
+		answered = true
+	}
+	if rolling := st.boolPtr("rolling"); rolling != nil {
+		regulations.RollingSubs = *rolling
+		answered = true
+	}
+	for key, field := range map[string]**time.Time{"roster_opens": &regulations.RosterOpens, "roster_closes": &regulations.RosterCloses} {
+		if _, ok := st.Data[key]; !ok {
+			continue
+		}
+		date, err := st.datePtr(key, loc)
+		if err != nil {
+			return regulations, false, err
+		}
+		*field = date
+		answered = true
+	}
+	if value, ok := st.Data["squad_size"]; ok {
+		regulations.MinSquad, regulations.MaxSquad = nil, nil
+		if fields := strings.Fields(value); len(fields) == 2 {
+			if bound, err := strconv.Atoi(fields[0]); err == nil {
+				regulations.MinSquad = &bound
+			}
+			if bound, err := strconv.Atoi(fields[1]); err == nil {
+				regulations.MaxSquad = &bound
+			}
+		}
+		answered = true
+	}
+	if value, ok := st.Data["ages"]; ok {
+		regulations.BornFrom, regulations.BornTo = nil, nil
+		if value == "" {
+			regulations.MaxOverage = 0
+		}
+		from, to, _ := strings.Cut(value, "-")
+		if year, err := strconv.Atoi(from); err == nil {
+			regulations.BornFrom = &year
+		}
+		if year, err := strconv.Atoi(to); err == nil {
+			regulations.BornTo = &year
+		}
+		answered = true
+	}
+	if oneTeam := st.boolPtr("one_team"); oneTeam != nil {
+		regulations.OneTeamPerPlayer = *oneTeam
+		answered = true
+	}
+	if _, ok := st.Data["overage"]; ok {
+		regulations.MaxOverage = 0
+		if overage := st.intPtr("overage"); overage != nil {
+			regulations.MaxOverage = *overage
+		}
+		answered = true
+	}
+	return regulations, answered, nil
+}
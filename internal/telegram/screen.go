@@ -0,0 +1,94 @@
+package telegram
+
+import (
+	"context"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+// liveScreen is the menu message an admin currently has in a chat; admins
+// sharing a group have one each. Editable is false
+// once anything else has been posted below it, so that the next screen is sent
+// at the bottom of the chat instead of rewriting a message out of sight.
+type liveScreen struct {
+	MessageID int
+	Editable  bool
+}
+
+// render shows a menu screen. The live screen of the chat is edited in place
+// when possible; otherwise a new message is sent and the previous screen is
+// deleted, so that a chat keeps a single live menu.
+func (b *Bot) render(ctx context.Context, chatID int64, text string, markup tgbotapi.InlineKeyboardMarkup) error {
+	if err := b.tokenizeKeyboard(ctx, &markup); err != nil {
+		return err
+	}
+	key := sessionKeyFor(ctx, chatID)
+	live, ok := b.liveScreen(key)
+	if ok && live.Editable {
+		edit := tgbotapi.NewEditMessageText(chatID, live.MessageID, text)
+		edit.ParseMode = "Markdown"
+		if len(markup.InlineKeyboard) > 0 {
+			edit.ReplyMarkup = &markup
+		}
+		_, err := b.out.Send(edit)
+		if err == nil || isNotModified(err) {
+			return nil
+		}
+		// The message may be too old to edit or already deleted: fall back
+		// to a fresh one.
+	}
+
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = "Markdown"
+	if len(markup.InlineKeyboard) > 0 {
+		msg.ReplyMarkup = markup
+	}
+	sent, err := b.out.Send(msg)
+	if err != nil {
+		return err
+	}
+	b.setScreen(key, sent.MessageID)
+	return nil
+}
+
+// detachScreen is called when a message is posted below the live screens of
+// a chat. The screens stay known so that they are cleaned up by the next
+// render.
+func (b *Bot) detachScreen(chatID int64) {
+	b.screenMu.Lock()
+	for key, live := range b.screens {
+		if key.ChatID == chatID {
+			live.Editable = false
+			b.screens[key] = live
+		}
+	}
+	b.screenMu.Unlock()
+}
+
+func (b *Bot) liveScreen(key models.SessionKey) (liveScreen, bool) {
+	b.screenMu.Lock()
+	defer b.screenMu.Unlock()
+	live, ok := b.screens[key]
+	return live, ok
+}
+
+// setScreen makes messageID the admin's live screen, for example the message
+// whose button was pressed. A different screen left over from earlier is
+// removed. A message taken over from another admin in the group is no longer
+// theirs.
+func (b *Bot) setScreen(key models.SessionKey, messageID int) {
+	b.screenMu.Lock()
+	previous, ok := b.screens[key]
+	for other, live := range b.screens {
+		if other != key && other.ChatID == key.ChatID && live.MessageID == messageID {
+			delete(b.screens, other)
+		}
+	}
+	b.screens[key] = liveScreen{MessageID: messageID, Editable: true}
+	b.screenMu.Unlock()
+	if ok && previous.MessageID != messageID {
+		_, _ = b.out.Request(tgbotapi.NewDeleteMessage(key.ChatID, previous.MessageID))
+	}
+}
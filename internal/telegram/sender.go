@@ -0,0 +1,191 @@
+package telegram
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/dynamost/telegram-bot/internal/repository"
+)
+
+// Telegram allows roughly 30 messages per second overall, one message per
+// second in a private chat and 20 messages per minute in a group.
+const (
+	globalSendInterval  = time.Second / 30
+	privateSendInterval = time.Second
+	groupSendInterval   = 3 * time.Second
+	chatBurst           = 3
+	maxSendAttempts     = 4
+	baseSendBackoff     = 500 * time.Millisecond
+	maxChatSlots        = 1000
+)
+
+// sender is the single path for outgoing API calls. It spaces calls per chat
+// and globally, honours retry_after from 429 responses, retries transient
+// failures with exponential backoff and reports final errors to the logger.
+type sender struct {
+	api    *tgbotapi.BotAPI
+	logger repository.Logger
+	sleep  func(time.Duration)
+	now    func() time.Time
+
+	mu         sync.Mutex
+	nextGlobal time.Time
+	nextChat   map[int64]time.Time
+}
+
+func newSender(api *tgbotapi.BotAPI, logger repository.Logger) *sender {
+	return &sender{
+		api:      api,
+		logger:   logger,
+		sleep:    time.Sleep,
+		now:      time.Now,
+		nextChat: make(map[int64]time.Time),
+	}
+}
+
+// Send delivers a message-producing request (send, edit, document).
+func (s *sender) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	chatID := chattableChatID(c)
+	var msg tgbotapi.Message
+	err := s.do(chatID, !postsMessage(c), func() error {
+		var err error
+		msg, err = s.api.Send(c)
+		return err
+	})
+	return msg, err
+}
+
+// Request performs calls that do not produce a message, such as callback
+// answers, inline answers and deletions.
+func (s *sender) Request(c tgbotapi.Chattable) (*tgbotapi.APIResponse, error) {
+	var resp *tgbotapi.APIResponse
+	err := s.do(0, true, func() error {
+		var err error
+		resp, err = s.api.Request(c)
+		return err
+	})
+	return resp, err
+}
+
+// do runs the call with retries. Calls that are not idempotent are only
+// repeated when Telegram reported that it did not handle them.
+func (s *sender) do(chatID int64, idempotent bool, call func() error) error {
+	var err error
+	for attempt := 0; attempt < maxSendAttempts; attempt++ {
+		s.wait(chatID)
+		err = call()
+		if err == nil {
+			return nil
+		}
+		delay, retry := retryDelay(err, attempt, idempotent)
+		if !retry || attempt == maxSendAttempts-1 {
+			break
+		}
+		s.sleep(delay)
+	}
+	if !isNotModified(err) {
+		s.logger.Error(err, "send", "chat", chatID, 0)
+	}
+	return err
+}
+
+// wait reserves the next free slot for the chat and the bot as a whole and
+// sleeps until it comes.
+func (s *sender) wait(chatID int64) {
+	s.mu.Lock()
+	now := s.now()
+	at := now
+	if s.nextGlobal.After(at) {
+		at = s.nextGlobal
+	}
+	if chatID != 0 {
+		// Generic cell rate algorithm: a short burst goes out immediately,
+		// a longer one is spread out to the chat interval.
+		interval := chatSendInterval(chatID)
+		theoretical := s.nextChat[chatID]
+		if theoretical.Before(now) {
+			theoretical = now
+		}
+		if allowed := theoretical.Add(-(chatBurst - 1) * interval); allowed.After(at) {
+			at = allowed
+		}
+		if len(s.nextChat) > maxChatSlots {
+			for id, next := range s.nextChat {
+				if next.Before(now) {
+					delete(s.nextChat, id)
+				}
+			}
+		}
+		s.nextChat[chatID] = theoretical.Add(interval)
+	}
+	s.nextGlobal = at.Add(globalSendInterval)
+	s.mu.Unlock()
+
+	if delay := at.Sub(now); delay > 0 {
+		s.sleep(delay)
+	}
+}
+
+func chatSendInterval(chatID int64) time.Duration {
+	if chatID < 0 {
+		return groupSendInterval
+	}
+	return privateSendInterval
+}
+
+// retryDelay decides whether a failed call is worth repeating. A network
+// error or a server error may come after the message was posted, so
+// non-idempotent calls are not repeated on them.
+func retryDelay(err error, attempt int, idempotent bool) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.RetryAfter > 0:
+			return time.Duration(apiErr.RetryAfter) * time.Second, true
+		case apiErr.Code >= 500:
+			return baseSendBackoff << attempt, idempotent
+		default:
+			return 0, false
+		}
+	}
+	// Network errors and undecodable responses.
+	return baseSendBackoff << attempt, idempotent
+}
+
+// postsMessage reports whether the call posts a new message, which would be
+// duplicated if repeated after it went through.
+func postsMessage(c tgbotapi.Chattable) bool {
+	switch c.(type) {
+	case tgbotapi.MessageConfig, tgbotapi.DocumentConfig, tgbotapi.PhotoConfig:
+		return true
+	default:
+		return false
+	}
+}
+
+func isNotModified(err error) bool {
+	var apiErr *tgbotapi.Error
+	return errors.As(err, &apiErr) && apiErr.Code == 400 &&
+		strings.Contains(apiErr.Message, "message is not modified")
+}
+
+func chattableChatID(c tgbotapi.Chattable) int64 {
+	switch v := c.(type) {
+	case tgbotapi.MessageConfig:
+		return v.ChatID
+	case tgbotapi.EditMessageTextConfig:
+		return v.ChatID
+	case tgbotapi.EditMessageReplyMarkupConfig:
+		return v.ChatID
+	case tgbotapi.DocumentConfig:
+		return v.ChatID
+	case tgbotapi.PhotoConfig:
+		return v.ChatID
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,731 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/dynamost/telegram-bot/internal/models"
+)
+
+// Words understood by every wizard step.
+const (
+	wizardSkipWord  = "-"
+	wizardClearWord = "удалить"
+	wizardBackWord  = "назад"
+)
+
+// defaultWizardTTL is how long an untouched wizard stays open.
+const defaultWizardTTL = time.Hour
+
+// errWizardExpired is returned by loadWizard when the open wizard has been
+// idle for longer than the TTL. The wizard is closed by then.
+var errWizardExpired = errors.New("wizard expired")
+
+type wizardState struct {
+	Flow string            `json:"flow"`
+	Step int               `json:"step"`
+	Data map[string]string `json:"data"`
+	// View is what the picker of the current step shows between taps, for
+	// example the calendar month.
+	View string `json:"view,omitempty"`
+	// Note is shown above the prompt until the next answer, for example how a
+	// typed date was understood.
+	Note string `json:"note,omitempty"`
+	// Pending is a wizard the admin tried to start while this one was
+	// unfinished; it waits for the "continue / start over" answer.
+	Pending *wizardStart `json:"pending,omitempty"`
+	// Touched is when the admin last worked with the wizard. The TTL counts
+	// from it, since browsing menus also saves the session.
+	Touched time.Time `json:"touched,omitempty"`
+}
+
+type wizardStart struct {
+	Flow string            `json:"flow"`
+	Data map[string]string `json:"data,omitempty"`
+}
+
+// wizardChoice is an inline button offered by a step. Tapping it is the same
+// as typing Value.
+type wizardChoice struct {
+	Label string
+	Value string
+}
+
+// wizardStep is one question of a wizard. The answer is stored in
+// wizardState.Data under Key: a missing key means the step was skipped, an
+// empty value means the field is to be cleared.
+type wizardStep struct {
+	Key   string
+	Label string
+	// Prompt builds the question. Hints for skipping, clearing and going
+	// back are appended by the engine.
+	Prompt func(st *wizardState) string
+	// Parse normalises a typed answer; its userMessage is shown to the admin
+	// as is.
+	// Without Parse any non-empty text is accepted.
+	Parse func(text string) (string, error)
+	// Validate checks the parsed value against earlier answers.
+	Validate func(st *wizardState, value string) error
+	Choices  []wizardChoice
+	Picker   wizardPicker
+	// Clock names the time step that a time typed together with the date
+	// ("сб 10:00") answers as well.
+	Clock string
+	// Document steps also take an uploaded file; its text is the answer.
+	Document bool
+	// When limits the step to some earlier answers; the step is passed over
+	// otherwise.
+	When func(st *wizardState) bool
+	// Optional steps accept "-" to skip; Clearable steps accept "удалить" to
+	// clear the field (and "-" as well when they are not optional).
+	Optional  bool
+	Clearable bool
+}
+
+// wizardFlow is a declarative wizard: a list of steps, a summary with a
+// confirm button when there is more than one step, and the action to run.
+type wizardFlow struct {
+	Title string
+	// Edit flows change an existing record, so a skipped step keeps the
+	// current value.
+	Edit  bool
+	Steps []wizardStep
+	// Finish applies the answers. On error the wizard stays open so that the
+	// admin can correct them.
+	Finish func(ctx context.Context, st *wizardState) error
+	// Done shows the screen to return to after a successful Finish.
+	Done func(ctx context.Context, chatID int64, st *wizardState) error
+	// Summary replaces the list of answers on the confirmation screen. A flow
+	// with a Summary is confirmed even when it has a single step.
+	Summary func(ctx context.Context, st *wizardState) (string, error)
+	Success string
+	Failure string
+}
+
+func (s wizardStep) applies(st *wizardState) bool {
+	return s.When == nil || s.When(st)
+}
+
+func (f *wizardFlow) confirms() bool {
+	return len(f.Steps) > 1 || f.Summary != nil
+}
+
+// startWizard opens a wizard. If another one is unfinished the admin is asked
+// whether to continue it or to start the new one.
+func (b *Bot) startWizard(ctx context.Context, key models.SessionKey, flowName string, data map[string]string) error {
+	if data == nil {
+		data = make(map[string]string)
+	}
+	current, err := b.loadWizard(ctx, key)
+	if err != nil && !errors.Is(err, errWizardExpired) {
+		return err
+	}
+	if current != nil {
+		current.Pending = &wizardStart{Flow: flowName, Data: data}
+		if err := b.saveWizard(ctx, key, current); err != nil {
+			return err
+		}
+		return b.renderWizardConflict(ctx, key.ChatID, current)
+	}
+	return b.openWizard(ctx, key, flowName, data)
+}
+
+func (b *Bot) openWizard(ctx context.Context, key models.SessionKey, flowName string, data map[string]string) error {
+	state := &wizardState{Flow: flowName, Data: data}
+	if err := b.saveWizard(ctx, key, state); err != nil {
+		return err
+	}
+	return b.promptWizard(ctx, key.ChatID, state)
+}
+
+// loadWizard restores the admin's session and returns the open wizard, if
+// any. A wizard idle for longer than the TTL is closed and reported with
+// errWizardExpired.
+func (b *Bot) loadWizard(ctx context.Context, key models.SessionKey) (*wizardState, error) {
+	state := &wizardState{}
+	var navState []models.NavigationEntry
+	stored, err := b.svc.Sessions.Load(ctx, key, state, &navState)
+	if err != nil {
+		return nil, err
+	}
+	b.restoreNav(key, navState)
+	if stored == nil || state.Flow == "" {
+		return nil, nil
+	}
+	if _, ok := b.flows[state.Flow]; !ok {
+		return nil, nil
+	}
+	touched := state.Touched
+	if touched.IsZero() {
+		touched = stored.UpdatedAt
+	}
+	if b.timeNow().Sub(touched) > b.wizardTTL {
+		if err := b.endWizard(ctx, key); err != nil {
+			return nil, err
+		}
+		return nil, errWizardExpired
+	}
+	if state.Data == nil {
+		state.Data = make(map[string]string)
+	}
+	return state, nil
+}
+
+// saveWizard stores the wizard as just worked with.
+func (b *Bot) saveWizard(ctx context.Context, key models.SessionKey, state *wizardState) error {
+	state.Touched = b.timeNow()
+	return b.saveSession(ctx, key, &state.Flow, state)
+}
+
+// endWizard closes the wizard but keeps the navigation history.
+func (b *Bot) endWizard(ctx context.Context, key models.SessionKey) error {
+	return b.saveSession(ctx, key, nil, nil)
+}
+
+func (b *Bot) cancelWizard(ctx context.Context, key models.SessionKey) error {
+	if err := b.endWizard(ctx, key); err != nil {
+		return err
+	}
+	b.sendSimple(key.ChatID, "Действие отменено.")
+	return nil
+}
+
+// handleWizardInput processes a text message sent while a wizard is open.
+func (b *Bot) handleWizardInput(ctx context.Context, key models.SessionKey, state *wizardState, text string) error {
+	flow := b.flows[state.Flow]
+	text = strings.TrimSpace(text)
+	// Answering the current step settles a pending conflict in its favour.
+	state.Pending = nil
+	if strings.EqualFold(text, wizardBackWord) {
+		return b.wizardBack(ctx, key, state)
+	}
+	if state.Step >= len(flow.Steps) {
+		yes, ok := parseYesNo(text)
+		switch {
+		case ok && yes:
+			return b.finishWizard(ctx, key, state)
+		case ok:
+			return b.cancelWizard(ctx, key)
+		}
+		b.sendSimple(key.ChatID, "Ответьте 'да', чтобы сохранить, 'нет', чтобы отменить, или 'назад'.")
+		return b.promptWizard(ctx, key.ChatID, state)
+	}
+	return b.answerWizard(ctx, key, state, text)
+}
+
+// handleWizardDocument processes a file sent while a wizard is open.
+func (b *Bot) handleWizardDocument(ctx context.Context, key models.SessionKey, state *wizardState, doc *tgbotapi.Document) error {
+	flow := b.flows[state.Flow]
+	if state.Step >= len(flow.Steps) || !flow.Steps[state.Step].Document {
+		b.sendSimple(key.ChatID, "Сейчас файл не нужен, ответьте текстом.")
+		return b.promptWizard(ctx, key.ChatID, state)
+	}
+	text, err := b.downloadDocument(ctx, doc)
+	if err != nil {
+		b.sendSimple(key.ChatID, "Не удалось получить файл: "+escape(err.Error()))
+		return b.promptWizard(ctx, key.ChatID, state)
+	}
+	state.Pending = nil
+	return b.answerWizard(ctx, key, state, text)
+}
+
+// handleWizardCallback processes the buttons under wizard prompts. Every
+// button carries the step it was shown for, so taps on an outdated prompt are
+// ignored.
+func (b *Bot) handleWizardCallback(ctx context.Context, cb *tgbotapi.CallbackQuery, payload *callbackPayload) error {
+	key := models.SessionKey{AdminID: cb.From.ID, ChatID: cb.Message.Chat.ID}
+	state, err := b.loadWizard(ctx, key)
+	if err != nil && !errors.Is(err, errWizardExpired) {
+		return err
+	}
+	if state == nil {
+		_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, "Ввод уже завершён."))
+		return nil
+	}
+	switch payload.Action {
+	case "wz_noop":
+		_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, ""))
+		return nil
+	case "wz_cancel":
+		_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, ""))
+		return b.cancelWizard(ctx, key)
+	case "wz_resume", "wz_restart":
+		pending := state.Pending
+		if pending == nil {
+			_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, "Вопрос уже решён."))
+			return nil
+		}
+		_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, ""))
+		if payload.Action == "wz_restart" {
+			return b.openWizard(ctx, key, pending.Flow, pending.Data)
+		}
+		state.Pending = nil
+		if err := b.saveWizard(ctx, key, state); err != nil {
+			return err
+		}
+		return b.promptWizard(ctx, key.ChatID, state)
+	}
+	if step, err := strconv.Atoi(payload.Params["s"]); err != nil || step != state.Step {
+		_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, "Этот шаг уже пройден."))
+		return nil
+	}
+	_, _ = b.out.Request(tgbotapi.NewCallback(cb.ID, ""))
+	switch payload.Action {
+	case "wz_pick":
+		return b.answerWizard(ctx, key, state, payload.Params["v"])
+	case "wz_skip":
+		return b.answerWizard(ctx, key, state, wizardSkipWord)
+	case "wz_clear":
+		return b.answerWizard(ctx, key, state, wizardClearWord)
+	case "wz_back":
+		return b.wizardBack(ctx, key, state)
+	case "wz_confirm":
+		return b.finishWizard(ctx, key, state)
+	case "wz_cal", "wz_hour":
+		state.View = payload.Params["m"]
+		if payload.Action == "wz_hour" {
+			state.View = payload.Params["h"]
+		}
+		if err := b.saveWizard(ctx, key, state); err != nil {
+			return err
+		}
+		return b.promptWizard(ctx, key.ChatID, state)
+	}
+	return nil
+}
+
+func (b *Bot) answerWizard(ctx context.Context, key models.SessionKey, state *wizardState, text string) error {
+	flow := b.flows[state.Flow]
+	if state.Step >= len(flow.Steps) {
+		return b.promptWizard(ctx, key.ChatID, state)
+	}
+	step := flow.Steps[state.Step]
+	state.Note = ""
+	if step.Clock != "" {
+		// Only a time typed with this answer skips the time step.
+		delete(state.Data, step.Clock)
+	}
+	switch {
+	case text == wizardSkipWord && step.Optional:
+		delete(state.Data, step.Key)
+	case step.Clearable && (strings.EqualFold(text, wizardClearWord) || text == wizardSkipWord):
+		state.Data[step.Key] = ""
+	default:
+		value, err := parseWizardAnswer(step, state, text)
+		if err != nil {
+			b.sendSimple(key.ChatID, b.describeAnswerError(key, state, err))
+			return b.promptWizard(ctx, key.ChatID, state)
+		}
+		state.Data[step.Key] = value
+		if step.Picker == pickerDate {
+			b.noteTypedDate(step, state, text, value)
+		}
+	}
+	state.Step++
+	state.View = ""
+	if step.Clock != "" && state.Data[step.Clock] != "" && state.Step < len(flow.Steps) && flow.Steps[state.Step].Key == step.Clock {
+		state.Step++
+	}
+	for state.Step < len(flow.Steps) && !flow.Steps[state.Step].applies(state) {
+		delete(state.Data, flow.Steps[state.Step].Key)
+		state.Step++
+	}
+	if state.Step == len(flow.Steps) && !flow.confirms() {
+		return b.finishWizard(ctx, key, state)
+	}
+	if err := b.saveWizard(ctx, key, state); err != nil {
+		return err
+	}
+	return b.promptWizard(ctx, key.ChatID, state)
+}
+
+// noteTypedDate echoes how a typed date was understood. A time typed along
+// with it answers the step named by Clock.
+func (b *Bot) noteTypedDate(step wizardStep, state *wizardState, text, value string) {
+	date, err := time.ParseInLocation("2006-01-02", value, b.loc)
+	if err != nil {
+		return
+	}
+	clock := ""
+	if step.Clock != "" {
+		if parsed, err := parseHumanDate(text, b.timeNow().In(b.loc), true); err == nil && parsed.Clock != "" {
+			clock = parsed.Clock
+			state.Data[step.Clock] = clock
+		}
+	}
+	if text != value || clock != "" {
+		state.Note = "Понял как: " + formatHumanDate(date, clock) + ". Если неверно — 'назад'."
+	}
+}
+
+// userMessage is a problem with an answer, worded as a complete sentence for
+// the admin. Parse and Validate return it as plain text; the wizard escapes it
+// before showing it.
+type userMessage string
+
+func (m userMessage) Error() string {
+	return string(m)
+}
+
+func userMessagef(format string, args ...any) error {
+	return userMessage(fmt.Sprintf(format, args...))
+}
+
+// describeAnswerError words a rejected answer for the admin. Errors other than
+// a userMessage are internal and only logged.
+func (b *Bot) describeAnswerError(key models.SessionKey, state *wizardState, err error) string {
+	var message userMessage
+	if !errors.As(err, &message) {
+		b.logger.Error(err, "wizard_answer", state.Flow, key.ChatID, key.AdminID)
+		return "Не удалось разобрать ответ, попробуйте ещё раз."
+	}
+	return escape(string(message))
+}
+
+func parseWizardAnswer(step wizardStep, state *wizardState, text string) (string, error) {
+	if text == "" {
+		return "", userMessage("Ответ не может быть пустым.")
+	}
+	value, matched := "", false
+	for _, choice := range step.Choices {
+		if strings.EqualFold(text, choice.Value) || strings.EqualFold(text, choice.Label) {
+			value, matched = choice.Value, true
+			break
+		}
+	}
+	switch {
+	case matched:
+	case step.Parse != nil:
+		parsed, err := step.Parse(text)
+		if err != nil {
+			return "", err
+		}
+		value = parsed
+	case len(step.Choices) > 0:
+		labels := make([]string, 0, len(step.Choices))
+		for _, choice := range step.Choices {
+			labels = append(labels, choice.Label)
+		}
+		return "", userMessagef("Выберите один из вариантов: %s.", strings.Join(labels, ", "))
+	default:
+		value = text
+	}
+	if step.Validate != nil {
+		if err := step.Validate(state, value); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
+
+func (b *Bot) wizardBack(ctx context.Context, key models.SessionKey, state *wizardState) error {
+	flow := b.flows[state.Flow]
+	if state.Step == 0 {
+		b.sendSimple(key.ChatID, "Это первый шаг. Чтобы выйти, нажмите «Отмена» или отправьте /cancel.")
+		return b.promptWizard(ctx, key.ChatID, state)
+	}
+	state.Step--
+	for state.Step > 0 && !flow.Steps[state.Step].applies(state) {
+		state.Step--
+	}
+	state.View = ""
+	state.Note = ""
+	delete(state.Data, flow.Steps[state.Step].Key)
+	if err := b.saveWizard(ctx, key, state); err != nil {
+		return err
+	}
+	return b.promptWizard(ctx, key.ChatID, state)
+}
+
+func (b *Bot) finishWizard(ctx context.Context, key models.SessionKey, state *wizardState) error {
+	flow := b.flows[state.Flow]
+	if err := flow.Finish(ctx, state); err != nil {
+		b.sendSimple(key.ChatID, flow.Failure+": "+escape(err.Error()))
+		if !flow.confirms() {
+			// Ask the only question again.
+			state.Step = 0
+			delete(state.Data, flow.Steps[0].Key)
+		}
+		if err := b.saveWizard(ctx, key, state); err != nil {
+			return err
+		}
+		return b.promptWizard(ctx, key.ChatID, state)
+	}
+	if err := b.endWizard(ctx, key); err != nil {
+		return err
+	}
+	b.sendSimple(key.ChatID, flow.Success)
+	if flow.Done != nil {
+		return flow.Done(ctx, key.ChatID, state)
+	}
+	return nil
+}
+
+// promptWizard shows the current step, or the summary once all steps are
+// answered.
+func (b *Bot) promptWizard(ctx context.Context, chatID int64, state *wizardState) error {
+	flow := b.flows[state.Flow]
+	if state.Step >= len(flow.Steps) {
+		return b.renderWizardSummary(ctx, chatID, state)
+	}
+	step := flow.Steps[state.Step]
+	stepParam := fmt.Sprintf("s=%d", state.Step)
+
+	var builder strings.Builder
+	if len(flow.Steps) > 1 {
+		builder.WriteString(fmt.Sprintf("*%s* · шаг %d из %d\n", flow.Title, state.Step+1, len(flow.Steps)))
+	} else {
+		builder.WriteString(fmt.Sprintf("*%s*\n", flow.Title))
+	}
+	if state.Note != "" {
+		builder.WriteString(state.Note + "\n\n")
+	}
+	builder.WriteString(step.Prompt(state))
+	builder.WriteString("\n")
+	if step.Optional {
+		if flow.Edit {
+			builder.WriteString("\n'-' — оставить без изменений")
+		} else {
+			builder.WriteString("\n'-' — пропустить")
+		}
+	}
+	if step.Clearable {
+		builder.WriteString("\n'удалить' — очистить")
+	}
+	if state.Step > 0 {
+		builder.WriteString("\n'назад' — предыдущий шаг")
+	}
+	builder.WriteString("\n/cancel — отменить")
+
+	keyboard := b.pickerKeyboard(step, state, stepParam)
+	var row []tgbotapi.InlineKeyboardButton
+	for _, choice := range step.Choices {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(choice.Label, fmt.Sprintf("wz_pick|%s|v=%s", stepParam, choice.Value)))
+		if len(row) == 3 {
+			keyboard = append(keyboard, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		keyboard = append(keyboard, row)
+	}
+	row = nil
+	if step.Optional {
+		label := "⏭ Пропустить"
+		if flow.Edit {
+			label = "⏭ Оставить"
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, "wz_skip|"+stepParam))
+	}
+	if step.Clearable {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("🗑 Очистить", "wz_clear|"+stepParam))
+	}
+	if len(row) > 0 {
+		keyboard = append(keyboard, row)
+	}
+	row = nil
+	if state.Step > 0 {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "wz_back|"+stepParam))
+	}
+	row = append(row, tgbotapi.NewInlineKeyboardButtonData("✖ Отмена", "wz_cancel"))
+	keyboard = append(keyboard, row)
+
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(keyboard...))
+}
+
+// renderWizardConflict asks what to do with the unfinished wizard when
+// another one is being started.
+func (b *Bot) renderWizardConflict(ctx context.Context, chatID int64, state *wizardState) error {
+	flow := b.flows[state.Flow]
+	text := fmt.Sprintf("Есть незавершённый ввод: *%s* (шаг %d из %d).\nПродолжить его или начать заново?",
+		flow.Title, min(state.Step+1, len(flow.Steps)), len(flow.Steps))
+	return b.render(ctx, chatID, text, tgbotapi.NewInlineKeyboardMarkup(
+		[]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("▶ Продолжить", "wz_resume"),
+			tgbotapi.NewInlineKeyboardButtonData("🔄 Начать заново", "wz_restart"),
+		},
+	))
+}
+
+func (b *Bot) renderWizardSummary(ctx context.Context, chatID int64, state *wizardState) error {
+	flow := b.flows[state.Flow]
+	var builder strings.Builder
+	builder.WriteString(fmt.Sprintf("*%s* · проверьте данные\n\n", flow.Title))
+	if state.Note != "" {
+		builder.WriteString(state.Note + "\n\n")
+	}
+	if flow.Summary != nil {
+		summary, err := flow.Summary(ctx, state)
+		if err != nil {
+			return err
+		}
+		builder.WriteString(summary)
+	} else {
+		for _, step := range flow.Steps {
+			if !step.applies(state) {
+				continue
+			}
+			builder.WriteString(fmt.Sprintf("%s: %s\n", step.Label, wizardDisplay(flow, step, state)))
+		}
+	}
+	builder.WriteString("\nСохранить? (да/нет, 'назад' — изменить последний ответ)")
+	stepParam := fmt.Sprintf("s=%d", state.Step)
+	return b.render(ctx, chatID, builder.String(), tgbotapi.NewInlineKeyboardMarkup(
+		[]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("✅ Сохранить", "wz_confirm|"+stepParam),
+		},
+		[]tgbotapi.InlineKeyboardButton{
+			tgbotapi.NewInlineKeyboardButtonData("⬅ Назад", "wz_back|"+stepParam),
+			tgbotapi.NewInlineKeyboardButtonData("✖ Отмена", "wz_cancel"),
+		},
+	))
+}
+
+func wizardDisplay(flow *wizardFlow, step wizardStep, state *wizardState) string {
+	value, ok := state.Data[step.Key]
+	switch {
+	case !ok && flow.Edit:
+		return "без изменений"
+	case !ok:
+		return "—"
+	case value == "":
+		return "очистить"
+	}
+	for _, choice := range step.Choices {
+		if choice.Value == value {
+			return escape(choice.Label)
+		}
+	}
+	return escape(value)
+}
+
+// Answer accessors used by Finish functions.
+
+func (st *wizardState) id(key string) int64 {
+	return parseInt64(st.Data[key])
+}
+
+// stringPtr returns the answer or nil when the step was skipped or cleared.
+func (st *wizardState) stringPtr(key string) *string {
+	if val := st.Data[key]; val != "" {
+		return &val
+	}
+	return nil
+}
+
+// optionalString turns the answer into a patch field: unset when skipped,
+// null when cleared.
+func (st *wizardState) optionalString(key string) models.OptionalString {
+	val, ok := st.Data[key]
+	if !ok {
+		return models.OptionalString{}
+	}
+	if val == "" {
+		return models.NewOptionalString(nil)
+	}
+	return models.NewOptionalString(&val)
+}
+
+func (st *wizardState) datePtr(key string, loc *time.Location) (*time.Time, error) {
+	val := st.Data[key]
+	if val == "" {
+		return nil, nil
+	}
+	parsed, err := time.ParseInLocation("2006-01-02", val, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
+func (st *wizardState) optionalDate(key string, loc *time.Location) (models.OptionalTime, error) {
+	if _, ok := st.Data[key]; !ok {
+		return models.OptionalTime{}, nil
+	}
+	date, err := st.datePtr(key, loc)
+	if err != nil {
+		return models.OptionalTime{}, err
+	}
+	return models.NewOptionalTime(date), nil
+}
+
+func (st *wizardState) stagePtr(key string) *models.MatchStage {
+	if val := st.Data[key]; val != "" {
+		stage := models.MatchStage(val)
+		return &stage
+	}
+	return nil
+}
+
+func (st *wizardState) boolPtr(key string) *bool {
+	val, err := strconv.ParseBool(st.Data[key])
+	if err != nil {
+		return nil
+	}
+	return &val
+}
+
+func (st *wizardState) intPtr(key string) *int {
+	val, err := strconv.Atoi(st.Data[key])
+	if err != nil {
+		return nil
+	}
+	return &val
+}
+
+// Parsers shared by the flows.
+
+func parseWizardClock(text string) (string, error) {
+	parsed, err := time.Parse("15:04", text)
+	if err != nil {
+		return "", userMessage("Неверный формат времени. Используйте HH:MM (24 часа).")
+	}
+	return parsed.Format("15:04"), nil
+}
+
+func parseWizardNumber(text string) (string, error) {
+	number, err := strconv.Atoi(text)
+	if err != nil {
+		return "", userMessage("Номер должен быть целым числом.")
+	}
+	return strconv.Itoa(number), nil
+}
+
+func parseWizardYesNo(text string) (string, error) {
+	val, ok := parseYesNo(text)
+	if !ok {
+		return "", userMessage("Введите 'да' или 'нет'.")
+	}
+	return strconv.FormatBool(val), nil
+}
+
+// parseWizardScores checks the six score tokens: HT FT ET PEN FINAL_US
+// FINAL_THEM, each of them may be '-'.
+func parseWizardScores(text string) (string, error) {
+	tokens := strings.Fields(text)
+	if len(tokens) != 6 {
+		return "", userMessagef("Ожидалось 6 значений, получено %d.", len(tokens))
+	}
+	for _, token := range tokens[:4] {
+		if _, err := optionalScoreString(token); err != nil {
+			return "", err
+		}
+	}
+	for _, token := range tokens[4:] {
+		if _, err := optionalScoreInt(token); err != nil {
+			return "", err
+		}
+	}
+	return strings.Join(tokens, " "), nil
+}
+
+var yesNoChoices = []wizardChoice{
+	{Label: "да", Value: "true"},
+	{Label: "нет", Value: "false"},
+}
@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS players_full_name_trgm_idx
+  ON players USING GIN (translate(lower(full_name), 'ё', 'е') gin_trgm_ops);
+
+-- +goose Down
+DROP INDEX IF EXISTS players_full_name_trgm_idx;
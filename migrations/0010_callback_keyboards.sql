@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS callback_keyboards (
+  id BIGSERIAL PRIMARY KEY,
+  actions JSONB NOT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  expires_at TIMESTAMPTZ NOT NULL
+);
+CREATE INDEX IF NOT EXISTS callback_keyboards_expires_at_idx ON callback_keyboards (expires_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS callback_keyboards;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE admin_sessions ADD COLUMN IF NOT EXISTS chat_id BIGINT;
+-- Sessions so far come from private chats, whose id equals the user id.
+UPDATE admin_sessions SET chat_id = admin_tg_id WHERE chat_id IS NULL;
+ALTER TABLE admin_sessions ALTER COLUMN chat_id SET NOT NULL;
+ALTER TABLE admin_sessions DROP CONSTRAINT IF EXISTS admin_sessions_pkey;
+ALTER TABLE admin_sessions ADD PRIMARY KEY (admin_tg_id, chat_id);
+
+-- +goose Down
+DELETE FROM admin_sessions WHERE chat_id <> admin_tg_id;
+ALTER TABLE admin_sessions DROP CONSTRAINT IF EXISTS admin_sessions_pkey;
+ALTER TABLE admin_sessions DROP COLUMN IF EXISTS chat_id;
+ALTER TABLE admin_sessions ADD PRIMARY KEY (admin_tg_id);
@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS external_uid TEXT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS matches_external_uid_uniq
+  ON matches (tournament_id, team_id, external_uid)
+  WHERE external_uid IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS matches_external_uid_uniq;
+ALTER TABLE matches DROP COLUMN IF EXISTS external_uid;
@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE IF NOT EXISTS tournament_teams (
+  tournament_id BIGINT NOT NULL REFERENCES tournaments(id),
+  team_id BIGINT NOT NULL REFERENCES teams(id),
+  group_name TEXT NULL,
+  registered_on DATE NOT NULL DEFAULT CURRENT_DATE,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (tournament_id, team_id)
+);
+
+-- Teams that already have players or matches in a tournament are registered.
+INSERT INTO tournament_teams (tournament_id, team_id, registered_on)
+SELECT tournament_id, team_id, MIN(created_at)::date
+FROM (
+  SELECT tournament_id, team_id, created_at FROM tournament_roster
+  UNION ALL
+  SELECT tournament_id, team_id, created_at FROM matches
+) existing
+GROUP BY tournament_id, team_id
+ON CONFLICT DO NOTHING;
+
+-- +goose Down
+DROP TABLE IF EXISTS tournament_teams;
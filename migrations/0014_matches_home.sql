@@ -0,0 +1,6 @@
+-- +goose Up
+-- NULL when it is not known whether the club team hosts the match.
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS is_home BOOLEAN NULL;
+
+-- +goose Down
+ALTER TABLE matches DROP COLUMN IF EXISTS is_home;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS stage TEXT NULL; -- group/r16/qf/sf/final
+
+-- Ties of a knockout bracket. Entrants are stored for the first stage only;
+-- later stages are filled by the winners. winner_name records a result
+-- entered by hand for ties without a club match.
+CREATE TABLE IF NOT EXISTS bracket_ties (
+  tournament_id BIGINT NOT NULL REFERENCES tournaments(id),
+  stage TEXT NOT NULL,
+  position INT NOT NULL,
+  home_name TEXT NULL,
+  away_name TEXT NULL,
+  winner_name TEXT NULL,
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  PRIMARY KEY (tournament_id, stage, position)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS bracket_ties;
+ALTER TABLE matches DROP COLUMN IF EXISTS stage;
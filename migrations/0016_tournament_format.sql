@@ -0,0 +1,36 @@
+-- +goose Up
+ALTER TABLE tournaments ADD COLUMN IF NOT EXISTS format TEXT NOT NULL DEFAULT 'league';
+ALTER TABLE tournaments ADD COLUMN IF NOT EXISTS format_config JSONB NOT NULL DEFAULT '{}';
+
+UPDATE tournaments SET format = CASE
+    WHEN type ~* '(групп|group)' THEN 'group_playoff'
+    WHEN type ~* '(кубок|cup|плей|playoff|олимп)' THEN 'cup'
+    WHEN type ~* '(товарищ|friendly|спарринг)' THEN 'friendly'
+    WHEN type ~* '(фестивал|festival)' THEN 'festival'
+    ELSE 'league'
+  END
+WHERE type IS NOT NULL;
+
+UPDATE tournaments SET format_config = CASE format
+    WHEN 'league' THEN '{"points_win": 3, "points_draw": 1, "legs": 2}'::jsonb
+    WHEN 'group_playoff' THEN '{"points_win": 3, "points_draw": 1, "groups": 2, "advance_per_group": 2}'::jsonb
+    ELSE '{}'::jsonb
+  END;
+
+-- The free-text type is kept in the note so that nothing typed is lost.
+UPDATE tournaments
+SET note = CONCAT_WS(E'\n', NULLIF(note, ''), 'Тип: ' || type)
+WHERE type IS NOT NULL AND btrim(type) <> '';
+
+ALTER TABLE tournaments
+  ADD CONSTRAINT tournaments_format_check
+  CHECK (format IN ('league', 'cup', 'group_playoff', 'friendly', 'festival'));
+
+ALTER TABLE tournaments DROP COLUMN IF EXISTS type;
+
+-- +goose Down
+ALTER TABLE tournaments ADD COLUMN IF NOT EXISTS type TEXT NULL;
+UPDATE tournaments SET type = format;
+ALTER TABLE tournaments DROP CONSTRAINT IF EXISTS tournaments_format_check;
+ALTER TABLE tournaments DROP COLUMN IF EXISTS format_config;
+ALTER TABLE tournaments DROP COLUMN IF EXISTS format;
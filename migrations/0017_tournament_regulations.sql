@@ -0,0 +1,18 @@
+-- +goose Up
+-- Match rules of a tournament. A tournament without a row plays by the
+-- defaults of the service; NULL limits mean no limit.
+CREATE TABLE IF NOT EXISTS tournament_regulations (
+  tournament_id BIGINT PRIMARY KEY REFERENCES tournaments(id),
+  periods INT NOT NULL DEFAULT 2 CHECK (periods > 0),
+  period_minutes INT NOT NULL DEFAULT 45 CHECK (period_minutes > 0),
+  max_starters INT NOT NULL DEFAULT 11 CHECK (max_starters > 0),
+  max_bench INT NULL CHECK (max_bench >= 0),
+  max_subs INT NULL CHECK (max_subs >= 0),
+  rolling_subs BOOLEAN NOT NULL DEFAULT FALSE,
+  min_roster INT NOT NULL DEFAULT 7 CHECK (min_roster > 0),
+  created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS tournament_regulations;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Registration window and squad size of the team rosters of a tournament;
+-- NULL means no restriction.
+ALTER TABLE tournament_regulations ADD COLUMN IF NOT EXISTS roster_opens DATE NULL;
+ALTER TABLE tournament_regulations ADD COLUMN IF NOT EXISTS roster_closes DATE NULL;
+ALTER TABLE tournament_regulations ADD COLUMN IF NOT EXISTS min_squad INT NULL CHECK (min_squad > 0);
+ALTER TABLE tournament_regulations ADD COLUMN IF NOT EXISTS max_squad INT NULL CHECK (max_squad > 0);
+ALTER TABLE tournament_regulations
+  ADD CONSTRAINT tournament_regulations_roster_window_check
+  CHECK (roster_opens IS NULL OR roster_closes IS NULL OR roster_opens <= roster_closes);
+ALTER TABLE tournament_regulations
+  ADD CONSTRAINT tournament_regulations_squad_check
+  CHECK (min_squad IS NULL OR max_squad IS NULL OR min_squad <= max_squad);
+
+-- +goose Down
+ALTER TABLE tournament_regulations DROP CONSTRAINT IF EXISTS tournament_regulations_squad_check;
+ALTER TABLE tournament_regulations DROP CONSTRAINT IF EXISTS tournament_regulations_roster_window_check;
+ALTER TABLE tournament_regulations DROP COLUMN IF EXISTS max_squad;
+ALTER TABLE tournament_regulations DROP COLUMN IF EXISTS min_squad;
+ALTER TABLE tournament_regulations DROP COLUMN IF EXISTS roster_closes;
+ALTER TABLE tournament_regulations DROP COLUMN IF EXISTS roster_opens;
@@ -0,0 +1,15 @@
+-- +goose Up
+-- Age rules of a tournament: eligible birth years and how many players born
+-- before born_from a team may still enter.
+ALTER TABLE tournament_regulations ADD COLUMN IF NOT EXISTS born_from INT NULL;
+ALTER TABLE tournament_regulations ADD COLUMN IF NOT EXISTS born_to INT NULL;
+ALTER TABLE tournament_regulations ADD COLUMN IF NOT EXISTS max_overage INT NOT NULL DEFAULT 0 CHECK (max_overage >= 0);
+ALTER TABLE tournament_regulations
+  ADD CONSTRAINT tournament_regulations_born_check
+  CHECK (born_from IS NULL OR born_to IS NULL OR born_from <= born_to);
+
+-- +goose Down
+ALTER TABLE tournament_regulations DROP CONSTRAINT IF EXISTS tournament_regulations_born_check;
+ALTER TABLE tournament_regulations DROP COLUMN IF EXISTS max_overage;
+ALTER TABLE tournament_regulations DROP COLUMN IF EXISTS born_to;
+ALTER TABLE tournament_regulations DROP COLUMN IF EXISTS born_from;
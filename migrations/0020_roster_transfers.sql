@@ -0,0 +1,10 @@
+-- +goose Up
+-- A player may be limited to one team per tournament. Moving a player to
+-- another team keeps the old roster entry with the day it ended, so earlier
+-- lineups and events still count for the old team.
+ALTER TABLE tournament_regulations ADD COLUMN IF NOT EXISTS one_team_per_player BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE tournament_roster ADD COLUMN IF NOT EXISTS left_on DATE NULL;
+
+-- +goose Down
+ALTER TABLE tournament_roster DROP COLUMN IF EXISTS left_on;
+ALTER TABLE tournament_regulations DROP COLUMN IF EXISTS one_team_per_player;
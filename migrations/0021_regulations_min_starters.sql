@@ -0,0 +1,11 @@
+-- +goose Up
+-- The minimum is about starters, not the roster, and by default there is
+-- none: smaller formats used to be held up by the eleven-a-side minimum of 7.
+ALTER TABLE tournament_regulations RENAME COLUMN min_roster TO min_starters;
+ALTER TABLE tournament_regulations RENAME CONSTRAINT tournament_regulations_min_roster_check TO tournament_regulations_min_starters_check;
+ALTER TABLE tournament_regulations ALTER COLUMN min_starters SET DEFAULT 1;
+
+-- +goose Down
+ALTER TABLE tournament_regulations ALTER COLUMN min_starters SET DEFAULT 7;
+ALTER TABLE tournament_regulations RENAME CONSTRAINT tournament_regulations_min_starters_check TO tournament_regulations_min_roster_check;
+ALTER TABLE tournament_regulations RENAME COLUMN min_starters TO min_roster;
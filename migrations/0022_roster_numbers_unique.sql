@@ -0,0 +1,31 @@
+-- +goose Up
+-- Numbers are unique within the current roster of a team. The service checks
+-- this before writing; the index closes the race between two admins. Existing
+-- duplicates are listed so that they can be renumbered before migrating.
+-- +goose StatementBegin
+DO $$
+DECLARE
+  duplicates TEXT;
+BEGIN
+  SELECT string_agg(format('tournament %s, team %s, number %s: %s', tournament_id, team_id, tournament_number, holders), E'\n')
+  INTO duplicates
+  FROM (
+    SELECT tr.tournament_id, tr.team_id, tr.tournament_number,
+           string_agg(p.full_name, ', ' ORDER BY p.full_name) AS holders
+    FROM tournament_roster tr
+    JOIN players p ON p.id = tr.player_id
+    WHERE tr.left_on IS NULL AND tr.tournament_number IS NOT NULL
+    GROUP BY tr.tournament_id, tr.team_id, tr.tournament_number
+    HAVING COUNT(*) > 1
+  ) taken;
+  IF duplicates IS NOT NULL THEN
+    RAISE EXCEPTION E'duplicate roster numbers, renumber these players first:\n%', duplicates;
+  END IF;
+END $$;
+-- +goose StatementEnd
+CREATE UNIQUE INDEX IF NOT EXISTS tournament_roster_number_key
+  ON tournament_roster (tournament_id, team_id, tournament_number)
+  WHERE left_on IS NULL AND tournament_number IS NOT NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS tournament_roster_number_key;
@@ -0,0 +1,35 @@
+-- +goose Up
+-- Each spell of a player in a team is its own roster entry, from joined_on
+-- up to the day before left_on, so that a player who comes back keeps the
+-- earlier spell. Existing entries cover the tournament from its start or the
+-- first match of the team, whichever is earlier.
+ALTER TABLE tournament_roster ADD COLUMN IF NOT EXISTS joined_on DATE NULL;
+UPDATE tournament_roster tr
+SET joined_on = LEAST(
+  tr.created_at::date,
+  (SELECT t.start_date FROM tournaments t WHERE t.id = tr.tournament_id),
+  (SELECT MIN(m.start_time)::date FROM matches m WHERE m.tournament_id = tr.tournament_id AND m.team_id = tr.team_id)
+)
+WHERE joined_on IS NULL;
+ALTER TABLE tournament_roster ALTER COLUMN joined_on SET DEFAULT CURRENT_DATE;
+ALTER TABLE tournament_roster ALTER COLUMN joined_on SET NOT NULL;
+ALTER TABLE tournament_roster
+  ADD CONSTRAINT tournament_roster_spell_check
+  CHECK (left_on IS NULL OR joined_on <= left_on);
+ALTER TABLE tournament_roster DROP CONSTRAINT IF EXISTS tournament_roster_tournament_id_team_id_player_id_key;
+CREATE UNIQUE INDEX IF NOT EXISTS tournament_roster_player_key
+  ON tournament_roster (tournament_id, team_id, player_id)
+  WHERE left_on IS NULL;
+
+-- +goose Down
+-- Only the latest spell of a player in a team is kept.
+DELETE FROM tournament_roster tr
+USING tournament_roster later
+WHERE later.tournament_id = tr.tournament_id
+  AND later.team_id = tr.team_id
+  AND later.player_id = tr.player_id
+  AND (later.joined_on, later.id) > (tr.joined_on, tr.id);
+DROP INDEX IF EXISTS tournament_roster_player_key;
+ALTER TABLE tournament_roster ADD CONSTRAINT tournament_roster_tournament_id_team_id_player_id_key UNIQUE (tournament_id, team_id, player_id);
+ALTER TABLE tournament_roster DROP CONSTRAINT IF EXISTS tournament_roster_spell_check;
+ALTER TABLE tournament_roster DROP COLUMN IF EXISTS joined_on;
@@ -0,0 +1,7 @@
+-- +goose Up
+-- Marks matches canceled by a calendar import, so that they come back when
+-- the event is confirmed again; matches canceled by hand stay canceled.
+ALTER TABLE matches ADD COLUMN IF NOT EXISTS canceled_by_calendar BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- +goose Down
+ALTER TABLE matches DROP COLUMN IF EXISTS canceled_by_calendar;